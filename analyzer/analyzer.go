@@ -0,0 +1,204 @@
+// Package analyzer provides a go/analysis Analyzer that reports misuse of
+// github.com/jcgregorio/logger, so it can be caught by go vet:
+//
+//	go install github.com/jcgregorio/logger/analyzer/cmd/loggervet@latest
+//	go vet -vettool=$(which loggervet) ./...
+//
+// It reports:
+//
+//	Format strings passed to Infof and friends whose verbs don't match the
+//	number of arguments.
+//
+//	keysAndValues passed to With and the *w methods, such as Infow, with an
+//	odd number of elements or a key that isn't a string.
+//
+//	Fatal logs in packages other than main, since libraries shouldn't decide
+//	to exit the process.
+//
+// It lives in its own module so the logger module doesn't depend on
+// golang.org/x/tools.
+package analyzer
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+// loggerPath is the import path of the logger package.
+const loggerPath = "github.com/jcgregorio/logger"
+
+// Analyzer reports misuse of the logger package.
+var Analyzer = &analysis.Analyzer{
+	Name:     "loggervet",
+	Doc:      "report misuse of github.com/jcgregorio/logger: format string mismatches, bad keysAndValues, and Fatal in libraries",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// printfMethods are the methods that take a format string as their first
+// argument.
+var printfMethods = map[string]bool{
+	"Debugf":   true,
+	"Infof":    true,
+	"Warningf": true,
+	"Errorf":   true,
+	"Fatalf":   true,
+}
+
+// kvMethods are the methods that take keysAndValues, mapped to the index of
+// the first of them.
+var kvMethods = map[string]int{
+	"With":     0,
+	"Debugw":   1,
+	"Infow":    1,
+	"Warningw": 1,
+	"Errorw":   1,
+	"Fatalw":   1,
+}
+
+// fatalFuncs are the methods and functions that exit the process.
+var fatalFuncs = map[string]bool{
+	"Fatal":       true,
+	"Fatalf":      true,
+	"FatalFields": true,
+	"Fatalw":      true,
+	"FatalCtx":    true,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	insp.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		name, ok := loggerFunc(pass, call)
+		if !ok {
+			return
+		}
+		if printfMethods[name] {
+			checkPrintf(pass, call, name)
+		}
+		if start, ok := kvMethods[name]; ok {
+			checkKeysAndValues(pass, call, name, start)
+		}
+		if fatalFuncs[name] && pass.Pkg.Name() != "main" && !strings.HasSuffix(pass.Fset.File(call.Pos()).Name(), "_test.go") {
+			pass.Reportf(call.Pos(), "%s called in library package %s, return an error instead of exiting", name, pass.Pkg.Name())
+		}
+	})
+	return nil, nil
+}
+
+// loggerFunc returns the name of the function called by call if it's a
+// method of a type in the logger package, or a function in it.
+func loggerFunc(pass *analysis.Pass, call *ast.CallExpr) (string, bool) {
+	var id *ast.Ident
+	switch fun := call.Fun.(type) {
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	case *ast.Ident:
+		id = fun
+	default:
+		return "", false
+	}
+	fn, ok := pass.TypesInfo.Uses[id].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != loggerPath {
+		return "", false
+	}
+	return fn.Name(), true
+}
+
+// checkPrintf reports a mismatch between the verbs of a constant format
+// string and the number of arguments.
+func checkPrintf(pass *analysis.Pass, call *ast.CallExpr, name string) {
+	if len(call.Args) == 0 || call.Ellipsis.IsValid() {
+		return
+	}
+	tv, ok := pass.TypesInfo.Types[call.Args[0]]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return
+	}
+	want, ok := countVerbs(constant.StringVal(tv.Value))
+	if !ok {
+		return
+	}
+	if got := len(call.Args) - 1; got != want {
+		pass.Reportf(call.Pos(), "%s format %q reads %d args, but call has %d", name, constant.StringVal(tv.Value), want, got)
+	}
+}
+
+// countVerbs returns the number of arguments consumed by format, or false
+// if it uses explicit argument indexes, which aren't checked.
+func countVerbs(format string) (int, bool) {
+	n := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		if i < len(format) && format[i] == '%' {
+			continue
+		}
+		for i < len(format) && strings.IndexByte("+-# 0", format[i]) >= 0 {
+			i++
+		}
+		for ; i < len(format); i++ {
+			c := format[i]
+			if c == '[' {
+				return 0, false
+			}
+			if c == '*' {
+				n++
+				continue
+			}
+			if (c >= '0' && c <= '9') || c == '.' {
+				continue
+			}
+			break
+		}
+		if i < len(format) {
+			n++
+		}
+	}
+	return n, true
+}
+
+// checkKeysAndValues reports an odd number of keysAndValues, or a key that
+// isn't a string.
+func checkKeysAndValues(pass *analysis.Pass, call *ast.CallExpr, name string, start int) {
+	if call.Ellipsis.IsValid() || len(call.Args) <= start {
+		return
+	}
+	args := call.Args[start:]
+	for i := 0; i < len(args); i++ {
+		t := pass.TypesInfo.TypeOf(args[i])
+		if t == nil {
+			return
+		}
+		if isField(t) {
+			continue
+		}
+		if b, ok := t.Underlying().(*types.Basic); !ok || b.Info()&types.IsString == 0 {
+			pass.Reportf(args[i].Pos(), "%s key %s is a %s, not a string", name, types.ExprString(args[i]), t)
+			return
+		}
+		if i == len(args)-1 {
+			pass.Reportf(args[i].Pos(), "%s key %s has no value", name, types.ExprString(args[i]))
+			return
+		}
+		i++
+	}
+}
+
+// isField returns true if t is logger.Field.
+func isField(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == loggerPath && obj.Name() == "Field"
+}
@@ -0,0 +1,30 @@
+package analyzer
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "lib", "main")
+}
+
+func TestCountVerbs(t *testing.T) {
+	for format, want := range map[string]int{
+		"":               0,
+		"100%%":          0,
+		"%s %d":          2,
+		"%-8.3f|%+v|%#x": 3,
+		"%*d %.*s":       4,
+		"trailing %":     0,
+		"%v%%%v":         2,
+	} {
+		if got, ok := countVerbs(format); !ok || got != want {
+			t.Errorf("countVerbs(%q) = %d, %v want %d", format, got, ok, want)
+		}
+	}
+	if _, ok := countVerbs("%[2]s %[1]s"); ok {
+		t.Error("Expected explicit indexes to be skipped")
+	}
+}
@@ -0,0 +1,14 @@
+// Command loggervet reports misuse of github.com/jcgregorio/logger, see the
+// analyzer package. It's intended to be run by go vet:
+//
+//	go vet -vettool=$(which loggervet) ./...
+package main
+
+import (
+	"github.com/jcgregorio/logger/analyzer"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(analyzer.Analyzer)
+}
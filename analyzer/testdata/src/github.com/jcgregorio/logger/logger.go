@@ -0,0 +1,17 @@
+// Package logger is a stub of the parts of github.com/jcgregorio/logger
+// that the analyzer checks.
+package logger
+
+import "context"
+
+type Logger struct{}
+
+type Field struct{}
+
+func Int(key string, val int) Field { return Field{} }
+
+func (l *Logger) Infof(format string, args ...interface{})       {}
+func (l *Logger) Fatal(args ...interface{})                      {}
+func (l *Logger) Infow(msg string, keysAndValues ...interface{}) {}
+func (l *Logger) With(keysAndValues ...interface{}) *Logger      { return l }
+func FatalCtx(ctx context.Context, msg string, fields ...Field)  {}
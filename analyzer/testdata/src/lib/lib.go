@@ -0,0 +1,28 @@
+package lib
+
+import (
+	"context"
+
+	"github.com/jcgregorio/logger"
+)
+
+type key string
+
+func f(l *logger.Logger, ctx context.Context, args []interface{}) {
+	l.Infof("%s took %d ms", "get", 12)
+	l.Infof("100%% of %s", "requests")
+	l.Infof("%*d", 3, 12)
+	l.Infof("%[1]s %[1]s", "same")
+	l.Infof(args[0].(string), args...)
+	l.Infof("%s took %d ms", "get") // want `Infof format "%s took %d ms" reads 2 args, but call has 1`
+	l.Infof("done", 1)              // want `Infof format "done" reads 0 args, but call has 1`
+
+	l.Infow("served", "path", "/", logger.Int("status", 200), "ms", 12)
+	l.Infow("served", key("path"), "/")
+	l.Infow("served", args...)
+	l.Infow("served", "path") // want `Infow key "path" has no value`
+	l.With(42, "v")           // want `With key 42 is a int, not a string`
+
+	l.Fatal("giving up")              // want `Fatal called in library package lib, return an error instead of exiting`
+	logger.FatalCtx(ctx, "giving up") // want `FatalCtx called in library package lib, return an error instead of exiting`
+}
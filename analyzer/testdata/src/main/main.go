@@ -0,0 +1,8 @@
+package main
+
+import "github.com/jcgregorio/logger"
+
+func main() {
+	var l logger.Logger
+	l.Fatal("exiting is fine in main")
+}
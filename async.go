@@ -0,0 +1,411 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultAsyncQueueSize is the queue size of an AsyncWriter if none is
+// given.
+const defaultAsyncQueueSize = 1024
+
+// QueueFullPolicy selects what an AsyncWriter does with a write when its
+// queue is full.
+type QueueFullPolicy int
+
+const (
+	// BlockWhenFull waits for there to be room in the queue, so nothing is
+	// lost, but logging is held up by the destination.
+	BlockWhenFull QueueFullPolicy = iota
+
+	// DropNewestWhenFull drops the write.
+	DropNewestWhenFull
+
+	// DropOldestWhenFull drops the oldest queued write to make room, which
+	// keeps the most recent entries, usually the most relevant to a
+	// problem.
+	DropOldestWhenFull
+)
+
+// asyncItem is an entry queued by an AsyncWriter, or, if flushed isn't
+// nil, a request to close flushed once everything before it is written.
+type asyncItem struct {
+	e        entryInfo
+	hasEntry bool
+	p        []byte
+	flushed  chan struct{}
+
+	// mem is the number of bytes reserved for p against
+	// Options.MaxMemory.
+	mem int
+}
+
+// AsyncWriter is a SyncWriter that queues writes and makes them to another
+// SyncWriter from a background goroutine, so logging isn't held up by a
+// slow disk or network destination. When the queue is full writes wait,
+// or entries are dropped, as selected by a QueueFullPolicy, see
+// NewAsyncWriterWithPolicy.
+//
+// Sync and Flush wait for the queued writes to be made, and Close makes
+// them before closing the destination, so no entries are lost by a Logger
+// that's Shutdown, or exits after a Fatal log. Errors from the destination
+// are returned by the next Sync or Flush, and reported by Healthy.
+//
+// The AsyncWriter of Options.AsyncQueueSize also counts the queued writes
+// against Options.MaxMemory, and once it's reached applies the
+// QueueFullPolicy as if the queue were full. A write that waits for room
+// is queued regardless once nothing else is, so it doesn't wait forever
+// for memory held elsewhere.
+type AsyncWriter struct {
+	w      SyncWriter
+	queue  chan asyncItem
+	policy QueueFullPolicy
+
+	// mem, if not nil, is the state of the Logger whose Options.MaxMemory
+	// the queued writes are counted against.
+	mem *loggerState
+
+	// memFreed is signalled, with memMu held, as queued writes release
+	// their memory, for writes waiting for room.
+	memMu    sync.Mutex
+	memFreed *sync.Cond
+
+	// dropped is the number of writes dropped, accessed atomically.
+	dropped uint64
+
+	// dropMu serializes dropping the oldest writes.
+	dropMu sync.Mutex
+
+	// done is closed once the background goroutine has exited.
+	done chan struct{}
+
+	// mu is held for reading while queueing, and for writing by Close, so
+	// nothing is queued once closed is true.
+	mu     sync.RWMutex
+	closed bool
+
+	// errMu protects err, the first error from the destination since the
+	// last Sync or Flush, and lastErr, the error from the last write.
+	errMu   sync.Mutex
+	err     error
+	lastErr error
+}
+
+// NewAsyncWriter returns an AsyncWriter that writes to w, queueing up to
+// size writes, and waiting when the queue is full. If size isn't greater
+// than zero then 1024 is used.
+func NewAsyncWriter(w SyncWriter, size int) *AsyncWriter {
+	return NewAsyncWriterWithPolicy(w, size, BlockWhenFull)
+}
+
+// NewAsyncWriterWithPolicy returns an AsyncWriter that writes to w,
+// queueing up to size writes, and applying policy when the queue is full.
+// If size isn't greater than zero then 1024 is used.
+func NewAsyncWriterWithPolicy(w SyncWriter, size int, policy QueueFullPolicy) *AsyncWriter {
+	if size <= 0 {
+		size = defaultAsyncQueueSize
+	}
+	a := &AsyncWriter{
+		w:      w,
+		queue:  make(chan asyncItem, size),
+		policy: policy,
+		done:   make(chan struct{}),
+	}
+	a.memFreed = sync.NewCond(&a.memMu)
+	go a.run()
+	return a
+}
+
+// run makes the queued writes until the queue is closed.
+func (a *AsyncWriter) run() {
+	defer close(a.done)
+	for item := range a.queue {
+		if item.flushed != nil {
+			a.release(item)
+			close(item.flushed)
+			continue
+		}
+		var err error
+		if item.hasEntry {
+			err = writeTo(a.w, item.e, item.p)
+		} else {
+			_, err = a.w.Write(item.p)
+		}
+		a.release(item)
+		a.errMu.Lock()
+		a.lastErr = err
+		if a.err == nil {
+			a.err = err
+		}
+		a.errMu.Unlock()
+	}
+}
+
+// enqueue queues item, applying the QueueFullPolicy, returning
+// ErrShutdown if a is closed. Flushes always wait for room.
+func (a *AsyncWriter) enqueue(item asyncItem) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return ErrShutdown
+	}
+	if item.flushed == nil && a.mem != nil && !a.reserve(&item) {
+		return nil
+	}
+	if a.policy == BlockWhenFull || item.flushed != nil {
+		a.queue <- item
+		return nil
+	}
+	select {
+	case a.queue <- item:
+		return nil
+	default:
+	}
+	if a.policy == DropNewestWhenFull {
+		a.release(item)
+		atomic.AddUint64(&a.dropped, 1)
+		return nil
+	}
+	a.dropOldest(item)
+	return nil
+}
+
+// reserve reserves the memory for item against Options.MaxMemory,
+// applying the QueueFullPolicy until there's room, and returns false if
+// item is dropped instead.
+func (a *AsyncWriter) reserve(item *asyncItem) bool {
+	n := len(item.p)
+	if a.mem.reserveMemory(n) {
+		item.mem = n
+		return true
+	}
+	switch a.policy {
+	case BlockWhenFull:
+		a.memMu.Lock()
+		defer a.memMu.Unlock()
+		for !a.mem.reserveMemory(n) {
+			if len(a.queue) == 0 {
+				// The memory is held elsewhere, so queue item without it.
+				return true
+			}
+			a.memFreed.Wait()
+		}
+		item.mem = n
+		return true
+	case DropOldestWhenFull:
+		a.dropMu.Lock()
+		defer a.dropMu.Unlock()
+		var flushes []asyncItem
+		defer func() {
+			for _, f := range flushes {
+				a.queue <- f
+			}
+		}()
+		for !a.mem.reserveMemory(n) {
+			select {
+			case oldest := <-a.queue:
+				if oldest.flushed != nil {
+					flushes = append(flushes, oldest)
+					continue
+				}
+				a.release(oldest)
+				atomic.AddUint64(&a.dropped, 1)
+				a.mem.dropMemory()
+			default:
+				atomic.AddUint64(&a.dropped, 1)
+				a.mem.dropMemory()
+				return false
+			}
+		}
+		item.mem = n
+		return true
+	}
+	atomic.AddUint64(&a.dropped, 1)
+	a.mem.dropMemory()
+	return false
+}
+
+// release releases the memory reserved for item, if any, waking the writes
+// waiting for room, which also wait for the queue to empty.
+func (a *AsyncWriter) release(item asyncItem) {
+	if a.mem == nil {
+		return
+	}
+	if item.mem > 0 {
+		a.mem.releaseMemory(item.mem)
+	}
+	if a.policy == BlockWhenFull {
+		a.memMu.Lock()
+		a.memFreed.Broadcast()
+		a.memMu.Unlock()
+	}
+}
+
+// dropOldest queues item, dropping the oldest writes until there's room.
+// Flushes taken off the queue are queued again after item, rather than
+// dropped, so they still wait for everything before them.
+func (a *AsyncWriter) dropOldest(item asyncItem) {
+	a.dropMu.Lock()
+	defer a.dropMu.Unlock()
+	pending := []asyncItem{item}
+	for len(pending) > 0 {
+		select {
+		case a.queue <- pending[0]:
+			pending = pending[1:]
+			continue
+		default:
+		}
+		select {
+		case oldest := <-a.queue:
+			if oldest.flushed != nil {
+				pending = append(pending, oldest)
+			} else {
+				a.release(oldest)
+				atomic.AddUint64(&a.dropped, 1)
+			}
+		default:
+		}
+	}
+}
+
+// Purge implements Purger, removing the queued writes that contain
+// subject, and then those of the destination if it implements Purger.
+func (a *AsyncWriter) Purge(subject string) int {
+	n := a.purgeQueue(subject)
+	if p, ok := a.w.(Purger); ok {
+		n += p.Purge(subject)
+	}
+	return n
+}
+
+// purgeQueue removes the queued writes that contain subject, returning how
+// many. Nothing is queued meanwhile, so the rest are queued again in order.
+func (a *AsyncWriter) purgeQueue(subject string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return 0
+	}
+	n := 0
+	var kept []asyncItem
+drain:
+	for {
+		select {
+		case item := <-a.queue:
+			if item.flushed == nil && bytes.Contains(item.p, []byte(subject)) {
+				a.release(item)
+				n++
+			} else {
+				kept = append(kept, item)
+			}
+		default:
+			break drain
+		}
+	}
+	for _, item := range kept {
+		a.queue <- item
+	}
+	return n
+}
+
+// Dropped returns the number of writes dropped because the queue was full,
+// or Options.MaxMemory was reached.
+func (a *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&a.dropped)
+}
+
+// writeEntry implements entryWriter.
+func (a *AsyncWriter) writeEntry(e entryInfo, p []byte) (int, error) {
+	if err := a.enqueue(asyncItem{e: e, hasEntry: true, p: append([]byte(nil), p...)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Write implements SyncWriter, queueing a copy of p. It returns
+// ErrShutdown once a is closed.
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	if err := a.enqueue(asyncItem{p: append([]byte(nil), p...)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush waits for the writes queued so far to be made, and returns the
+// first error from the destination since the last Sync or Flush.
+func (a *AsyncWriter) Flush() error {
+	flushed := make(chan struct{})
+	if err := a.enqueue(asyncItem{flushed: flushed}); err != nil {
+		// Close has already made all the writes.
+		return a.takeErr()
+	}
+	<-flushed
+	return a.takeErr()
+}
+
+// takeErr returns and clears the first error since the last call.
+func (a *AsyncWriter) takeErr() error {
+	a.errMu.Lock()
+	defer a.errMu.Unlock()
+	err := a.err
+	a.err = nil
+	return err
+}
+
+// Sync implements SyncWriter, flushing and then syncing the destination.
+func (a *AsyncWriter) Sync() error {
+	err := a.Flush()
+	if syncErr := a.w.Sync(); err == nil {
+		err = syncErr
+	}
+	return err
+}
+
+// QueueDepth implements QueueDepther.
+func (a *AsyncWriter) QueueDepth() int {
+	return len(a.queue)
+}
+
+// Healthy implements HealthChecker, reporting the error from the last
+// write, and the health of the destination if it implements
+// HealthChecker.
+func (a *AsyncWriter) Healthy() error {
+	a.errMu.Lock()
+	err := a.lastErr
+	a.errMu.Unlock()
+	if err != nil {
+		return err
+	}
+	if hc, ok := a.w.(HealthChecker); ok {
+		return hc.Healthy()
+	}
+	return nil
+}
+
+// Close makes the queued writes and stops the background goroutine, then
+// syncs the destination, and closes it if it implements io.Closer, other
+// than os.Stdout and os.Stderr. Writes after Close return ErrShutdown.
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.queue)
+	a.mu.Unlock()
+	<-a.done
+	err := a.takeErr()
+	if syncErr := a.w.Sync(); err == nil {
+		err = syncErr
+	}
+	if c, ok := a.w.(io.Closer); ok && a.w != os.Stdout && a.w != os.Stderr {
+		if closeErr := c.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,131 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// gatedWriter is a flushBuffer whose writes wait for gate to be closed.
+type gatedWriter struct {
+	flushBuffer
+	gate chan struct{}
+}
+
+func (g *gatedWriter) Write(p []byte) (int, error) {
+	<-g.gate
+	return g.flushBuffer.Write(p)
+}
+
+func TestAsyncWriter(t *testing.T) {
+	g := &gatedWriter{gate: make(chan struct{})}
+	a := NewAsyncWriter(g, 10)
+	for _, s := range []string{"a\n", "b\n", "c\n"} {
+		if n, err := a.Write([]byte(s)); n != len(s) || err != nil {
+			t.Fatalf("Write returned %d, %v", n, err)
+		}
+	}
+	if a.QueueDepth() == 0 {
+		t.Error("Expected queued writes")
+	}
+	close(g.gate)
+	if err := a.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := g.String(); got != "a\nb\nc\n" {
+		t.Errorf("Got %q after Flush, want all the writes in order", got)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Write([]byte("d\n")); err != ErrShutdown {
+		t.Errorf("Got %v after Close, want ErrShutdown", err)
+	}
+	if err := a.Close(); err != nil {
+		t.Errorf("A second Close should be a no-op, got %s", err)
+	}
+}
+
+func TestAsyncWriterErrors(t *testing.T) {
+	f := &failingWriter{err: errors.New("disk full")}
+	a := NewAsyncWriter(f, 0)
+	defer a.Close()
+	a.Write([]byte("lost\n"))
+	if err := a.Sync(); err == nil || err.Error() != "disk full" {
+		t.Errorf("Got %v from Sync, want the write error", err)
+	}
+	if err := a.Healthy(); err == nil {
+		t.Error("Should be unhealthy after a failed write")
+	}
+	if err := a.Sync(); err != nil {
+		t.Errorf("The error should only be returned once, got %v", err)
+	}
+}
+
+func TestAsyncQueueSize(t *testing.T) {
+	w := &closeBuffer{}
+	r := NewRouter(nil, Route{MinSeverity: WarningSeverity, To: w})
+	l := NewFromOptions(&Options{SyncWriter: r, AsyncQueueSize: 100})
+	if _, ok := l.writer().(*AsyncWriter); !ok {
+		t.Fatalf("Destination is %T, want an *AsyncWriter", l.writer())
+	}
+	for i := 0; i < 50; i++ {
+		l.Infof("info %d", i)
+		l.Warningf("warning %d", i)
+	}
+	l.Shutdown(context.Background())
+	got := w.String()
+	if n := strings.Count(got, "] warning "); n != 50 || strings.Contains(got, "] info ") {
+		t.Errorf("Got %d warnings, want all 50 and no infos routed: %q", n, got)
+	}
+	if !w.synced || !w.closed {
+		t.Errorf("Destination not synced and closed: %v %v", w.synced, w.closed)
+	}
+}
+
+func TestAsyncWriterQueueFull(t *testing.T) {
+	for policy, want := range map[QueueFullPolicy]string{
+		DropNewestWhenFull: "a\nb\nc\n",
+		DropOldestWhenFull: "a\nc\nd\n",
+	} {
+		g := &gatedWriter{gate: make(chan struct{})}
+		a := NewAsyncWriterWithPolicy(g, 2, policy)
+		a.Write([]byte("a\n"))
+		// Wait for "a" to be taken off the queue, so the next two fill it.
+		for a.QueueDepth() != 0 {
+			time.Sleep(time.Millisecond)
+		}
+		for _, s := range []string{"b\n", "c\n", "d\n"} {
+			if _, err := a.Write([]byte(s)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		close(g.gate)
+		a.Flush()
+		if got := g.String(); got != want {
+			t.Errorf("Policy %d: got %q, want %q", policy, got, want)
+		}
+		if a.Dropped() != 1 {
+			t.Errorf("Policy %d: got %d dropped, want 1", policy, a.Dropped())
+		}
+		a.Close()
+	}
+}
+
+func TestDroppedWrites(t *testing.T) {
+	g := &gatedWriter{gate: make(chan struct{})}
+	l := NewFromOptions(&Options{SyncWriter: g, AsyncQueueSize: 1, AsyncQueueFull: DropNewestWhenFull})
+	for i := 0; i < 10; i++ {
+		l.Info("entry")
+	}
+	if n := l.DroppedWrites(); n < 8 {
+		t.Errorf("Got %d dropped, want at least 8", n)
+	}
+	close(g.gate)
+	l.Shutdown(context.Background())
+	if NewFromOptions(&Options{SyncWriter: &flushBuffer{}}).DroppedWrites() != 0 {
+		t.Error("Want no drops for a destination that doesn't drop")
+	}
+}
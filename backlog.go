@@ -0,0 +1,150 @@
+package logger
+
+import "sync"
+
+// maxBacklogGoroutines is the most goroutines whose Debug entries are kept
+// for Options.DebugBacklog, beyond which those of an arbitrary goroutine
+// are discarded, so goroutines that exit without logging an Error don't
+// leak.
+const maxBacklogGoroutines = 1000
+
+// backlogEntry is an entry kept for Options.DebugBacklog or
+// Options.FlightRecorder.
+type backlogEntry struct {
+	// l is the Logger it was logged by, for its fields and name.
+	l      *Logger
+	s      severity
+	header *buffer
+	msg    []byte
+	fields []Field
+}
+
+// size returns the number of bytes e is counted as holding against
+// Options.MaxMemory.
+func (e backlogEntry) size() int {
+	return e.header.Len() + len(e.msg)
+}
+
+// debugBacklog keeps the most recent Debug entries that weren't written,
+// for each goroutine, see Options.DebugBacklog.
+type debugBacklog struct {
+	size int
+
+	// mem is the state of the Logger whose Options.MaxMemory the entries
+	// are counted against.
+	mem *loggerState
+
+	mu      sync.Mutex
+	entries map[uint64][]backlogEntry
+}
+
+// newDebugBacklog returns a debugBacklog keeping size entries for each
+// goroutine, counted against the Options.MaxMemory of mem, or nil if size
+// isn't greater than zero.
+func newDebugBacklog(size int, mem *loggerState) *debugBacklog {
+	if size <= 0 {
+		return nil
+	}
+	return &debugBacklog{size: size, mem: mem, entries: map[uint64][]backlogEntry{}}
+}
+
+// add keeps e for goroutine id, discarding the oldest entry if there are
+// already size. Until there's room for e within Options.MaxMemory the
+// oldest entries of the goroutine are discarded, and then those of other
+// goroutines.
+func (b *debugBacklog) add(id uint64, e backlogEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entries, ok := b.entries[id]
+	if !ok && len(b.entries) >= maxBacklogGoroutines {
+		for other := range b.entries {
+			b.removeLocked(other)
+			break
+		}
+	}
+	delete(b.entries, id)
+	if len(entries) == b.size {
+		b.mem.releaseMemory(entries[0].size())
+		copy(entries, entries[1:])
+		entries = entries[:len(entries)-1]
+	}
+	for !b.mem.reserveMemory(e.size()) {
+		if len(entries) > 0 {
+			b.mem.releaseMemory(entries[0].size())
+			b.mem.dropMemory()
+			entries = entries[1:]
+			continue
+		}
+		if len(b.entries) == 0 {
+			b.mem.dropMemory()
+			return
+		}
+		for other, kept := range b.entries {
+			for range kept {
+				b.mem.dropMemory()
+			}
+			b.removeLocked(other)
+			break
+		}
+	}
+	b.entries[id] = append(entries, e)
+}
+
+// removeLocked discards the entries kept for goroutine id, and must be
+// called with mu held.
+func (b *debugBacklog) removeLocked(id uint64) {
+	for _, e := range b.entries[id] {
+		b.mem.releaseMemory(e.size())
+	}
+	delete(b.entries, id)
+}
+
+// take removes and returns the entries kept for goroutine id.
+func (b *debugBacklog) take(id uint64) []backlogEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entries := b.entries[id]
+	b.removeLocked(id)
+	return entries
+}
+
+// newBacklogEntry returns a copy of the entry of severity s in buf with
+// header and fields, logged by l.
+func newBacklogEntry(l *Logger, s severity, buf, header *buffer, fields []Field) backlogEntry {
+	h := &buffer{time: header.time, pid: header.pid, file: header.file, line: header.line, record: header.record}
+	h.Write(header.Bytes())
+	return backlogEntry{
+		l:      l,
+		s:      s,
+		header: h,
+		msg:    append([]byte(nil), buf.Bytes()...),
+		fields: append([]Field(nil), fields...),
+	}
+}
+
+// keepDebug keeps the Debug entry in buf with header, which isn't being
+// written, in the backlog of the calling goroutine.
+func (l *Logger) keepDebug(buf, header *buffer, fields []Field) {
+	e := newBacklogEntry(l, debugLog, buf, header, fields)
+	// It's already been kept by the flight recorder, if any.
+	e.header.flight = true
+	l.debugBacklog.add(goroutineID(), e)
+}
+
+// writeBacklog writes the Debug entries kept for the calling goroutine,
+// ahead of an Error, with a "backlog" field so they can be told apart from
+// those that were written when logged. They're written regardless of the
+// minimum severity, which is what kept them from being written when
+// logged.
+func (l *Logger) writeBacklog() {
+	for _, e := range l.debugBacklog.take(goroutineID()) {
+		fields := e.fields[:len(e.fields):len(e.fields)]
+		if len(e.l.fields) > 0 {
+			fields = append(e.l.fields[:len(e.l.fields):len(e.l.fields)], fields...)
+		}
+		buf := l.getBuffer()
+		buf.Write(e.msg)
+		e.l.emitFields(debugLog, buf, e.header, append(fields, Bool("backlog", true)))
+		l.putBuffer(buf)
+	}
+}
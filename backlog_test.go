@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDebugBacklog(t *testing.T) {
+	b := &flushBuffer{}
+	l := NewFromOptions(&Options{SyncWriter: b, DebugBacklog: 2})
+	l.Debug("dropped")
+	l.Named("db").Debugf("query %d", 1)
+	l.DebugFields("query", Int("n", 2))
+	l.Info("info")
+	if got := b.String(); strings.Contains(got, "query") {
+		t.Fatalf("Debug entries written before an Error: %q", got)
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		l.Error("elsewhere")
+	}()
+	wg.Wait()
+	if got := b.String(); strings.Contains(got, "query") {
+		t.Fatalf("Another goroutine's Error wrote the backlog: %q", got)
+	}
+
+	l.Error("failed")
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("Got %q, want 5 lines", lines)
+	}
+	for i, want := range []string{"] db: query 1 backlog=true", "] query n=2 backlog=true", "] failed"} {
+		line := lines[i+2]
+		if !strings.HasSuffix(line, want) {
+			t.Errorf("Line %d is %q, want the suffix %q", i+2, line, want)
+		}
+	}
+	if lines[2][0] != 'D' || lines[3][0] != 'D' {
+		t.Errorf("The backlog should be written as Debug entries: %q", lines)
+	}
+
+	l.Error("again")
+	if got := b.String(); strings.Count(got, "backlog=true") != 2 {
+		t.Errorf("The backlog was written twice: %q", got)
+	}
+}
+
+// Test that the backlog is written even though the minimum severity is what
+// kept its entries from being written when logged.
+func TestDebugBacklogMinSeverity(t *testing.T) {
+	b := &flushBuffer{}
+	l := NewFromOptions(&Options{SyncWriter: b, DebugBacklog: 5, MinSeverity: InfoSeverity})
+	l.With(Str("req", "1")).Debug("context")
+	l.Error("boom")
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "] context req=1 backlog=true") || !strings.HasSuffix(lines[1], "] boom") {
+		t.Errorf("Got %q, want the backlog ahead of the Error", lines)
+	}
+}
+
+func TestDebugBacklogEnabledDebug(t *testing.T) {
+	b := &flushBuffer{}
+	l := NewFromOptions(&Options{SyncWriter: b, DebugBacklog: 2, IncludeDebug: true})
+	l.Debug("written")
+	l.Error("failed")
+	if got := b.String(); !strings.Contains(got, "] written\n") || strings.Contains(got, "backlog=true") {
+		t.Errorf("Enabled Debug entries should be written, not kept: %q", got)
+	}
+}
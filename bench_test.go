@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// benchLog logs a typical entry with l.
+func benchLog(l *Logger) {
+	l.InfoFields("request served", Str("path", "/index.html"), Int("status", 200), Dur("latency", time.Millisecond))
+}
+
+func BenchmarkFormats(b *testing.B) {
+	for _, bm := range []struct {
+		name string
+		o    Options
+	}{
+		{"text", Options{}},
+		{"json", Options{Format: JSONFormat}},
+		{"formatter", Options{Formatter: GlogFormatter{}}},
+		{"metadata", Options{InstanceMetadata: &InstanceMetadata{Provider: "gce", InstanceID: "1234", Zone: "us-central1-a"}}},
+		{"messagehash", Options{MessageHash: true}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			o := bm.o
+			o.SyncWriter = discardWriter{}
+			l := NewFromOptions(&o)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				benchLog(l)
+			}
+		})
+	}
+}
+
+// BenchmarkPooling compares reusing buffers from the free list with
+// allocating a new one for every entry, which is what happens when every
+// buffer is dropped because Options.MaxMemory is tiny.
+func BenchmarkPooling(b *testing.B) {
+	for _, bm := range []struct {
+		name      string
+		maxMemory int64
+	}{
+		{"pooled", 0},
+		{"unpooled", 1},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			l := NewFromOptions(&Options{SyncWriter: discardWriter{}, MaxMemory: bm.maxMemory})
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				benchLog(l)
+			}
+		})
+	}
+}
+
+func BenchmarkSinks(b *testing.B) {
+	b.Run("discard", func(b *testing.B) {
+		benchSink(b, discardWriter{})
+	})
+	b.Run("file", func(b *testing.B) {
+		w, err := NewFileWriter(filepath.Join(b.TempDir(), "bench.log"))
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer w.Close()
+		benchSink(b, w)
+	})
+	b.Run("tcp", func(b *testing.B) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer ln.Close()
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				go io.Copy(io.Discard, conn)
+			}
+		}()
+		w, err := NewSink("tcp://" + ln.Addr().String())
+		if err != nil {
+			b.Fatal(err)
+		}
+		benchSink(b, w)
+	})
+}
+
+// benchSink benchmarks logging to w.
+func benchSink(b *testing.B, w SyncWriter) {
+	l := NewFromOptions(&Options{SyncWriter: w})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchLog(l)
+	}
+}
+
+// BenchmarkConcurrency logs from GOMAXPROCS times the given number of
+// goroutines at once.
+func BenchmarkConcurrency(b *testing.B) {
+	for name, format := range map[string]Format{"text": TextFormat, "json": JSONFormat} {
+		for _, parallelism := range []int{1, 4, 16} {
+			b.Run(fmt.Sprintf("%s/goroutines=%dx", name, parallelism), func(b *testing.B) {
+				l := NewFromOptions(&Options{SyncWriter: discardWriter{}, Format: format})
+				b.ReportAllocs()
+				b.SetParallelism(parallelism)
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						benchLog(l)
+					}
+				})
+			})
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package logger
+
+import (
+	"fmt"
+)
+
+// Broadcast forwards every log to a set of child Loggers, each of which
+// applies its own options. For example, during development Debug logs can
+// go to a file while only Info and above go to the console:
+//
+//	b := logger.NewBroadcast(
+//		logger.NewFromOptions(&logger.Options{SyncWriter: f, IncludeDebug: true}),
+//		logger.New(),
+//	)
+//
+// The calling function is reported correctly by each child. A Fatal log is
+// written to every child before the program exits.
+type Broadcast struct {
+	children []*Logger
+}
+
+// NewBroadcast returns a Broadcast that forwards to children.
+func NewBroadcast(children ...*Logger) *Broadcast {
+	return &Broadcast{children: children}
+}
+
+// print sends an already rendered msg to every child, exiting if s is fatalLog.
+func (b *Broadcast) print(s severity, msg string, fields []Field) {
+	for _, child := range b.children {
+		// Skip print and the exported method that called it.
+		child.logDepth(s, 1, []byte(msg), fields)
+	}
+	if s == fatalLog {
+		exitFatal(b.children...)
+	}
+}
+
+// Debug logs to every child that has Debug logs enabled.
+// Arguments are handled in the manner of fmt.Print.
+func (b *Broadcast) Debug(args ...interface{}) {
+	b.print(debugLog, fmt.Sprint(args...), nil)
+}
+
+// Debugf logs to every child that has Debug logs enabled.
+// Arguments are handled in the manner of fmt.Printf.
+func (b *Broadcast) Debugf(format string, args ...interface{}) {
+	b.print(debugLog, fmt.Sprintf(format, args...), nil)
+}
+
+// DebugFields logs msg along with fields to every child that has Debug logs enabled.
+func (b *Broadcast) DebugFields(msg string, fields ...Field) {
+	b.print(debugLog, msg, fields)
+}
+
+// Info logs informational logs.
+// Arguments are handled in the manner of fmt.Print.
+func (b *Broadcast) Info(args ...interface{}) {
+	b.print(infoLog, fmt.Sprint(args...), nil)
+}
+
+// Infof logs informational logs.
+// Arguments are handled in the manner of fmt.Printf.
+func (b *Broadcast) Infof(format string, args ...interface{}) {
+	b.print(infoLog, fmt.Sprintf(format, args...), nil)
+}
+
+// InfoFields logs msg along with fields.
+func (b *Broadcast) InfoFields(msg string, fields ...Field) {
+	b.print(infoLog, msg, fields)
+}
+
+// Warning logs warning logs.
+// Arguments are handled in the manner of fmt.Print.
+func (b *Broadcast) Warning(args ...interface{}) {
+	b.print(warningLog, fmt.Sprint(args...), nil)
+}
+
+// Warningf logs warning logs.
+// Arguments are handled in the manner of fmt.Printf.
+func (b *Broadcast) Warningf(format string, args ...interface{}) {
+	b.print(warningLog, fmt.Sprintf(format, args...), nil)
+}
+
+// WarningFields logs msg along with fields.
+func (b *Broadcast) WarningFields(msg string, fields ...Field) {
+	b.print(warningLog, msg, fields)
+}
+
+// Error logs error logs.
+// Arguments are handled in the manner of fmt.Print.
+func (b *Broadcast) Error(args ...interface{}) {
+	b.print(errorLog, fmt.Sprint(args...), nil)
+}
+
+// Errorf logs error logs.
+// Arguments are handled in the manner of fmt.Printf.
+func (b *Broadcast) Errorf(format string, args ...interface{}) {
+	b.print(errorLog, fmt.Sprintf(format, args...), nil)
+}
+
+// ErrorFields logs msg along with fields.
+func (b *Broadcast) ErrorFields(msg string, fields ...Field) {
+	b.print(errorLog, msg, fields)
+}
+
+// Fatal logs a fatal log to every child and then exits the program.
+// Arguments are handled in the manner of fmt.Print.
+func (b *Broadcast) Fatal(args ...interface{}) {
+	b.print(fatalLog, fmt.Sprint(args...), nil)
+}
+
+// Fatalf logs a fatal log to every child and then exits the program.
+// Arguments are handled in the manner of fmt.Printf.
+func (b *Broadcast) Fatalf(format string, args ...interface{}) {
+	b.print(fatalLog, fmt.Sprintf(format, args...), nil)
+}
+
+// FatalFields logs msg along with fields to every child and then exits the program.
+func (b *Broadcast) FatalFields(msg string, fields ...Field) {
+	b.print(fatalLog, msg, fields)
+}
+
+// Raw sends the string s to every child without any additional formatting.
+func (b *Broadcast) Raw(s string) {
+	for _, child := range b.children {
+		child.Raw(s)
+	}
+}
+
+// Assert that we implement Interface:
+var _ Interface = (*Broadcast)(nil)
@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBroadcast(t *testing.T) {
+	verbose := NewFromOptions(&Options{SyncWriter: &flushBuffer{}, IncludeDebug: true})
+	terse := NewFromOptions(&Options{SyncWriter: &flushBuffer{}})
+	b := NewBroadcast(verbose, terse)
+
+	b.Debugf("debug %d", 1)
+	b.Info("info")
+	b.WarningFields("warning", Int("n", 2))
+
+	v := verbose.w.(*flushBuffer).String()
+	if !strings.Contains(v, "] debug 1\n") || !strings.Contains(v, "] info\n") || !strings.Contains(v, "] warning n=2\n") {
+		t.Errorf("Verbose child missing logs: %q", v)
+	}
+	tr := terse.w.(*flushBuffer).String()
+	if strings.Contains(tr, "debug") || !strings.Contains(tr, "] info\n") {
+		t.Errorf("Terse child should only have Info and above: %q", tr)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(v+tr), "\n") {
+		if !strings.Contains(line, " broadcast_test.go:") {
+			t.Errorf("Wrong caller reported: %q", line)
+		}
+	}
+}
+
+func TestBroadcastFatal(t *testing.T) {
+	defer func(previous func(int)) { osExit = previous }(osExit)
+	exits := 0
+	osExit = func(code int) { exits++ }
+
+	first := NewFromOptions(&Options{SyncWriter: &flushBuffer{}})
+	second := NewFromOptions(&Options{SyncWriter: &flushBuffer{}})
+	NewBroadcast(first, second).Fatal("foo")
+	if exits != 1 {
+		t.Errorf("Should exit exactly once, got %d", exits)
+	}
+	for _, l := range []*Logger{first, second} {
+		if got := l.w.(*flushBuffer).String(); !strings.HasPrefix(got, "F") || !strings.Contains(got, "] foo\n") {
+			t.Errorf("Fatal not written to every child: %q", got)
+		}
+	}
+}
+
+// Test that a Broadcast exits after a Fatal log as a Logger does.
+func TestBroadcastFatalExit(t *testing.T) {
+	defer func(previous func(int)) { osExit = previous }(osExit)
+	osExit = func(code int) {}
+
+	registered := &closeBuffer{}
+	defer FlushOnExit(NewFromOptions(&Options{SyncWriter: registered}))()
+	b := &flushBuffer{}
+	child := NewFromOptions(&Options{SyncWriter: b, Lifecycle: &Lifecycle{OmitArgs: true}})
+	NewBroadcast(child).Fatal("foo")
+	if !registered.synced {
+		t.Error("Loggers registered with FlushOnExit weren't flushed")
+	}
+	if got := b.String(); !strings.Contains(got, "] process exiting reason=fatal") {
+		t.Errorf("No exiting entry written: %q", got)
+	}
+
+	// The entry still gets out if writing it panics.
+	w := &panicWriter{}
+	NewBroadcast(NewFromOptions(&Options{SyncWriter: w})).Fatal("goodbye")
+	if got := w.String(); !strings.Contains(got, "] goodbye\ngoroutine ") {
+		t.Errorf("Fatal entry not written in the manner of Crash: %q", got)
+	}
+}
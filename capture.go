@@ -0,0 +1,154 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry is a single log entry, as passed to a Formatter or to LogRecord, or
+// as parsed back from the output of a Logger by CaptureLogs.
+type Entry struct {
+	// Severity is the name of the severity, e.g. "INFO" or "WARNING".
+	Severity string
+
+	// Time is when the entry was logged. The header doesn't record the
+	// year, so the current year is assumed.
+	Time time.Time
+
+	// PID is the process id, or Options.PID.
+	PID int
+
+	File string
+	Line int
+
+	// Message is everything after the header, including any fields. Lines
+	// that don't start with a header, such as the stack traces written for
+	// Crash, are appended to the Message of the preceding entry, as are the
+	// messages of continuation lines, see Options.MarkContinuations. For
+	// JSONFormat it's only the message, without the fields.
+	Message string
+
+	// Fields are any Options.InstanceMetadata, the fields passed to the
+	// *Fields methods, and the msg_hash for Options.MessageHash, in that
+	// order. They are only set for entries passed to a Formatter, or
+	// returned by RecordingLogger.Entries, not those returned by
+	// CaptureLogs. For LogRecord they're the record's fields.
+	Fields []Field
+}
+
+// headerRegex matches the header written by formatHeader, which has a lower
+// case severity letter for continuation lines, see
+// Options.MarkContinuations.
+var headerRegex = regexp.MustCompile(`^([A-Za-z])(\d\d)(\d\d) (\d\d):(\d\d):(\d\d)(?:\.(\d{1,9}))? +(\d+) ([^:]+):(\d+)\] ?(.*)$`)
+
+// CaptureLogs runs f with the Logger writing to an in-memory buffer instead
+// of its destination, and returns the entries logged while f ran. The
+// original destination is restored when f returns, even if it panics.
+//
+// CaptureLogs is intended for tests, and only understands the default
+// header format and JSONFormat, i.e. not Options.StdLogHeader or renamed
+// members in Options.EncoderConfig.
+func (l *Logger) CaptureLogs(f func()) []Entry {
+	w := &captureWriter{}
+	l.wMu.Lock()
+	orig := l.w
+	l.w = w
+	l.wMu.Unlock()
+	func() {
+		defer func() {
+			l.wMu.Lock()
+			l.w = orig
+			l.wMu.Unlock()
+		}()
+		f()
+	}()
+	return parseEntries(w.String(), l.timeNow().Year(), l.timeLocation())
+}
+
+// parseEntries parses the entries out of s, which were logged in year with
+// timestamps in loc.
+func parseEntries(s string, year int, loc *time.Location) []Entry {
+	ret := []Entry{}
+	for _, line := range strings.Split(strings.TrimSuffix(s, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		if line[0] == '{' {
+			var j struct {
+				Severity  string
+				Timestamp time.Time
+				PID       int
+				Caller    struct {
+					File string
+					Line int
+				}
+				Message string
+			}
+			if err := json.Unmarshal([]byte(line), &j); err == nil {
+				ret = append(ret, Entry{Severity: j.Severity, Time: j.Timestamp, PID: j.PID, File: j.Caller.File, Line: j.Caller.Line, Message: j.Message})
+				continue
+			}
+		}
+		m := headerRegex.FindStringSubmatch(line)
+		if m != nil && m[1][0] >= 'a' && m[1][0] <= 'z' && len(ret) > 0 {
+			ret[len(ret)-1].Message += "\n" + m[11]
+			continue
+		}
+		if m == nil {
+			if len(ret) == 0 {
+				ret = append(ret, Entry{Message: line})
+			} else {
+				ret[len(ret)-1].Message += "\n" + line
+			}
+			continue
+		}
+		// Month, day, hour, minute, second, and nanoseconds.
+		m[7] = (m[7] + "000000000")[:9]
+		n := make([]int, 6)
+		for i := range n {
+			n[i], _ = strconv.Atoi(m[i+2])
+		}
+		s, ok := severityFromChar(strings.ToUpper(m[1])[0])
+		if !ok {
+			s = infoLog
+		}
+		pid, _ := strconv.Atoi(m[8])
+		lineNum, _ := strconv.Atoi(m[10])
+		ret = append(ret, Entry{
+			Severity: s.name(),
+			Time:     time.Date(year, time.Month(n[0]), n[1], n[2], n[3], n[4], n[5], loc),
+			PID:      pid,
+			File:     m[9],
+			Line:     lineNum,
+			Message:  m[11],
+		})
+	}
+	return ret
+}
+
+// captureWriter is the SyncWriter used by CaptureLogs.
+type captureWriter struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (c *captureWriter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.Write(p)
+}
+
+func (c *captureWriter) Sync() error {
+	return nil
+}
+
+func (c *captureWriter) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.String()
+}
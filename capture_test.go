@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCaptureLogs(t *testing.T) {
+	defer func(now func() time.Time) { timeNow = now }(timeNow)
+	ts := time.Date(time.Now().Year(), 3, 4, 5, 6, 7, 891000, time.Local)
+	timeNow = func() time.Time { return ts }
+
+	newTestLogger()
+	l := testLogger
+	l.Info("before")
+	entries := l.CaptureLogs(func() {
+		l.Warning("first")
+		l.InfoFields("second", Int("n", 2))
+	})
+	l.Info("after")
+
+	if len(entries) != 2 {
+		t.Fatalf("Wrong number of entries: %#v", entries)
+	}
+	want := Entry{Severity: "WARNING", Time: ts, PID: pid, File: "capture_test.go", Line: entries[0].Line, Message: "first"}
+	if !reflect.DeepEqual(entries[0], want) {
+		t.Errorf("Got %#v want %#v", entries[0], want)
+	}
+	if entries[0].Line == 0 {
+		t.Error("Line not parsed")
+	}
+	if got := entries[1]; got.Severity != "INFO" || got.Message != "second n=2" {
+		t.Errorf("Wrong second entry: %#v", got)
+	}
+	if !contains("] before\n", t) || !contains("] after\n", t) || contains("first", t) {
+		t.Errorf("Original destination not restored: %q", contents())
+	}
+}
+
+func TestCaptureLogsPanic(t *testing.T) {
+	newTestLogger()
+	l := testLogger
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected panic")
+			}
+		}()
+		l.CaptureLogs(func() {
+			panic(errors.New("boom"))
+		})
+	}()
+	l.Info("restored")
+	if !contains("] restored\n", t) {
+		t.Errorf("Original destination not restored after panic: %q", contents())
+	}
+}
+
+func TestParseEntriesContinuation(t *testing.T) {
+	entries := parseEntries("F0102 15:04:05.000000 123 main.go:10] oops\ngoroutine 1 [running]:\n", 2006, time.UTC)
+	if len(entries) != 1 || entries[0].Message != "oops\ngoroutine 1 [running]:" || entries[0].Severity != "FATAL" {
+		t.Errorf("Wrong entries: %#v", entries)
+	}
+}
@@ -0,0 +1,221 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ParentEnvVar is the environment variable set by StartChild in the
+// environment of the child process. It holds the file descriptor of the
+// pipe to the parent, which New and NewFromOptions in the child use as the
+// destination if Options.SyncWriter and Options.SeverityWriters aren't set.
+const ParentEnvVar = "LOGGER_PARENT_FD"
+
+// maxChildLineLength is the longest entry read from a child process, beyond
+// which the rest of its output is discarded.
+const maxChildLineLength = 1024 * 1024
+
+// Child is a child process started by StartChild.
+type Child struct {
+	cmd *exec.Cmd
+
+	// done is closed once all of the child's entries have been written.
+	done chan struct{}
+}
+
+// StartChild starts cmd, as cmd.Start does, with a pipe passed to it in
+// cmd.ExtraFiles and its file descriptor in ParentEnvVar, so the entries of
+// the Loggers in the child are written by l, with a "child" field of name.
+// The child writes JSONFormat entries to the pipe, which keep their time,
+// pid, file, line, and fields, and are written by l in the order the child
+// wrote them, as they arrive, e.g.:
+//
+//	cmd := exec.Command("worker")
+//	child, err := l.StartChild(cmd, "worker")
+//	if err != nil {
+//		return err
+//	}
+//	return child.Wait()
+//
+// Lines that aren't JSON, e.g. from a child that doesn't use this package,
+// are written as Info entries. Passing files to children isn't supported on
+// Windows.
+func (l *Logger) StartChild(cmd *exec.Cmd, name string) (*Child, error) {
+	if runtime.GOOS == "windows" {
+		return nil, errors.New("logger: StartChild isn't supported on windows")
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer w.Close()
+	cmd.ExtraFiles = append(cmd.ExtraFiles, w)
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	// Files in ExtraFiles are numbered from 3, after stdin, stdout, and
+	// stderr.
+	cmd.Env = append(env[:len(env):len(env)], fmt.Sprintf("%s=%d", ParentEnvVar, 2+len(cmd.ExtraFiles)))
+	if err := cmd.Start(); err != nil {
+		r.Close()
+		return nil, err
+	}
+	c := &Child{cmd: cmd, done: make(chan struct{})}
+	go func() {
+		defer close(c.done)
+		defer r.Close()
+		l.copyChild(r, name)
+	}()
+	return c, nil
+}
+
+// Wait waits for the child to exit, as cmd.Wait does, and then for all of
+// its entries to be written, i.e. until every process that was passed the
+// pipe, including any children of the child, has closed it.
+func (c *Child) Wait() error {
+	err := c.cmd.Wait()
+	<-c.done
+	return err
+}
+
+// copyChild writes the entries read from r, the pipe from the child name.
+func (l *Logger) copyChild(r io.Reader, name string) {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 4096), maxChildLineLength)
+	for s.Scan() {
+		e, ok := decodeChildEntry(s.Bytes())
+		if !ok {
+			e = Entry{Severity: "INFO", Message: s.Text()}
+		}
+		e.Fields = append([]Field{Str("child", name)}, e.Fields...)
+		if err := l.LogRecord(e); err != nil {
+			l.diagnosef("child %s: %s", name, err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		l.diagnosef("child %s: %s", name, err)
+		// Don't block the child.
+		io.Copy(io.Discard, r)
+	}
+}
+
+// decodeChildEntry decodes line, a JSONFormat entry, keeping the members
+// other than those of the header, in order, as its fields.
+func decodeChildEntry(line []byte) (Entry, bool) {
+	var e Entry
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return e, false
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return e, false
+		}
+		key, _ := t.(string)
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return e, false
+		}
+		switch key {
+		case "schema_version":
+		case "severity":
+			e.Severity, _ = v.(string)
+		case "timestamp":
+			s, _ := v.(string)
+			e.Time, _ = time.Parse(time.RFC3339Nano, s)
+		case "pid":
+			e.PID = childInt(v)
+		case "caller":
+			caller, _ := v.(map[string]interface{})
+			e.File, _ = caller["file"].(string)
+			e.Line = childInt(caller["line"])
+		case "file":
+			// Written by children using schema version 1.
+			e.File, _ = v.(string)
+		case "line":
+			e.Line = childInt(v)
+		case "message":
+			e.Message, _ = v.(string)
+		default:
+			e.Fields = append(e.Fields, childField(key, v))
+		}
+	}
+	return e, e.Severity != ""
+}
+
+func childInt(v interface{}) int {
+	n, _ := v.(json.Number)
+	i, _ := strconv.Atoi(string(n))
+	return i
+}
+
+// childField returns the field for the member key of an entry, with the
+// value v decoded by encoding/json.
+func childField(key string, v interface{}) Field {
+	switch v := v.(type) {
+	case string:
+		return Str(key, v)
+	case bool:
+		return Bool(key, v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return Int64(key, i)
+		}
+		f, _ := v.Float64()
+		return Float64(key, f)
+	}
+	return Any(key, v)
+}
+
+// parentPipe is the pipe to the parent process, see ParentEnvVar.
+var parentPipe struct {
+	once sync.Once
+	w    SyncWriter
+}
+
+// parentWriter returns the pipe to the parent process, or nil if this
+// process wasn't started by StartChild.
+func parentWriter() SyncWriter {
+	parentPipe.once.Do(func() {
+		fd, err := strconv.Atoi(os.Getenv(ParentEnvVar))
+		if err != nil || fd < 3 {
+			return
+		}
+		parentPipe.w = &pipeWriter{f: os.NewFile(uintptr(fd), "logger-parent")}
+	})
+	return parentPipe.w
+}
+
+// pipeWriter is the SyncWriter for the pipe to the parent process. It's
+// shared by all the Loggers of the process, so it serializes writes to keep
+// entries whole, and isn't an io.Closer, so Shutdown doesn't close it.
+type pipeWriter struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// Write implements SyncWriter.
+func (p *pipeWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.f.Write(b)
+}
+
+// Sync implements SyncWriter. Pipes can't be synced, and entries are
+// written to them immediately.
+func (p *pipeWriter) Sync() error {
+	return nil
+}
@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// TestChildHelperProcess is run as the child process by TestStartChild.
+func TestChildHelperProcess(t *testing.T) {
+	if os.Getenv("LOGGER_TEST_CHILD") != "1" {
+		t.Skip("only run as a child process")
+	}
+	l := NewFromOptions(&Options{})
+	l.InfoFields("from child", Int("n", 1), Str("s", "a b"))
+	l.Named("worker").Warning("second")
+	l.Error("third")
+}
+
+func TestStartChild(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not supported on windows")
+	}
+	b := &flushBuffer{}
+	l := NewFromOptions(&Options{SyncWriter: b})
+	cmd := exec.Command(os.Args[0], "-test.run=^TestChildHelperProcess$")
+	cmd.Env = append(os.Environ(), "LOGGER_TEST_CHILD=1")
+	child, err := l.StartChild(cmd, "helper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := child.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	entries := parseEntries(b.String(), 2000, time.UTC)
+	var got []Entry
+	for _, e := range entries {
+		// Skip the output of the test framework, e.g. "PASS".
+		if e.File == "child_test.go" {
+			got = append(got, e)
+		}
+	}
+	if len(got) != 3 {
+		t.Fatalf("Got %d entries from the child, want 3: %q", len(got), b.String())
+	}
+	for i, want := range []struct {
+		severity, message string
+	}{
+		{"INFO", `from child child=helper n=1 s="a b"`},
+		{"WARNING", "second child=helper component=worker"},
+		{"ERROR", "third child=helper"},
+	} {
+		if got[i].Severity != want.severity || got[i].Message != want.message {
+			t.Errorf("Entry %d is %s %q, want %s %q", i, got[i].Severity, got[i].Message, want.severity, want.message)
+		}
+		if got[i].PID != cmd.Process.Pid {
+			t.Errorf("Entry %d has pid %d, want the child's, %d", i, got[i].PID, cmd.Process.Pid)
+		}
+	}
+}
+
+func TestDecodeChildEntry(t *testing.T) {
+	e, ok := decodeChildEntry([]byte(`{"schema_version":2,"severity":"WARNING","timestamp":"2021-03-04T05:06:07.000001Z","pid":12,"caller":{"file":"main.go","line":3,"function":"main","package":"main"},"message":"m","b":true,"f":1.5,"g":{"x":1}}`))
+	if !ok || e.Severity != "WARNING" || e.PID != 12 || e.File != "main.go" || e.Line != 3 || e.Message != "m" || e.Time.Nanosecond() != 1000 {
+		t.Fatalf("Wrong entry: %v %+v", ok, e)
+	}
+	if len(e.Fields) != 3 || e.Fields[0].Key != "b" || e.Fields[1].Key != "f" || e.Fields[2].Key != "g" {
+		t.Errorf("Wrong fields: %+v", e.Fields)
+	}
+	e, ok = decodeChildEntry([]byte(`{"schema_version":1,"severity":"INFO","file":"old.go","line":4,"message":"m"}`))
+	if !ok || e.File != "old.go" || e.Line != 4 || len(e.Fields) != 0 {
+		t.Errorf("Wrong version 1 entry: %v %+v", ok, e)
+	}
+	if _, ok := decodeChildEntry([]byte("not json")); ok {
+		t.Error("Non-JSON line decoded")
+	}
+}
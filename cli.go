@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CLIPreset selects the configuration of a Logger for a command line tool,
+// see NewCLI.
+type CLIPreset int
+
+const (
+	// QuietCLI writes Warning and more severe entries, without headers, e.g.:
+	//
+	//	warning: config not found path=/etc/app.conf
+	QuietCLI CLIPreset = iota
+
+	// NormalCLI also writes Info entries, which have no severity prefix, e.g.:
+	//
+	//	copied 3 files
+	//	warning: config not found path=/etc/app.conf
+	NormalCLI
+
+	// VerboseCLI writes all entries, including Debug, with their call sites,
+	// e.g.:
+	//
+	//	main.go:12: debug: reading path=/etc/app.conf
+	//	main.go:14: warning: config not found path=/etc/app.conf
+	VerboseCLI
+)
+
+// NewCLI returns a Logger for a command line tool, configured by preset,
+// which writes to os.Stderr with a CLIFormatter, e.g. selecting the preset
+// from flags:
+//
+//	preset := logger.NormalCLI
+//	if *quiet {
+//		preset = logger.QuietCLI
+//	} else if *verbose {
+//		preset = logger.VerboseCLI
+//	}
+//	l := logger.NewCLI(preset)
+//
+// LevelEnvVar still applies.
+func NewCLI(preset CLIPreset) *Logger {
+	o := &Options{SyncWriter: os.Stderr, Formatter: CLIFormatter{}}
+	switch preset {
+	case QuietCLI:
+		o.MinSeverity = WarningSeverity
+	case NormalCLI:
+		o.MinSeverity = InfoSeverity
+	default:
+		o.IncludeDebug = true
+		o.Formatter = CLIFormatter{Caller: true}
+	}
+	return NewFromOptions(o)
+}
+
+// CLIFormatter is a terse Formatter for the output of command line tools,
+// without timestamps, see NewCLI. Entries other than Info are prefixed
+// with their severity in lower case, e.g. "error: ". It's registered with
+// RegisterEncoder as "cli".
+type CLIFormatter struct {
+	// Caller prefixes each entry with its call site, e.g. "main.go:12: ".
+	Caller bool
+}
+
+func init() {
+	RegisterEncoder("cli", func() Formatter { return CLIFormatter{} })
+}
+
+// Format implements Formatter.
+func (c CLIFormatter) Format(entry Entry, buf *bytes.Buffer) {
+	if c.Caller {
+		buf.WriteString(entry.File)
+		buf.WriteByte(':')
+		buf.WriteString(strconv.Itoa(entry.Line))
+		buf.WriteString(": ")
+	}
+	if !strings.EqualFold(entry.Severity, infoLog.name()) {
+		buf.WriteString(strings.ToLower(entry.Severity))
+		buf.WriteString(": ")
+	}
+	buf.WriteString(strings.TrimSuffix(entry.Message, "\n"))
+	suffix := &buffer{}
+	for _, f := range entry.Fields {
+		f.appendTo(suffix)
+	}
+	buf.Write(suffix.Bytes())
+	buf.WriteByte('\n')
+}
@@ -0,0 +1,48 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"regexp"
+	"testing"
+)
+
+func TestCLIPresets(t *testing.T) {
+	for _, tc := range []struct {
+		preset CLIPreset
+		want   string
+	}{
+		{QuietCLI, "warning: config not found path=/etc/app.conf\nerror: failed\n"},
+		{NormalCLI, "copied 3 files\nwarning: config not found path=/etc/app.conf\nerror: failed\n"},
+		{VerboseCLI, "cli_test.go:L: debug: reading\ncli_test.go:L: copied 3 files\ncli_test.go:L: warning: config not found path=/etc/app.conf\ncli_test.go:L: error: failed\n"},
+	} {
+		l := NewCLI(tc.preset)
+		if l.writer() != os.Stderr {
+			t.Errorf("Preset %d writes to %v, want stderr", tc.preset, l.writer())
+		}
+		b := &flushBuffer{}
+		l.w = b
+		l.Debug("reading")
+		l.Infof("copied %d files", 3)
+		l.WarningFields("config not found", Str("path", "/etc/app.conf"))
+		l.Error("failed")
+		if got := cliLineRegex.ReplaceAllString(b.String(), ".go:L:"); got != tc.want {
+			t.Errorf("Preset %d: got %q, want %q", tc.preset, got, tc.want)
+		}
+	}
+}
+
+func TestCLIFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	CLIFormatter{}.Format(Entry{Severity: "INFO", Message: "two\nlines", Fields: []Field{Int("n", 2)}}, &buf)
+	if got, want := buf.String(), "two\nlines n=2\n"; got != want {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+	if f, err := NewEncoder("cli"); err != nil || f == nil {
+		t.Errorf("The cli encoder isn't registered: %v", err)
+	}
+}
+
+// cliLineRegex matches the line number of a call site written by
+// CLIFormatter.
+var cliLineRegex = regexp.MustCompile(`\.go:\d+:`)
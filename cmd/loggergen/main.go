@@ -0,0 +1,256 @@
+// Command loggergen generates strongly typed logging methods for an
+// organization's standard events from a JSON schema, so the events always
+// have the same fields with the same types, checked at compile time. It's
+// intended to be run by go generate:
+//
+//	//go:generate go run github.com/jcgregorio/logger/cmd/loggergen -schema events.json -out events_log.go
+//
+// where events.json is, e.g.:
+//
+//	{
+//	  "package": "events",
+//	  "type": "Events",
+//	  "events": [
+//	    {
+//	      "name": "UserLogin",
+//	      "severity": "info",
+//	      "message": "user login",
+//	      "fields": [
+//	        {"key": "user_id", "type": "string"},
+//	        {"key": "latency", "type": "duration"}
+//	      ]
+//	    }
+//	  ]
+//	}
+//
+// which generates:
+//
+//	type Events struct{ l *logger.Logger }
+//
+//	func NewEvents(l *logger.Logger) *Events
+//
+//	// UserLogin logs "user login" at Info.
+//	func (e *Events) UserLogin(userID string, latency time.Duration)
+//
+// The field types are string, int, int64, float64, bool, duration, time,
+// error, and any, along with secret and pii, which are strings that are
+// masked or hashed, see logger.SecretStr and logger.PIIStr. A field of type
+// error must have the key "error", and is passed as the parameter err.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"log"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// Schema describes the events to generate methods for.
+type Schema struct {
+	// Package is the name of the generated package.
+	Package string `json:"package"`
+
+	// Type is the name of the generated type holding the methods.
+	Type string `json:"type"`
+
+	Events []Event `json:"events"`
+}
+
+// Event is a single event, logged by a method of the same name.
+type Event struct {
+	Name     string  `json:"name"`
+	Severity string  `json:"severity"`
+	Message  string  `json:"message"`
+	Fields   []Field `json:"fields"`
+}
+
+// Field is a field of an event, which is a parameter of its method.
+type Field struct {
+	Key  string `json:"key"`
+	Type string `json:"type"`
+}
+
+// fieldTypes maps the field types to the Go type of the parameter and the
+// Field constructor.
+var fieldTypes = map[string]struct{ goType, constructor string }{
+	"string":   {"string", "Str"},
+	"int":      {"int", "Int"},
+	"int64":    {"int64", "Int64"},
+	"float64":  {"float64", "Float64"},
+	"bool":     {"bool", "Bool"},
+	"duration": {"time.Duration", "Dur"},
+	"time":     {"time.Time", "Time"},
+	"error":    {"error", "Err"},
+	"any":      {"interface{}", "Any"},
+	"secret":   {"string", "SecretStr"},
+	"pii":      {"string", "PIIStr"},
+}
+
+// methods maps the severities to the Logger methods.
+var methods = map[string]string{
+	"debug":   "DebugFields",
+	"info":    "InfoFields",
+	"warning": "WarningFields",
+	"error":   "ErrorFields",
+	"fatal":   "FatalFields",
+}
+
+// param is a parameter of a generated method.
+type param struct {
+	Name, GoType, Key, Constructor string
+}
+
+// method is a generated method.
+type method struct {
+	Name, Message, Severity, LoggerMethod string
+	Params                                []param
+}
+
+var tmpl = template.Must(template.New("").Parse(`// Code generated by loggergen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- if .UsesTime}}
+	"time"
+{{end}}
+	"github.com/jcgregorio/logger"
+)
+
+// {{.Type}} logs the standard events.
+type {{.Type}} struct {
+	l *logger.Logger
+}
+
+// New{{.Type}} returns a new {{.Type}} that logs to l.
+func New{{.Type}}(l *logger.Logger) *{{.Type}} {
+	return &{{.Type}}{l: l.WithDepth(1)}
+}
+{{range .Methods}}
+// {{.Name}} logs {{printf "%q" .Message}} at {{.Severity}}.
+func (e *{{$.Type}}) {{.Name}}({{range $i, $p := .Params}}{{if $i}}, {{end}}{{$p.Name}} {{$p.GoType}}{{end}}) {
+	e.l.{{.LoggerMethod}}({{printf "%q" .Message}}{{range .Params}}, {{if eq .Constructor "Err"}}logger.Err({{.Name}}){{else}}logger.{{.Constructor}}({{printf "%q" .Key}}, {{.Name}}){{end}}{{end}})
+}
+{{end}}`))
+
+// generate returns the Go source for schema.
+func generate(schema *Schema) ([]byte, error) {
+	if !token.IsIdentifier(schema.Package) {
+		return nil, fmt.Errorf("invalid package %q", schema.Package)
+	}
+	if !token.IsIdentifier(schema.Type) || !token.IsExported(schema.Type) {
+		return nil, fmt.Errorf("invalid type %q, it must be exported", schema.Type)
+	}
+	data := struct {
+		Package, Type string
+		UsesTime      bool
+		Methods       []method
+	}{Package: schema.Package, Type: schema.Type}
+	for _, e := range schema.Events {
+		for _, f := range e.Fields {
+			if strings.HasPrefix(fieldTypes[f.Type].goType, "time.") {
+				data.UsesTime = true
+			}
+		}
+	}
+	names := map[string]bool{}
+	for _, e := range schema.Events {
+		if !token.IsIdentifier(e.Name) || !token.IsExported(e.Name) {
+			return nil, fmt.Errorf("invalid event name %q, it must be exported", e.Name)
+		}
+		if names[e.Name] {
+			return nil, fmt.Errorf("duplicate event %q", e.Name)
+		}
+		names[e.Name] = true
+		severity := strings.ToLower(e.Severity)
+		m := method{Name: e.Name, Message: e.Message, Severity: strings.Title(severity), LoggerMethod: methods[severity]}
+		if m.LoggerMethod == "" {
+			return nil, fmt.Errorf("event %s: invalid severity %q", e.Name, e.Severity)
+		}
+		params := map[string]bool{}
+		for _, f := range e.Fields {
+			t, ok := fieldTypes[f.Type]
+			if !ok {
+				return nil, fmt.Errorf("event %s: field %q has invalid type %q", e.Name, f.Key, f.Type)
+			}
+			if f.Type == "error" && f.Key != "error" {
+				return nil, fmt.Errorf("event %s: field %q of type error must have the key \"error\"", e.Name, f.Key)
+			}
+			name := paramName(f.Key)
+			if f.Type == "error" {
+				name = "err"
+			}
+			// Parameters mustn't shadow the imported packages.
+			if name == "logger" || name == "time" && data.UsesTime {
+				name += "_"
+			}
+			if !token.IsIdentifier(name) || params[name] || name == "e" {
+				return nil, fmt.Errorf("event %s: field %q isn't usable as a parameter name", e.Name, f.Key)
+			}
+			params[name] = true
+			m.Params = append(m.Params, param{Name: name, GoType: t.goType, Key: f.Key, Constructor: t.constructor})
+		}
+		data.Methods = append(data.Methods, m)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+// initialisms are written in upper case in parameter names, as golint
+// suggests.
+var initialisms = map[string]bool{"id": true, "url": true, "http": true, "ip": true, "uri": true, "api": true}
+
+// paramName converts a snake_case or dotted key into a lowerCamelCase
+// parameter name, e.g. "user_id" into "userID".
+func paramName(key string) string {
+	parts := strings.FieldsFunc(key, func(r rune) bool { return r == '_' || r == '.' || r == '-' })
+	var b strings.Builder
+	for i, p := range parts {
+		switch {
+		case i == 0:
+			b.WriteString(strings.ToLower(p))
+		case initialisms[strings.ToLower(p)]:
+			b.WriteString(strings.ToUpper(p))
+		default:
+			b.WriteString(strings.ToUpper(p[:1]) + p[1:])
+		}
+	}
+	name := b.String()
+	if token.IsKeyword(name) {
+		name += "_"
+	}
+	return name
+}
+
+func main() {
+	schemaPath := flag.String("schema", "", "The JSON schema of the events.")
+	out := flag.String("out", "", "The file to write the generated code to.")
+	flag.Parse()
+	if *schemaPath == "" || *out == "" {
+		log.Fatal("loggergen: -schema and -out are required")
+	}
+	b, err := os.ReadFile(*schemaPath)
+	if err != nil {
+		log.Fatalf("loggergen: %s", err)
+	}
+	var schema Schema
+	if err := json.Unmarshal(b, &schema); err != nil {
+		log.Fatalf("loggergen: parsing %s: %s", *schemaPath, err)
+	}
+	src, err := generate(&schema)
+	if err != nil {
+		log.Fatalf("loggergen: %s: %s", *schemaPath, err)
+	}
+	if err := os.WriteFile(*out, src, 0644); err != nil {
+		log.Fatalf("loggergen: %s", err)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	src, err := generate(&Schema{
+		Package: "events",
+		Type:    "Events",
+		Events: []Event{
+			{Name: "UserLogin", Severity: "info", Message: "user login", Fields: []Field{{Key: "user_id", Type: "string"}, {Key: "latency", Type: "duration"}}},
+			{Name: "PaymentFailed", Severity: "ERROR", Message: "payment failed", Fields: []Field{{Key: "error", Type: "error"}, {Key: "type", Type: "int"}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `// Code generated by loggergen. DO NOT EDIT.
+
+package events
+
+import (
+	"time"
+
+	"github.com/jcgregorio/logger"
+)
+
+// Events logs the standard events.
+type Events struct {
+	l *logger.Logger
+}
+
+// NewEvents returns a new Events that logs to l.
+func NewEvents(l *logger.Logger) *Events {
+	return &Events{l: l.WithDepth(1)}
+}
+
+// UserLogin logs "user login" at Info.
+func (e *Events) UserLogin(userID string, latency time.Duration) {
+	e.l.InfoFields("user login", logger.Str("user_id", userID), logger.Dur("latency", latency))
+}
+
+// PaymentFailed logs "payment failed" at Error.
+func (e *Events) PaymentFailed(err error, type_ int) {
+	e.l.ErrorFields("payment failed", logger.Err(err), logger.Int("type", type_))
+}
+`
+	if string(src) != want {
+		t.Errorf("Got:\n%s\nwant:\n%s", src, want)
+	}
+}
+
+func TestGenerateSensitive(t *testing.T) {
+	src, err := generate(&Schema{
+		Package: "events",
+		Type:    "Events",
+		Events: []Event{
+			{Name: "SignUp", Severity: "info", Message: "sign up", Fields: []Field{{Key: "email", Type: "pii"}, {Key: "token", Type: "secret"}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `func (e *Events) SignUp(email string, token string) {
+	e.l.InfoFields("sign up", logger.PIIStr("email", email), logger.SecretStr("token", token))
+}`
+	if !strings.Contains(string(src), want) {
+		t.Errorf("Got:\n%s\nwant it to contain:\n%s", src, want)
+	}
+}
+
+func TestGeneratePackageNames(t *testing.T) {
+	src, err := generate(&Schema{
+		Package: "events",
+		Type:    "Events",
+		Events: []Event{
+			{Name: "Start", Severity: "info", Message: "start", Fields: []Field{{Key: "logger", Type: "string"}, {Key: "time", Type: "time"}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `func (e *Events) Start(logger_ string, time_ time.Time) {
+	e.l.InfoFields("start", logger.Str("logger", logger_), logger.Time("time", time_))
+}`
+	if !strings.Contains(string(src), want) {
+		t.Errorf("Got:\n%s\nwant it to contain:\n%s", src, want)
+	}
+
+	// Without a time field the time package isn't imported.
+	src, err = generate(&Schema{
+		Package: "events",
+		Type:    "Events",
+		Events:  []Event{{Name: "Tick", Severity: "info", Message: "tick", Fields: []Field{{Key: "time", Type: "string"}}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "func (e *Events) Tick(time string) {"; !strings.Contains(string(src), want) {
+		t.Errorf("Got:\n%s\nwant it to contain:\n%s", src, want)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for name, schema := range map[string]*Schema{
+		"package":   {Package: "", Type: "Events"},
+		"type":      {Package: "events", Type: "events"},
+		"event":     {Package: "events", Type: "Events", Events: []Event{{Name: "login", Severity: "info"}}},
+		"duplicate": {Package: "events", Type: "Events", Events: []Event{{Name: "Login", Severity: "info"}, {Name: "Login", Severity: "info"}}},
+		"severity":  {Package: "events", Type: "Events", Events: []Event{{Name: "Login", Severity: "loud"}}},
+		"fieldtype": {Package: "events", Type: "Events", Events: []Event{{Name: "Login", Severity: "info", Fields: []Field{{Key: "a", Type: "complex128"}}}}},
+		"errorkey":  {Package: "events", Type: "Events", Events: []Event{{Name: "Login", Severity: "info", Fields: []Field{{Key: "cause", Type: "error"}}}}},
+		"param":     {Package: "events", Type: "Events", Events: []Event{{Name: "Login", Severity: "info", Fields: []Field{{Key: "a_b", Type: "int"}, {Key: "a.b", Type: "int"}}}}},
+	} {
+		if _, err := generate(schema); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Command logpurge removes the lines about a subject, such as the id of a
+// person who asked for their data to be erased, from log files, including
+// rotated files compressed with logger.FileOptions.Compress:
+//
+//	logpurge -subject alice@example.com /var/log/app.log.*
+//
+// It's intended to be run offline, on files that aren't being written to,
+// see logger.PurgeFile. With -pii_key_file the lines holding the hash of
+// the subject written by logger.PIIStr, with the key in that file, are
+// removed too. Use Logger.Purge for the entries a process holds in memory.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/jcgregorio/logger"
+)
+
+// purge removes the lines containing subject, or its PII hash if keyFile
+// isn't empty, from the files at paths, reporting the number removed from
+// each to w.
+func purge(w io.Writer, subject, keyFile string, paths []string) error {
+	subjects := []string{subject}
+	if keyFile != "" {
+		key, err := os.ReadFile(keyFile)
+		if err != nil {
+			return err
+		}
+		logger.SetPIIKey(key)
+		subjects = append(subjects, logger.PIIHash(subject))
+	}
+	for _, path := range paths {
+		total := 0
+		for _, s := range subjects {
+			n, err := logger.PurgeFile(path, s)
+			if err != nil {
+				return fmt.Errorf("%s: %s", path, err)
+			}
+			total += n
+		}
+		fmt.Fprintf(w, "%s: removed %d lines\n", path, total)
+	}
+	return nil
+}
+
+func main() {
+	subject := flag.String("subject", "", "The subject whose lines are removed.")
+	keyFile := flag.String("pii_key_file", "", "If set, a file holding the key passed to logger.SetPIIKey, to also remove the lines with the PII hash of the subject.")
+	flag.Parse()
+	if *subject == "" || flag.NArg() == 0 {
+		log.Fatal("logpurge: -subject and at least one file are required")
+	}
+	if err := purge(os.Stdout, *subject, *keyFile, flag.Args()); err != nil {
+		log.Fatalf("logpurge: %s", err)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jcgregorio/logger"
+)
+
+func TestPurge(t *testing.T) {
+	dir := t.TempDir()
+	key := filepath.Join(dir, "key")
+	if err := os.WriteFile(key, []byte("secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	logger.SetPIIKey([]byte("secret"))
+	path := filepath.Join(dir, "app.log")
+	contents := "a alice\nb " + logger.PIIHash("alice") + "\nc bob\n"
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := purge(&out, "alice", key, []string{path}); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != path+": removed 2 lines\n" {
+		t.Errorf("Wrong output: %q", got)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "c bob\n" {
+		t.Errorf("Wrong contents: %q", b)
+	}
+	if err := purge(&out, "alice", key, []string{filepath.Join(dir, "missing")}); err == nil {
+		t.Error("No error for a missing file")
+	}
+}
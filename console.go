@@ -0,0 +1,169 @@
+package logger
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ConsoleFormatter is a Formatter for reading logs in a terminal during
+// development, which trades the full glog header for short timestamps that
+// make latency gaps visually obvious, e.g.:
+//
+//	12:04:05.120 I main.go:10] started
+//	12:04:06.320 W main.go:12] slow request path=/
+//
+// or with Relative and Deltas set:
+//
+//	+0.000s (+0s) I main.go:10] started
+//	+1.200s (+1.2s) W main.go:12] slow request path=/
+//
+// Each line of a multi-line message gets a header, and the fields follow the
+// last line. It's registered with RegisterEncoder as "console". A
+// ConsoleFormatter keeps state between entries, so must not be copied after
+// first use.
+type ConsoleFormatter struct {
+	// Relative writes timestamps as the time since Start, e.g. "+1.200s",
+	// instead of the wall clock time, e.g. "12:04:05.120".
+	Relative bool
+
+	// Start is the time Relative timestamps are measured from. If zero, the
+	// time of the first entry is used.
+	Start time.Time
+
+	// Deltas adds the time elapsed since the previous entry after the
+	// timestamp, e.g. "(+15ms)".
+	Deltas bool
+
+	// Width is the number of columns that messages, along with their
+	// fields, are wrapped to, breaking them at spaces where possible, with
+	// the lines after the first indented to the column the message starts
+	// at, e.g.:
+	//
+	//	12:04:05.120 E main.go:10] failed to fetch the config: connection
+	//	                           refused
+	//
+	// The header is never broken. If zero, the width of the terminal on
+	// stderr is used, if it is one, and if negative messages aren't wrapped.
+	Width int
+
+	// mu protects prev, and Start once set.
+	mu   sync.Mutex
+	prev time.Time
+}
+
+func init() {
+	RegisterEncoder("console", func() Formatter { return &ConsoleFormatter{} })
+}
+
+// Format implements Formatter.
+func (c *ConsoleFormatter) Format(entry Entry, buf *bytes.Buffer) {
+	c.mu.Lock()
+	if c.Start.IsZero() {
+		c.Start = entry.Time
+	}
+	if c.prev.IsZero() {
+		c.prev = entry.Time
+	}
+	start, delta := c.Start, entry.Time.Sub(c.prev)
+	c.prev = entry.Time
+	c.mu.Unlock()
+
+	prefix := &buffer{}
+	if c.Relative {
+		prefix.WriteByte('+')
+		prefix.Write(strconv.AppendFloat(prefix.tmp[:0], entry.Time.Sub(start).Seconds(), 'f', 3, 64))
+		prefix.WriteByte('s')
+	} else {
+		prefix.Write(entry.Time.AppendFormat(prefix.tmp[:0], "15:04:05.000"))
+	}
+	if c.Deltas {
+		prefix.WriteString(" (+")
+		prefix.WriteString(delta.Round(time.Millisecond).String())
+		prefix.WriteByte(')')
+	}
+	prefix.WriteByte(' ')
+	prefix.WriteByte(entry.Severity[0])
+	prefix.WriteByte(' ')
+	prefix.WriteString(entry.File)
+	prefix.WriteByte(':')
+	prefix.Write(strconv.AppendInt(prefix.tmp[:0], int64(entry.Line), 10))
+	prefix.WriteString("] ")
+
+	suffix := &buffer{}
+	for _, f := range entry.Fields {
+		f.appendTo(suffix)
+	}
+	width := c.Width
+	if width == 0 {
+		width = stderrWidth()
+	}
+	// Leave at least minWrapWidth columns for the message.
+	indent := utf8.RuneCount(prefix.Bytes())
+	if width > 0 && width-indent < minWrapWidth {
+		width = 0
+	}
+	var lines []string
+	for _, line := range strings.Split(entry.Message, "\n") {
+		// Don't emit blank lines.
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	fields := suffix.String()
+	if len(lines) == 0 {
+		// An entry without a message is still written, as a line of just
+		// its fields.
+		lines = []string{""}
+		fields = strings.TrimPrefix(fields, " ")
+	}
+	// The fields follow the last line only.
+	lines[len(lines)-1] += fields
+	for _, line := range lines {
+		buf.Write(prefix.Bytes())
+		if width <= 0 {
+			buf.WriteString(line)
+			buf.WriteByte('\n')
+			continue
+		}
+		for i, wrapped := range wrap(line, width-indent) {
+			if i > 0 {
+				buf.WriteString(strings.Repeat(" ", indent))
+			}
+			buf.WriteString(wrapped)
+			buf.WriteByte('\n')
+		}
+	}
+}
+
+// minWrapWidth is the fewest columns ConsoleFormatter wraps messages to.
+const minWrapWidth = 20
+
+// wrap splits text into lines of at most width runes, breaking them at
+// spaces where possible, and otherwise within words.
+func wrap(text string, width int) []string {
+	var lines []string
+	for utf8.RuneCountInString(text) > width {
+		// cut is the offset of the first rune that doesn't fit.
+		cut := 0
+		for n := 0; n < width; n++ {
+			_, size := utf8.DecodeRuneInString(text[cut:])
+			cut += size
+		}
+		i := strings.LastIndexByte(text[:cut+1], ' ')
+		if head := strings.TrimRight(text[:i+1], " "); i > 0 && head != "" {
+			lines = append(lines, head)
+			text = strings.TrimLeft(text[i:], " ")
+		} else {
+			lines = append(lines, text[:cut])
+			text = text[cut:]
+		}
+	}
+	if text != "" || len(lines) == 0 {
+		lines = append(lines, text)
+	}
+	return lines
+}
@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lineNumberRegex matches the line number in a header.
+var lineNumberRegex = regexp.MustCompile(`:\d+\]`)
+
+func TestConsoleFormatter(t *testing.T) {
+	now := time.Date(2006, 1, 2, 12, 4, 5, 120000000, time.UTC)
+	clock := func() time.Time { return now }
+
+	for _, tc := range []struct {
+		name string
+		f    *ConsoleFormatter
+		want string
+	}{
+		{"absolute", &ConsoleFormatter{}, "12:04:05.120 I console_test.go:L] started\n12:04:06.320 W console_test.go:L] slow path=/\n"},
+		{"relative", &ConsoleFormatter{Relative: true, Deltas: true}, "+0.000s (+0s) I console_test.go:L] started\n+1.200s (+1.2s) W console_test.go:L] slow path=/\n"},
+		{"start", &ConsoleFormatter{Relative: true, Start: now.Add(-time.Second)}, "+1.000s I console_test.go:L] started\n+2.200s W console_test.go:L] slow path=/\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			start := now
+			defer func() { now = start }()
+			b := &flushBuffer{}
+			l := NewFromOptions(&Options{SyncWriter: b, Formatter: tc.f, Now: clock})
+			l.Info("started")
+			now = now.Add(1200 * time.Millisecond)
+			l.WarningFields("slow", Str("path", "/"))
+			if got := lineNumberRegex.ReplaceAllString(b.String(), ":L]"); got != tc.want {
+				t.Errorf("Got %q want %q", got, tc.want)
+			}
+		})
+	}
+
+	f, err := NewEncoder("console")
+	if _, ok := f.(*ConsoleFormatter); err != nil || !ok {
+		t.Errorf("Wrong console encoder: %T %v", f, err)
+	}
+}
+
+func TestConsoleFormatterWidth(t *testing.T) {
+	now := time.Date(2006, 1, 2, 12, 4, 5, 120000000, time.UTC)
+	b := &flushBuffer{}
+	l := NewFromOptions(&Options{SyncWriter: b, Formatter: &ConsoleFormatter{Width: 70}, Now: func() time.Time { return now }})
+	l.ErrorFields("failed to fetch the config from the server: connection refused\nretrying", Str("host", "config.example.com"))
+	indent := strings.Repeat(" ", len("12:04:05.120 E console_test.go:NN] "))
+	want := "12:04:05.120 E console_test.go:L] failed to fetch the config from the\n" +
+		indent + "server: connection refused\n" +
+		"12:04:05.120 E console_test.go:L] retrying host=config.example.com\n"
+	if got := lineNumberRegex.ReplaceAllString(b.String(), ":L]"); got != want {
+		t.Errorf("Got %q want %q", got, want)
+	}
+
+	// Too narrow to leave room for the message.
+	b.Reset()
+	l = NewFromOptions(&Options{SyncWriter: b, Formatter: &ConsoleFormatter{Width: 50}, Now: func() time.Time { return now }})
+	l.Info("not wrapped at all")
+	if got := lineNumberRegex.ReplaceAllString(b.String(), ":L]"); got != "12:04:05.120 I console_test.go:L] not wrapped at all\n" {
+		t.Errorf("Got %q", got)
+	}
+}
+
+// Test that the fields are written once, after the last line of the
+// message, and that entries without a message are still written.
+func TestConsoleFormatterFields(t *testing.T) {
+	now := time.Date(2006, 1, 2, 12, 4, 5, 120000000, time.UTC)
+	b := &flushBuffer{}
+	l := NewFromOptions(&Options{SyncWriter: b, Formatter: &ConsoleFormatter{Width: -1}, Now: func() time.Time { return now }})
+	l.InfoFields("a\nb", Int("k", 1))
+	l.ErrorFields("", Err(errors.New("it broke")))
+	l.Info("")
+	want := "12:04:05.120 I console_test.go:L] a\n" +
+		"12:04:05.120 I console_test.go:L] b k=1\n" +
+		"12:04:05.120 E console_test.go:L] error=\"it broke\"\n" +
+		"12:04:05.120 I console_test.go:L] \n"
+	if got := lineNumberRegex.ReplaceAllString(b.String(), ":L]"); got != want {
+		t.Errorf("Got %q want %q", got, want)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	for _, tc := range []struct {
+		text  string
+		width int
+		want  []string
+	}{
+		{"short", 10, []string{"short"}},
+		{"one two three", 7, []string{"one two", "three"}},
+		{"one  two", 4, []string{"one", "two"}},
+		{"abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"héllo wörld", 5, []string{"héllo", "wörld"}},
+		{"  lead", 3, []string{"  l", "ead"}},
+	} {
+		got := wrap(tc.text, tc.width)
+		if strings.Join(got, "|") != strings.Join(tc.want, "|") {
+			t.Errorf("wrap(%q, %d) = %q, want %q", tc.text, tc.width, got, tc.want)
+		}
+	}
+}
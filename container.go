@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// DockerMaxLineLength is the size at which Docker splits lines written to
+// stdout/stderr into multiple log records.
+const DockerMaxLineLength = 16 * 1024
+
+// inContainer reports if the process looks like it is running in a container
+// runtime that splits long lines. A variable so it can be stubbed out for testing.
+var inContainer = func() bool {
+	for _, name := range []string{"/.dockerenv", "/run/.containerenv"} {
+		if _, err := os.Stat(name); err == nil {
+			return true
+		}
+	}
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return true
+	}
+	b, err := os.ReadFile("/proc/1/cgroup")
+	if err != nil {
+		return false
+	}
+	cgroup := string(b)
+	return strings.Contains(cgroup, "docker") || strings.Contains(cgroup, "kubepods") || strings.Contains(cgroup, "containerd")
+}
+
+// maxLineLength returns the maximum line length to use given the value of
+// Options.MaxLineLength and the destination writer, where 0 means no limit.
+func maxLineLength(o *Options, w SyncWriter) int {
+	if o.MaxLineLength > 0 {
+		return o.MaxLineLength
+	}
+	if o.MaxLineLength < 0 {
+		return 0
+	}
+	if (w == os.Stdout || w == os.Stderr) && inContainer() {
+		return DockerMaxLineLength
+	}
+	return 0
+}
+
+// splitLine breaks pline up into parts so that each part, once written with
+// the header, the stamp, and a " part=N/M" marker, fits in max bytes. Parts
+// are never split in the middle of a UTF-8 encoded rune.
+func splitLine(pline []byte, overhead, max int) [][]byte {
+	if max <= 0 || len(pline)+overhead <= max {
+		return [][]byte{pline}
+	}
+	// The size of the marker depends on the number of parts, so grow the
+	// reserved number of digits until the parts fit.
+	for digits := 1; ; digits++ {
+		room := max - overhead - len(" part=/") - 2*digits
+		if room < utf8.UTFMax {
+			// There's no room for even a single rune, give up on splitting.
+			return [][]byte{pline}
+		}
+		var parts [][]byte
+		rest := pline
+		for len(rest) > 0 {
+			n := room
+			if n >= len(rest) {
+				n = len(rest)
+			} else {
+				for n > 0 && !utf8.RuneStart(rest[n]) {
+					n--
+				}
+				if n == 0 {
+					// Not valid UTF-8, split anywhere.
+					n = room
+				}
+			}
+			parts = append(parts, rest[:n])
+			rest = rest[n:]
+		}
+		if len(parts) < pow10(digits) {
+			return parts
+		}
+	}
+}
+
+func pow10(n int) int {
+	ret := 1
+	for i := 0; i < n; i++ {
+		ret *= 10
+	}
+	return ret
+}
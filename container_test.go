@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSplitLine(t *testing.T) {
+	pline := []byte(strings.Repeat("a", 25))
+	parts := splitLine(pline, 5, 20)
+	// Each part has room for 20 - 5 - len(" part=/") - 2 = 6 bytes.
+	if len(parts) != 5 {
+		t.Fatalf("Wrong number of parts, got %d want 5", len(parts))
+	}
+	if got := bytes.Join(parts, nil); !bytes.Equal(got, pline) {
+		t.Errorf("Parts don't reassemble: %q", got)
+	}
+
+	if parts := splitLine(pline, 5, 0); len(parts) != 1 {
+		t.Errorf("Should not split with no limit, got %d parts", len(parts))
+	}
+	if parts := splitLine(pline, 5, 100); len(parts) != 1 {
+		t.Errorf("Should not split short lines, got %d parts", len(parts))
+	}
+}
+
+func TestSplitLineUTF8(t *testing.T) {
+	pline := []byte(strings.Repeat("☃", 10)) // 3 bytes each.
+	for _, part := range splitLine(pline, 0, 15) {
+		if !bytes.Equal(part, bytes.ToValidUTF8(part, nil)) {
+			t.Errorf("Part split in the middle of a rune: %q", part)
+		}
+	}
+}
+
+func TestMaxLineLength(t *testing.T) {
+	defer func(previous func() bool) { inContainer = previous }(inContainer)
+	inContainer = func() bool { return true }
+	if got := maxLineLength(&Options{}, os.Stdout); got != DockerMaxLineLength {
+		t.Errorf("Got %d want %d in a container", got, DockerMaxLineLength)
+	}
+	if got := maxLineLength(&Options{}, &flushBuffer{}); got != 0 {
+		t.Errorf("Got %d want 0 for non-stdout writers", got)
+	}
+	if got := maxLineLength(&Options{MaxLineLength: -1}, os.Stdout); got != 0 {
+		t.Errorf("Got %d want 0 when disabled", got)
+	}
+	inContainer = func() bool { return false }
+	if got := maxLineLength(&Options{}, os.Stdout); got != 0 {
+		t.Errorf("Got %d want 0 outside a container", got)
+	}
+}
+
+// Test that long lines are split into parts no longer than the limit.
+func TestLongLinesSplit(t *testing.T) {
+	l := NewFromOptions(&Options{
+		SyncWriter:    &flushBuffer{},
+		MaxLineLength: 100,
+	})
+	l.Info(strings.Repeat("x", 250))
+	lines := strings.Split(strings.TrimSuffix(l.w.(*flushBuffer).String(), "\n"), "\n")
+	if len(lines) < 3 {
+		t.Fatalf("Expected the line to be split, got %d lines", len(lines))
+	}
+	for i, line := range lines {
+		if len(line)+1 > 100 {
+			t.Errorf("Line too long: %d", len(line)+1)
+		}
+		if suffix := fmt.Sprintf(" part=%d/%d", i+1, len(lines)); !strings.HasSuffix(line, suffix) {
+			t.Errorf("Missing %q: %q", suffix, line)
+		}
+	}
+}
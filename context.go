@@ -0,0 +1,114 @@
+package logger
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// contextKey is the key of the Logger stored in a context by NewContext.
+type contextKey struct{}
+
+var (
+	// defaultLogger is returned by FromContext for contexts without a
+	// Logger, created on first use.
+	defaultLogger     *Logger
+	defaultLoggerOnce sync.Once
+)
+
+// NewContext returns a copy of ctx holding l, to be retrieved by FromContext,
+// so request scoped Loggers, e.g. those returned by With, can flow through
+// handler stacks.
+//
+// The functions that take a context, such as InfoCtx, log with the Logger
+// in the context, and if the context carries an active OpenTelemetry span
+// add its trace_id and span_id as fields, so logs and traces can be
+// correlated.
+//
+// If ctx holds a sampling decision, see Sample, it's applied to l.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	if l != nil {
+		l = l.WithSampling(ctx)
+	}
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the Logger stored in ctx by NewContext, or if there
+// isn't one a default Logger created with New, which writes to os.Stdout.
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok && l != nil {
+		return l
+	}
+	defaultLoggerOnce.Do(func() {
+		defaultLogger = New()
+	})
+	return defaultLogger.WithSampling(ctx)
+}
+
+// ctxFields returns fields along with any Fields derived from ctx, which
+// are the trace_id and span_id of the OpenTelemetry span in ctx, if any.
+func ctxFields(ctx context.Context, fields []Field) []Field {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return fields
+	}
+	ret := make([]Field, 0, len(fields)+2)
+	ret = append(ret, fields...)
+	return append(ret, Str("trace_id", sc.TraceID().String()), Str("span_id", sc.SpanID().String()))
+}
+
+// DebugCtx logs msg and fields at Debug with the Logger from FromContext(ctx),
+// if it includes Debug logs.
+func DebugCtx(ctx context.Context, msg string, fields ...Field) {
+	l := FromContext(ctx)
+	if l.debugEnabled(0) {
+		l.printFieldsCtx(ctx, debugLog, msg, ctxFields(ctx, fields))
+	}
+}
+
+// InfoCtx logs msg and fields at Info with the Logger from FromContext(ctx).
+func InfoCtx(ctx context.Context, msg string, fields ...Field) {
+	FromContext(ctx).printFieldsCtx(ctx, infoLog, msg, ctxFields(ctx, fields))
+}
+
+// WarningCtx logs msg and fields at Warning with the Logger from
+// FromContext(ctx).
+func WarningCtx(ctx context.Context, msg string, fields ...Field) {
+	FromContext(ctx).printFieldsCtx(ctx, warningLog, msg, ctxFields(ctx, fields))
+}
+
+// ErrorCtx logs msg and fields at Error with the Logger from
+// FromContext(ctx).
+func ErrorCtx(ctx context.Context, msg string, fields ...Field) {
+	FromContext(ctx).printFieldsCtx(ctx, errorLog, msg, ctxFields(ctx, fields))
+}
+
+// FatalCtx logs msg and fields at Fatal with the Logger from
+// FromContext(ctx), and then exits.
+func FatalCtx(ctx context.Context, msg string, fields ...Field) {
+	FromContext(ctx).printFieldsCtx(ctx, fatalLog, msg, ctxFields(ctx, fields))
+}
+
+// ContextFields returns Fields describing the state of ctx, for debugging
+// timeout cascades:
+//
+//	ctx_deadline_in  The time remaining until ctx's deadline, negative if it
+//	                 has passed. Omitted if ctx has no deadline.
+//	ctx_err          ctx.Err(), e.g. "context canceled", if ctx is done.
+//	                 Omitted otherwise.
+//
+// For example:
+//
+//	l.WarningFields("backend call failed", logger.ContextFields(ctx)...)
+func ContextFields(ctx context.Context) []Field {
+	var ret []Field
+	if deadline, ok := ctx.Deadline(); ok {
+		ret = append(ret, Dur("ctx_deadline_in", deadline.Sub(timeNow()).Round(time.Millisecond)))
+	}
+	if err := ctx.Err(); err != nil {
+		ret = append(ret, Str("ctx_err", err.Error()))
+	}
+	return ret
+}
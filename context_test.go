@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestContextFields(t *testing.T) {
+	if fields := ContextFields(context.Background()); len(fields) != 0 {
+		t.Errorf("Expected no fields for a background context, got %v", fields)
+	}
+
+	defer func(now func() time.Time) { timeNow = now }(timeNow)
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	ctx, cancel := context.WithDeadline(context.Background(), now.Add(time.Hour))
+	newTestLogger()
+	testLogger.InfoFields("waiting", ContextFields(ctx)...)
+	if !strings.HasSuffix(contents(), "] waiting ctx_deadline_in=1h0m0s\n") {
+		t.Errorf("Wrong output: %q", contents())
+	}
+
+	cancel()
+	newTestLogger()
+	testLogger.InfoFields("gave up", ContextFields(ctx)...)
+	if !contains(` ctx_err="context canceled"`, t) {
+		t.Errorf("Wrong output: %q", contents())
+	}
+}
+
+func TestNewContext(t *testing.T) {
+	newTestLogger()
+	ctx := NewContext(context.Background(), testLogger.With("request_id", "abc"))
+	InfoCtx(ctx, "handled", Int("status", 200))
+	DebugCtx(ctx, "hidden")
+	WarningCtx(ctx, "slow")
+
+	lines := strings.Split(strings.TrimSpace(contents()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Wrong number of lines: %q", lines)
+	}
+	if !strings.HasPrefix(lines[0], "I") || !strings.Contains(lines[0], " context_test.go:") || !strings.HasSuffix(lines[0], "] handled request_id=abc status=200") {
+		t.Errorf("Wrong line: %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "W") || !strings.HasSuffix(lines[1], "] slow request_id=abc") {
+		t.Errorf("Wrong line: %q", lines[1])
+	}
+
+	if l := FromContext(context.Background()); l == nil || l != FromContext(context.TODO()) {
+		t.Errorf("Expected the same default Logger, got %v", l)
+	}
+}
+
+func TestCtxTraceFields(t *testing.T) {
+	newTestLogger()
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10},
+		SpanID:     trace.SpanID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(NewContext(context.Background(), testLogger), sc)
+	ErrorCtx(ctx, "failed", Int("status", 500))
+	InfoCtx(NewContext(context.Background(), testLogger), "untraced")
+
+	lines := strings.Split(strings.TrimSpace(contents()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Wrong number of lines: %q", lines)
+	}
+	if want := "] failed status=500 trace_id=0102030405060708090a0b0c0d0e0f10 span_id=0102030405060708"; !strings.HasSuffix(lines[0], want) {
+		t.Errorf("Got %q want suffix %q", lines[0], want)
+	}
+	if !strings.HasSuffix(lines[1], "] untraced") {
+		t.Errorf("Wrong line: %q", lines[1])
+	}
+}
@@ -0,0 +1,146 @@
+package logger
+
+import (
+	"os"
+	"runtime"
+	"sync"
+)
+
+// crashBufferSize is the size of the buffer used when crashing, which
+// bounds how much of the message and stack traces is written.
+const crashBufferSize = 256 << 10
+
+var (
+	// crashBuf is allocated up front so crashing doesn't have to allocate.
+	crashBuf [crashBufferSize]byte
+
+	// crashMu protects crashBuf.
+	crashMu sync.Mutex
+)
+
+// newCrashTemplate returns the header template used by Crash, with
+// everything but the timestamp, file, and line already rendered.
+func (l *Logger) newCrashTemplate() []byte {
+	buf := &buffer{}
+	buf.tmp[0] = 'F'
+	copy(buf.tmp[1:], "0000 00:00:00.000000 ")
+	buf.nDigits(7, 22, l.processID(), ' ')
+	buf.tmp[29] = ' '
+	return append([]byte(nil), buf.tmp[:30]...)
+}
+
+// Crash writes msg as a Fatal log, followed by the stack traces of all
+// goroutines, then exits without running any hooks registered with OnFatal.
+//
+// Unlike Fatal, Crash doesn't allocate or use any of the Logger's buffers,
+// so it can still get the final message out when the heap can't be trusted.
+// Fatal falls back to it if anything goes wrong while writing the log. The
+// header is always the default one, the stack traces are written as is,
+// without a header on every line, and everything is truncated at 256KiB.
+func (l *Logger) Crash(msg string) {
+	// runtime.Caller allocates, so look up the caller by hand.
+	var pcs [1]uintptr
+	file, line := "???", 1
+	if l.caller != nil {
+		file, line = l.caller.file, l.caller.line
+	} else if runtime.Callers(2+l.depthDelta, pcs[:]) == 1 {
+		if f := runtime.FuncForPC(pcs[0] - 1); f != nil {
+			file, line = f.FileLine(pcs[0] - 1)
+		}
+	}
+	for i := len(file) - 1; i >= 0; i-- {
+		if file[i] == '/' {
+			file = file[i+1:]
+			break
+		}
+	}
+
+	crashMu.Lock()
+	b := l.appendCrashHeader(crashBuf[:0], file, line)
+	// Leave room for the newline.
+	if room := cap(b) - len(b) - 1; len(msg) > room {
+		msg = msg[:room]
+	}
+	b = append(b, msg...)
+	l.crash(b)
+	crashMu.Unlock()
+	l.osExit(255)
+}
+
+// crashFatal writes the Fatal log in buf with the already rendered header
+// in the manner of Crash. It's used if writing the log normally fails.
+func (l *Logger) crashFatal(buf, header *buffer) {
+	crashMu.Lock()
+	defer crashMu.Unlock()
+	b := crashBuf[:0]
+	if header.Len() == 0 {
+		// Options.Formatter is set, so the header wasn't rendered.
+		b = l.appendCrashHeader(b, header.file, header.line)
+	} else if header.Len() < cap(b) {
+		b = append(b, header.Bytes()...)
+	}
+	msg := buf.Bytes()
+	if room := cap(b) - len(b) - 1; len(msg) > room {
+		msg = msg[:room]
+	}
+	b = append(b, msg...)
+	l.crash(b)
+}
+
+// appendCrashHeader appends a Fatal header for file and line to b, filling
+// in the template from newCrashTemplate.
+func (l *Logger) appendCrashHeader(b []byte, file string, line int) []byte {
+	start := len(b)
+	b = append(b, l.crashTemplate...)
+	now := l.timeNow()
+	_, month, day := now.Date()
+	hour, minute, second := now.Clock()
+	h := b[start:]
+	putDigits(h[1:3], int(month))
+	putDigits(h[3:5], day)
+	putDigits(h[6:8], hour)
+	putDigits(h[9:11], minute)
+	putDigits(h[12:14], second)
+	putDigits(h[15:21], now.Nanosecond()/1000)
+	b = append(b, file...)
+	b = append(b, ':')
+	if line < 0 {
+		line = 0
+	}
+	var digits [20]byte
+	n := numDigits(line)
+	putDigits(digits[:n], line)
+	b = append(b, digits[:n]...)
+	return append(b, "] "...)
+}
+
+// crash ends the message in b, which must be a prefix of crashBuf, with a
+// newline, fills the rest of crashBuf with the stack traces of all
+// goroutines, and writes it directly to the destination, or to stderr if
+// that fails. crashMu must be held.
+func (l *Logger) crash(b []byte) {
+	b = append(b, '\n')
+	n := runtime.Stack(b[len(b):cap(b)], true)
+	b = b[:len(b)+n]
+
+	defer func() {
+		if recover() != nil {
+			os.Stderr.Write(b)
+		}
+	}()
+	w := l.writer()
+	if _, err := w.Write(b); err != nil {
+		os.Stderr.Write(b)
+		return
+	}
+	w.Sync()
+}
+
+// putDigits writes n into b as zero padded decimal digits, keeping only the
+// least significant len(b) digits.
+func putDigits(b []byte, n int) {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = byte('0' + n%10)
+		n /= 10
+	}
+}
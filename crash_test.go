@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCrash(t *testing.T) {
+	exitCode := 0
+	l := NewFromOptions(&Options{
+		SyncWriter: &flushBuffer{},
+		Now:        func() time.Time { return time.Date(2006, 1, 2, 15, 4, 5, 67890000, time.Local) },
+		Exit:       func(code int) { exitCode = code },
+		PID:        1234,
+	})
+	l.Crash("out of memory")
+	got := l.w.(*flushBuffer).String()
+	if !strings.HasPrefix(got, "F0102 15:04:05.067890    1234 crash_test.go:") || !strings.Contains(got, "] out of memory\ngoroutine ") {
+		t.Errorf("Wrong output: %q", got)
+	}
+	if !strings.Contains(got, "TestCrash") {
+		t.Errorf("Missing stack trace: %q", got)
+	}
+	if exitCode != 255 {
+		t.Errorf("Wrong exit code: %d", exitCode)
+	}
+}
+
+func TestCrashAllocs(t *testing.T) {
+	l := NewFromOptions(&Options{SyncWriter: &discardWriter{}, Exit: func(int) {}})
+	if n := testing.AllocsPerRun(10, func() { l.Crash("crashing") }); n != 0 {
+		t.Errorf("Crash allocated %v times", n)
+	}
+}
+
+// panicWriter is a SyncWriter that panics on the first write.
+type panicWriter struct {
+	flushBuffer
+	panicked bool
+}
+
+func (p *panicWriter) Write(b []byte) (int, error) {
+	if !p.panicked {
+		p.panicked = true
+		panic("corrupted")
+	}
+	return p.flushBuffer.Write(b)
+}
+
+// Test that Fatal falls back to the crash path if writing the log fails.
+func TestFatalFallsBackToCrash(t *testing.T) {
+	exited := false
+	w := &panicWriter{}
+	l := NewFromOptions(&Options{SyncWriter: w, Exit: func(int) { exited = true }})
+	l.Fatal("goodbye")
+	got := w.String()
+	if !strings.HasPrefix(got, "F") || !strings.Contains(got, " crash_test.go:") || !strings.Contains(got, "] goodbye\ngoroutine ") {
+		t.Errorf("Wrong output: %q", got)
+	}
+	if !exited {
+		t.Error("Did not exit.")
+	}
+}
@@ -0,0 +1,30 @@
+package logger
+
+import "runtime"
+
+// Deprecated logs a Warning that name is deprecated, along with advice such
+// as "use NewFunc instead", at most once per call site for the life of the
+// process.
+//
+// It is meant to be called from within the deprecated function itself, so
+// the call site reported, and used to limit the logging, is the caller of
+// the function that called Deprecated:
+//
+//	func OldFunc() {
+//		l.Deprecated("OldFunc", "use NewFunc instead")
+//		...
+//	}
+func (l *Logger) Deprecated(name, advice string) {
+	pc, _, _, ok := runtime.Caller(2 + l.depthDelta)
+	if !ok {
+		pc = 0
+	}
+	if _, logged := l.deprecations.LoadOrStore(pc, true); logged {
+		return
+	}
+	msg := name + " is deprecated"
+	if advice != "" {
+		msg += ": " + advice
+	}
+	l.logDepth(warningLog, 1, []byte(msg), nil)
+}
@@ -0,0 +1,33 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+func oldFunc() {
+	testLogger.Deprecated("oldFunc", "use newFunc instead")
+}
+
+func TestDeprecated(t *testing.T) {
+	newTestLogger()
+	for i := 0; i < 3; i++ {
+		oldFunc()
+	}
+	oldFunc()
+	lines := strings.Split(strings.TrimSpace(contents()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected one warning per call site, got %d: %q", len(lines), lines)
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "W") || !strings.HasSuffix(line, "] oldFunc is deprecated: use newFunc instead") {
+			t.Errorf("Wrong warning: %q", line)
+		}
+	}
+	if lines[0] == lines[1] {
+		t.Errorf("Call sites should differ: %q", lines)
+	}
+	if !strings.Contains(lines[0], " deprecated_test.go:") {
+		t.Errorf("Wrong caller reported: %q", lines[0])
+	}
+}
@@ -0,0 +1,141 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxDigestMessages is the number of distinct messages an ErrorDigest
+	// tracks, further distinct messages are only counted in the total.
+	maxDigestMessages = 1000
+
+	// maxDigestMessageLength is the length messages are truncated to before
+	// being grouped.
+	maxDigestMessageLength = 200
+
+	// defaultDigestInterval is the interval of an ErrorDigest created with
+	// one that isn't greater than zero.
+	defaultDigestInterval = 5 * time.Minute
+)
+
+// ErrorDigest accumulates Error logs and periodically writes a summary of
+// them to a separate Logger, e.g.:
+//
+//	37 errors in last 5m0s, top 3: "db timeout" (20), "cache miss" (10), "bad request" (5)
+//
+// Pass it in Options.ErrorDigest to have a Logger feed it. The individual
+// Error logs are still written as usual.
+type ErrorDigest struct {
+	sink     *Logger
+	interval time.Duration
+	top      int
+
+	// mu protects the fields below.
+	mu     sync.Mutex
+	counts map[string]int
+	total  int
+	start  time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewErrorDigest returns an ErrorDigest that writes a summary of the last
+// interval's errors, listing the top most common messages, to sink. If
+// interval isn't greater than zero then 5 minutes is used.
+func NewErrorDigest(sink *Logger, interval time.Duration, top int) *ErrorDigest {
+	if interval <= 0 {
+		interval = defaultDigestInterval
+	}
+	d := &ErrorDigest{
+		sink:     sink,
+		interval: interval,
+		top:      top,
+		counts:   map[string]int{},
+		start:    sink.timeNow(),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+func (d *ErrorDigest) run() {
+	defer close(d.done)
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			d.Flush()
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// add records a single error message.
+func (d *ErrorDigest) add(msg []byte) {
+	if i := bytes.IndexByte(msg, '\n'); i >= 0 {
+		msg = msg[:i]
+	}
+	if len(msg) > maxDigestMessageLength {
+		msg = msg[:maxDigestMessageLength]
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.total++
+	if _, ok := d.counts[string(msg)]; ok || len(d.counts) < maxDigestMessages {
+		d.counts[string(msg)]++
+	}
+}
+
+// Flush writes the summary of errors seen since the last Flush, if there
+// were any, and starts a new interval.
+func (d *ErrorDigest) Flush() {
+	d.mu.Lock()
+	counts, total, start := d.counts, d.total, d.start
+	d.counts = map[string]int{}
+	d.total = 0
+	d.start = d.sink.timeNow()
+	d.mu.Unlock()
+
+	if total == 0 {
+		return
+	}
+	type messageCount struct {
+		msg   string
+		count int
+	}
+	sorted := make([]messageCount, 0, len(counts))
+	for msg, count := range counts {
+		sorted = append(sorted, messageCount{msg: msg, count: count})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].count != sorted[j].count {
+			return sorted[i].count > sorted[j].count
+		}
+		return sorted[i].msg < sorted[j].msg
+	})
+	if len(sorted) > d.top {
+		sorted = sorted[:d.top]
+	}
+	top := make([]string, len(sorted))
+	for i, mc := range sorted {
+		top[i] = fmt.Sprintf("%q (%d)", mc.msg, mc.count)
+	}
+	elapsed := d.sink.timeNow().Sub(start).Round(time.Second)
+	d.sink.printf(errorLog, "%d errors in last %s, top %d: %s", total, elapsed, len(top), strings.Join(top, ", "))
+}
+
+// Close stops the periodic summaries and writes a final summary.
+func (d *ErrorDigest) Close() {
+	close(d.stop)
+	<-d.done
+	d.Flush()
+}
@@ -0,0 +1,58 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestErrorDigest(t *testing.T) {
+	defer func(previous func() time.Time) { timeNow = previous }(timeNow)
+	now := time.Date(2006, 1, 2, 15, 4, 5, 0, time.Local)
+	timeNow = func() time.Time { return now }
+
+	sink := NewFromOptions(&Options{SyncWriter: &flushBuffer{}})
+	d := NewErrorDigest(sink, time.Hour, 2)
+	l := NewFromOptions(&Options{
+		SyncWriter:  &flushBuffer{},
+		ErrorDigest: d,
+	})
+	for i := 0; i < 3; i++ {
+		l.Error("db timeout")
+	}
+	l.Errorf("cache %s", "miss")
+	l.Errorf("cache %s", "miss")
+	l.Error("bad request\nwith details")
+	l.Info("not an error")
+
+	now = now.Add(5 * time.Minute)
+	d.Close()
+
+	got := sink.w.(*flushBuffer).String()
+	want := `] 6 errors in last 5m0s, top 2: "db timeout" (3), "cache miss" (2)` + "\n"
+	if !strings.HasPrefix(got, "E") || !strings.HasSuffix(got, want) {
+		t.Errorf("Wrong digest, got %q want suffix %q", got, want)
+	}
+	if n := strings.Count(l.w.(*flushBuffer).String(), "\n"); n != 8 {
+		t.Errorf("Individual logs should still be written, got %d lines", n)
+	}
+}
+
+func TestErrorDigestDefaultInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		d := NewErrorDigest(NewFromOptions(&Options{SyncWriter: &flushBuffer{}}), interval, 1)
+		if d.interval != defaultDigestInterval {
+			t.Errorf("Interval of %s for %s, want %s", d.interval, interval, defaultDigestInterval)
+		}
+		d.Close()
+	}
+}
+
+func TestErrorDigestNoErrors(t *testing.T) {
+	sink := NewFromOptions(&Options{SyncWriter: &flushBuffer{}})
+	d := NewErrorDigest(sink, time.Hour, 3)
+	d.Close()
+	if got := sink.w.(*flushBuffer).String(); got != "" {
+		t.Errorf("No digest should be written without errors: %q", got)
+	}
+}
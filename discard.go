@@ -0,0 +1,49 @@
+package logger
+
+import "sync/atomic"
+
+// DiscardSyncWriter is a SyncWriter that writes nothing, but counts what it
+// was given, for load tests that measure the cost of logging without that
+// of the destination:
+//
+//	var d logger.DiscardSyncWriter
+//	l := logger.NewFromOptions(&logger.Options{SyncWriter: &d})
+//	runLoadTest(l)
+//	fmt.Println(d.Writes(), "lines,", d.Bytes(), "bytes")
+//
+// The zero value is ready to use.
+type DiscardSyncWriter struct {
+	// writes and bytes are accessed atomically.
+	writes uint64
+	bytes  uint64
+}
+
+// Write implements SyncWriter.
+func (d *DiscardSyncWriter) Write(p []byte) (int, error) {
+	atomic.AddUint64(&d.writes, 1)
+	atomic.AddUint64(&d.bytes, uint64(len(p)))
+	return len(p), nil
+}
+
+// Sync implements SyncWriter.
+func (d *DiscardSyncWriter) Sync() error {
+	return nil
+}
+
+// Writes returns the number of writes, which is the number of entries
+// written, plus one for each extra line of the multi-line entries, other
+// than for JSONFormat and Formatters, which write each entry at once.
+func (d *DiscardSyncWriter) Writes() uint64 {
+	return atomic.LoadUint64(&d.writes)
+}
+
+// Bytes returns the number of bytes written.
+func (d *DiscardSyncWriter) Bytes() uint64 {
+	return atomic.LoadUint64(&d.bytes)
+}
+
+// Reset sets the counts back to zero, e.g. after warming up.
+func (d *DiscardSyncWriter) Reset() {
+	atomic.StoreUint64(&d.writes, 0)
+	atomic.StoreUint64(&d.bytes, 0)
+}
@@ -0,0 +1,31 @@
+package logger
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestDiscardSyncWriter(t *testing.T) {
+	var d DiscardSyncWriter
+	l := NewFromOptions(&Options{SyncWriter: &d})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.Info("line")
+			l.Info("two\nlines")
+		}()
+	}
+	wg.Wait()
+	if d.Writes() != 30 {
+		t.Errorf("Got %d writes, want 30", d.Writes())
+	}
+	if d.Bytes() == 0 || d.Bytes()%10 != 0 {
+		t.Errorf("Got %d bytes, want a multiple of 10", d.Bytes())
+	}
+	d.Reset()
+	if d.Writes() != 0 || d.Bytes() != 0 {
+		t.Errorf("Not reset: %d %d", d.Writes(), d.Bytes())
+	}
+}
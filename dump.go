@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// Dump writes the stack traces of all goroutines as a Warning, followed by
+// the entries kept by Options.FlightRecorder, if set, with a
+// "flight_recorder" field of true, without exiting, for debugging a
+// process that's stuck. The dump is always written, whatever the minimum
+// severity, and the flight recorder keeps its entries for a later Dump or
+// Fatal entry.
+func (l *Logger) Dump() {
+	// Report the caller of Dump.
+	header, _, _ := l.header(warningLog, -1)
+	defer l.putBuffer(header)
+	// The dump itself isn't kept by the flight recorder.
+	header.flight = true
+	l.stacks(func(trace []byte) {
+		buf := l.getBuffer()
+		defer l.putBuffer(buf)
+		buf.WriteString("diagnostic dump, goroutine stack traces:\n")
+		buf.Write(trace)
+		l.emitFields(warningLog, buf, header, nil)
+	})
+	if l.flight != nil {
+		l.writeFlight(l.flight.snapshot())
+	}
+}
+
+// DumpOnSignal calls Dump each time the process receives one of sigs, or
+// SIGQUIT or SIGUSR1 if none are given, where they exist, until stop is
+// called. Handling SIGQUIT keeps the Go runtime from exiting with the
+// stack traces, so the process keeps running.
+//
+//	defer l.DumpOnSignal()()
+func (l *Logger) DumpOnSignal(sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = dumpSignals
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ch:
+				l.Dump()
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+			<-stopped
+		})
+	}
+}
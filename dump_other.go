@@ -0,0 +1,12 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+
+package logger
+
+import (
+	"os"
+	"syscall"
+)
+
+// dumpSignals are the default signals of DumpOnSignal, SIGUSR1 not
+// existing here.
+var dumpSignals = []os.Signal{syscall.SIGQUIT}
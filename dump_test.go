@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDump(t *testing.T) {
+	b := &flushBuffer{}
+	exited := false
+	l := NewFromOptions(&Options{SyncWriter: b, MinSeverity: ErrorSeverity, FlightRecorder: 2, Exit: func(int) { exited = true }})
+	l.Info("before")
+	l.Dump()
+	if exited {
+		t.Error("Dump exited")
+	}
+	got := b.String()
+	if !strings.HasPrefix(got, "W") || !strings.Contains(got, "dump_test.go:") || !strings.Contains(got, "] diagnostic dump, goroutine stack traces:\n") || !strings.Contains(got, "goroutine ") {
+		t.Errorf("Wrong stack traces: %q", got)
+	}
+	if lines := flightLines(got); len(lines) != 1 || !strings.HasSuffix(lines[0], "] before flight_recorder=true") {
+		t.Errorf("Wrong flight recorder entries: %q", lines)
+	}
+
+	// The flight recorder still has its entries, and not the dump.
+	b.Reset()
+	l.Fatal("crashed")
+	if lines := flightLines(b.String()); len(lines) != 1 || !strings.HasSuffix(lines[0], "] before flight_recorder=true") {
+		t.Errorf("Wrong flight recorder entries for Fatal: %q", lines)
+	}
+}
+
+func TestDumpOnSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals can't be sent on windows")
+	}
+	b := &syncBuffer{}
+	l := NewFromOptions(&Options{SyncWriter: b})
+	stop := l.DumpOnSignal()
+	defer stop()
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n, sig := range dumpSignals {
+		if err := p.Signal(sig); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; strings.Count(b.String(), "] diagnostic dump") != n+1; i++ {
+			if i == 100 {
+				t.Fatalf("No dump for %v", sig)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
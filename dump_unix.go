@@ -0,0 +1,11 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package logger
+
+import (
+	"os"
+	"syscall"
+)
+
+// dumpSignals are the default signals of DumpOnSignal.
+var dumpSignals = []os.Signal{syscall.SIGQUIT, syscall.SIGUSR1}
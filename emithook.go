@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"context"
+	"time"
+)
+
+// EmitHook instruments the writing of entries, so the cost of logging can be
+// measured, such as by an APM agent attributing it to the requests in its
+// traces, e.g.:
+//
+//	l := logger.NewFromOptions(&logger.Options{
+//		EmitHook: &logger.EmitHook{
+//			Before: func(ctx context.Context, s logger.Severity) context.Context {
+//				ctx, _ = tracer.Start(ctx, "log")
+//				return ctx
+//			},
+//			After: func(ctx context.Context, stats logger.EmitStats) {
+//				span := trace.SpanFromContext(ctx)
+//				span.SetAttributes(attribute.Int("log.bytes", stats.Bytes))
+//				span.End()
+//			},
+//		},
+//	})
+//
+// The hooks are only called for entries that are written, not those
+// dropped, such as Debug entries that aren't enabled, and are called on the
+// goroutine logging the entry, so they add to its cost and must be safe to
+// call concurrently. Entries written ahead of another, such as those of
+// Options.DebugBacklog, and by Dump, aren't instrumented.
+type EmitHook struct {
+	// Before, if not nil, is called before each entry is formatted and
+	// written, with the context passed to the function logging it, such as
+	// InfoCtx, or context.Background for the methods of Logger. It returns
+	// the context passed to After.
+	Before func(ctx context.Context, s Severity) context.Context
+
+	// After, if not nil, is called once the entry has been written, with
+	// the context returned by Before, or the one Before would have been
+	// called with if it's nil.
+	After func(ctx context.Context, stats EmitStats)
+}
+
+// EmitStats describes the writing of an entry, for EmitHook.After.
+type EmitStats struct {
+	// Severity is the severity of the entry.
+	Severity Severity
+
+	// Duration is the time taken to format the entry and write it to the
+	// destination, or to queue it with Options.AsyncQueueSize, as measured
+	// by Options.Now if it's set. It doesn't include formatting the message,
+	// such as by Infof, or the hooks.
+	Duration time.Duration
+
+	// Bytes is the number of bytes written to the destination, including
+	// the stack traces of a Fatal entry, and the headers of the lines of an
+	// entry that spans more than one.
+	Bytes int
+}
+
+// emitInstrumented is emitFields for the entry in buf, with
+// Options.EmitHook called around it.
+func (l *Logger) emitInstrumented(s severity, buf, header *buffer, fields []Field) {
+	ctx := header.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if l.emitHook.Before != nil {
+		ctx = l.emitHook.Before(ctx, Severity(s))
+	}
+	n := 0
+	header.written = &n
+	start := l.timeNow()
+	l.emitFields(s, buf, header, fields)
+	duration := l.timeNow().Sub(start)
+	header.written = nil
+	if l.emitHook.After != nil {
+		l.emitHook.After(ctx, EmitStats{Severity: Severity(s), Duration: duration, Bytes: n})
+	}
+}
@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type hookKey struct{}
+
+func TestEmitHook(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	var got []EmitStats
+	var ctxs []string
+	buf := &flushBuffer{}
+	l := NewFromOptions(&Options{
+		SyncWriter: buf,
+		Now: func() time.Time {
+			now = now.Add(time.Millisecond)
+			return now
+		},
+		EmitHook: &EmitHook{
+			Before: func(ctx context.Context, s Severity) context.Context {
+				return context.WithValue(ctx, hookKey{}, s.String())
+			},
+			After: func(ctx context.Context, stats EmitStats) {
+				from := "background"
+				if ctx.Value(contextKey{}) != nil {
+					from = "request"
+				}
+				ctxs = append(ctxs, ctx.Value(hookKey{}).(string)+" "+from)
+				got = append(got, stats)
+			},
+		},
+		Exit: func(int) {},
+	})
+	l.Info("first")
+	l.Debug("dropped")
+	WarningCtx(NewContext(context.Background(), l), "second", Int("n", 2))
+	l.Fatal("third")
+
+	if len(got) != 3 {
+		t.Fatalf("Got %d calls, want 3: %v", len(got), got)
+	}
+	if want := "INFO background, WARNING request, FATAL background"; strings.Join(ctxs, ", ") != want {
+		t.Errorf("Got contexts %q, want %s", ctxs, want)
+	}
+	lines := strings.SplitAfter(buf.String(), "\n")
+	for i, s := range []Severity{InfoSeverity, WarningSeverity} {
+		if got[i].Severity != s || got[i].Bytes != len(lines[i]) {
+			t.Errorf("Wrong stats: %+v for %q", got[i], lines[i])
+		}
+	}
+	// The stack traces are counted.
+	if got[2].Bytes != buf.Len()-len(lines[0])-len(lines[1]) {
+		t.Errorf("Fatal entry of %d bytes, want %d", got[2].Bytes, buf.Len()-len(lines[0])-len(lines[1]))
+	}
+	for _, stats := range got {
+		if stats.Duration != time.Millisecond {
+			t.Errorf("Duration %s, want 1ms", stats.Duration)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package logger
+
+import "sync/atomic"
+
+// recordSeverity records that an entry of severity s is being written, for
+// ExitCode.
+func (l *Logger) recordSeverity(s severity) {
+	for {
+		max := atomic.LoadInt32(&l.maxSeverity)
+		if max >= 0 && !Severity(max).less(Severity(s)) {
+			return
+		}
+		if atomic.CompareAndSwapInt32(&l.maxSeverity, max, int32(s)) {
+			return
+		}
+	}
+}
+
+// ExitCode returns an exit status that reflects the problems logged, for
+// command line tools to end with:
+//
+//	func main() {
+//		l := logger.New()
+//		run(l)
+//		os.Exit(l.ExitCode())
+//	}
+//
+// It's the status given in Options.ExitCodes for the most severe of the
+// severities there that's at or below the highest severity of the entries
+// written so far, or 0 if there's none. By default it's 1 once an Error
+// has been written. Entries that are dropped, e.g. by MinSeverity, aren't
+// counted.
+func (l *Logger) ExitCode() int {
+	max := atomic.LoadInt32(&l.maxSeverity)
+	if max < 0 {
+		return 0
+	}
+	codes := l.exitCodes
+	if codes == nil {
+		codes = map[Severity]int{ErrorSeverity: 1}
+	}
+	code, found, best := 0, false, Severity(0)
+	for s, c := range codes {
+		if Severity(max).less(s) || found && s.less(best) {
+			continue
+		}
+		code, found, best = c, true, s
+	}
+	return code
+}
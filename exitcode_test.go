@@ -0,0 +1,41 @@
+package logger
+
+import "testing"
+
+func TestExitCode(t *testing.T) {
+	l := NewFromOptions(&Options{SyncWriter: &flushBuffer{}})
+	l.Info("fine")
+	l.Warning("hmm")
+	if got := l.ExitCode(); got != 0 {
+		t.Errorf("Got %d after a Warning, want 0", got)
+	}
+	l.Named("child").Error("failed")
+	l.Info("fine again")
+	if got := l.ExitCode(); got != 1 {
+		t.Errorf("Got %d after an Error, want 1", got)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	l := NewFromOptions(&Options{
+		SyncWriter:  &flushBuffer{},
+		ExitCodes:   map[Severity]int{WarningSeverity: 2, ErrorSeverity: 3},
+		MinSeverity: InfoSeverity,
+	})
+	if got := l.ExitCode(); got != 0 {
+		t.Errorf("Got %d before logging, want 0", got)
+	}
+	l.Warning("hmm")
+	if got := l.ExitCode(); got != 2 {
+		t.Errorf("Got %d after a Warning, want 2", got)
+	}
+	l.Error("failed")
+	if got := l.ExitCode(); got != 3 {
+		t.Errorf("Got %d after an Error, want 3", got)
+	}
+	dropped := NewFromOptions(&Options{SyncWriter: &flushBuffer{}, MinSeverity: FatalSeverity})
+	dropped.Error("dropped")
+	if got := dropped.ExitCode(); got != 0 {
+		t.Errorf("Got %d for a dropped Error, want 0", got)
+	}
+}
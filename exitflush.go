@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// exitLoggers holds the Loggers registered with FlushOnExit.
+var exitLoggers struct {
+	mu      sync.Mutex
+	loggers []*Logger
+}
+
+// FlushOnExit registers l to be flushed by FlushAll, and so before the
+// process exits via Exit, a Fatal entry, or a signal handled by
+// FlushOnSignal, so the tail of the log in buffered destinations, such as
+// an AsyncWriter, isn't lost. Registering a Logger more than once is a
+// no-op. The returned func unregisters it, e.g.:
+//
+//	l := logger.NewFromOptions(&logger.Options{AsyncQueueSize: 4096})
+//	logger.FlushOnExit(l)
+//	defer logger.FlushOnSignal()()
+//	defer logger.FlushAll()
+func FlushOnExit(l *Logger) (unregister func()) {
+	exitLoggers.mu.Lock()
+	defer exitLoggers.mu.Unlock()
+	for _, existing := range exitLoggers.loggers {
+		if existing == l {
+			return func() { unregisterExit(l) }
+		}
+	}
+	exitLoggers.loggers = append(exitLoggers.loggers, l)
+	return func() { unregisterExit(l) }
+}
+
+func unregisterExit(l *Logger) {
+	exitLoggers.mu.Lock()
+	defer exitLoggers.mu.Unlock()
+	for i, existing := range exitLoggers.loggers {
+		if existing == l {
+			exitLoggers.loggers = append(exitLoggers.loggers[:i:i], exitLoggers.loggers[i+1:]...)
+			return
+		}
+	}
+}
+
+// FlushAll flushes every Logger registered with FlushOnExit, see
+// Logger.Flush, and returns the first error. Deferring it in main flushes
+// them when main returns, as atexit would.
+func FlushAll() error {
+	exitLoggers.mu.Lock()
+	loggers := append([]*Logger(nil), exitLoggers.loggers...)
+	exitLoggers.mu.Unlock()
+	var err error
+	for _, l := range loggers {
+		if flushErr := l.Flush(); err == nil {
+			err = flushErr
+		}
+	}
+	return err
+}
+
+// Exit calls FlushAll, then exits with code. Use it in place of os.Exit,
+// which doesn't run deferred calls.
+func Exit(code int) {
+	FlushAll()
+	osExit(code)
+}
+
+// FlushOnSignal calls FlushAll when the process receives one of sigs, or
+// SIGINT or SIGTERM if none are given, until stop is called. After
+// flushing it stops handling the signal and sends it to the process again,
+// so the process exits, or any other handlers of it run, as they would
+// have without FlushOnSignal. If the signal can't be sent again, as on
+// Windows, the process exits with status 1.
+func FlushOnSignal(sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case sig := <-ch:
+			FlushAll()
+			signal.Stop(ch)
+			if p, err := os.FindProcess(os.Getpid()); err != nil || p.Signal(sig) != nil {
+				osExit(1)
+			}
+		case <-done:
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+			<-stopped
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// bufferedFile only writes to f when synced.
+type bufferedFile struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+	f   *os.File
+}
+
+func (b *bufferedFile) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *bufferedFile) Sync() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, err := b.buf.WriteTo(b.f)
+	return err
+}
+
+// TestFlushOnSignalHelperProcess is run as the child process by
+// TestFlushOnSignal.
+func TestFlushOnSignalHelperProcess(t *testing.T) {
+	path := os.Getenv("LOGGER_TEST_FLUSH_FILE")
+	if path == "" {
+		t.Skip("only run as a child process")
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewFromOptions(&Options{SyncWriter: &bufferedFile{f: f}})
+	FlushOnExit(l)
+	FlushOnSignal()
+	l.Info("before the signal")
+	p, _ := os.FindProcess(os.Getpid())
+	p.Signal(syscall.SIGTERM)
+	time.Sleep(10 * time.Second)
+	t.Error("not killed by the signal")
+}
+
+func TestFlushOnSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not supported on windows")
+	}
+	path := filepath.Join(t.TempDir(), "log")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFlushOnSignalHelperProcess$")
+	cmd.Env = append(os.Environ(), "LOGGER_TEST_FLUSH_FILE="+path)
+	err := cmd.Run()
+	if status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); !ok || !status.Signaled() || status.Signal() != syscall.SIGTERM {
+		t.Errorf("Child wasn't killed by SIGTERM: %v", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "] before the signal\n") {
+		t.Errorf("Entry wasn't flushed: %q", b)
+	}
+}
+
+func TestFlushAll(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := &bufferedFile{f: f}
+	l := NewFromOptions(&Options{SyncWriter: w})
+	unregister := FlushOnExit(l)
+	FlushOnExit(l)
+	l.Info("first")
+	if err := FlushAll(); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(f.Name())
+	if !strings.HasSuffix(string(b), "] first\n") {
+		t.Errorf("Wrong contents after FlushAll: %q", b)
+	}
+
+	unregister()
+	l.Info("second")
+	FlushAll()
+	if b, _ := os.ReadFile(f.Name()); strings.Contains(string(b), "second") {
+		t.Errorf("Unregistered Logger was flushed: %q", b)
+	}
+}
+
+func TestExitFlushes(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	l := NewFromOptions(&Options{SyncWriter: &bufferedFile{f: f}})
+	defer FlushOnExit(l)()
+	code := -1
+	osExit = func(c int) { code = c }
+	defer func() { osExit = os.Exit }()
+
+	l.Info("last words")
+	Exit(3)
+	if code != 3 {
+		t.Errorf("Exit code %d, want 3", code)
+	}
+	if b, _ := os.ReadFile(f.Name()); !strings.HasSuffix(string(b), "] last words\n") {
+		t.Errorf("Wrong contents after Exit: %q", b)
+	}
+}
@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// defaultFatalHookTimeout is how long a hook registered with OnFatal is
+// given to run if no timeout is specified.
+const defaultFatalHookTimeout = 5 * time.Second
+
+// fatalHook is a hook registered with OnFatal.
+type fatalHook struct {
+	f       func()
+	timeout time.Duration
+}
+
+// OnFatal registers f to be run after a Fatal log has been written and
+// synced, but before the process exits, e.g. to flush traces or metrics, or
+// to notify someone.
+//
+// Hooks are run one at a time in the order they were registered. Each is
+// given timeout to finish, or 5s if timeout is zero, after which the next
+// hook is run regardless. A hook that panics or times out is reported to
+// Options.Diagnostics and doesn't stop the remaining hooks from running.
+func (l *Logger) OnFatal(timeout time.Duration, f func()) {
+	if timeout <= 0 {
+		timeout = defaultFatalHookTimeout
+	}
+	l.fatalHooksMu.Lock()
+	defer l.fatalHooksMu.Unlock()
+	l.fatalHooks = append(l.fatalHooks, fatalHook{f: f, timeout: timeout})
+}
+
+// runFatalHooks runs the hooks registered with OnFatal.
+func (l *Logger) runFatalHooks() {
+	l.fatalHooksMu.Lock()
+	hooks := append([]fatalHook(nil), l.fatalHooks...)
+	l.fatalHooksMu.Unlock()
+	for i, hook := range hooks {
+		if err := hook.run(); err != nil {
+			l.diagnosef("fatal hook %d of %d: %s", i+1, len(hooks), err)
+		}
+	}
+}
+
+// run runs the hook, returning an error if it panics or doesn't finish in
+// time.
+func (h fatalHook) run() error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		h.f()
+		done <- nil
+	}()
+	timer := time.NewTimer(h.timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("timed out after %s", h.timeout)
+	}
+}
+
+// diagnosef reports a problem with the Logger itself to Options.Diagnostics,
+// or os.Stderr, since it can't be trusted to log it.
+func (l *Logger) diagnosef(format string, args ...interface{}) {
+	w := l.diagnostics
+	if w == nil {
+		w = os.Stderr
+	}
+	fmt.Fprintf(w, "logger: "+format+"\n", args...)
+}
@@ -0,0 +1,47 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOnFatal(t *testing.T) {
+	var diagnostics bytes.Buffer
+	var ran []int
+	exited := false
+	l := NewFromOptions(&Options{
+		SyncWriter:  &flushBuffer{},
+		Diagnostics: &diagnostics,
+		Exit:        func(int) { exited = true },
+	})
+	l.OnFatal(0, func() { ran = append(ran, 1) })
+	l.OnFatal(0, func() { panic("hook broke") })
+	block := make(chan struct{})
+	defer close(block)
+	l.OnFatal(10*time.Millisecond, func() { <-block })
+	l.OnFatal(0, func() {
+		if exited {
+			t.Error("Exited before running all hooks.")
+		}
+		ran = append(ran, 4)
+	})
+	l.Fatal("goodbye")
+
+	if len(ran) != 2 || ran[0] != 1 || ran[1] != 4 {
+		t.Errorf("Hooks not run in order: %v", ran)
+	}
+	if !exited {
+		t.Error("Did not exit.")
+	}
+	got := diagnostics.String()
+	for _, want := range []string{
+		"logger: fatal hook 2 of 4: panic: hook broke\n",
+		"logger: fatal hook 3 of 4: timed out after 10ms\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Diagnostics %q missing %q", got, want)
+		}
+	}
+}
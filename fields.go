@@ -0,0 +1,192 @@
+package logger
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fieldType identifies which member of Field holds the value.
+type fieldType uint8
+
+const (
+	stringField fieldType = iota
+	int64Field
+	float64Field
+	boolField
+	durationField
+	timeField
+	errorField
+	anyField
+	groupField
+)
+
+// Field is a typed key/value pair attached to a log entry.
+//
+// Fields are constructed with the functions below, such as Str and Int,
+// which store common types without reflection or boxing them in an
+// interface{}, and are then passed to the *Fields logging methods, e.g.:
+//
+//	l.InfoFields("request served", logger.Str("path", p), logger.Int("status", 200))
+type Field struct {
+	Key string
+
+	t     fieldType
+	num   int64
+	str   string
+	iface interface{}
+}
+
+// Str constructs a Field with a string value.
+func Str(key, val string) Field {
+	return Field{Key: key, t: stringField, str: val}
+}
+
+// Int constructs a Field with an int value.
+func Int(key string, val int) Field {
+	return Field{Key: key, t: int64Field, num: int64(val)}
+}
+
+// Int64 constructs a Field with an int64 value.
+func Int64(key string, val int64) Field {
+	return Field{Key: key, t: int64Field, num: val}
+}
+
+// Float64 constructs a Field with a float64 value.
+func Float64(key string, val float64) Field {
+	return Field{Key: key, t: float64Field, num: int64(math.Float64bits(val))}
+}
+
+// Bool constructs a Field with a bool value.
+func Bool(key string, val bool) Field {
+	var num int64
+	if val {
+		num = 1
+	}
+	return Field{Key: key, t: boolField, num: num}
+}
+
+// Dur constructs a Field with a time.Duration value.
+func Dur(key string, val time.Duration) Field {
+	return Field{Key: key, t: durationField, num: int64(val)}
+}
+
+// Time constructs a Field with a time.Time value.
+func Time(key string, val time.Time) Field {
+	if val.Before(minNanoTime) || val.After(maxNanoTime) {
+		// UnixNano overflows, as it does for the zero time.Time, so box it.
+		return Field{Key: key, t: timeField, iface: val}
+	}
+	// Store the location as a pointer, which doesn't require an allocation,
+	// instead of boxing the whole time.Time.
+	return Field{Key: key, t: timeField, num: val.UnixNano(), iface: val.Location()}
+}
+
+// minNanoTime and maxNanoTime are the range of times UnixNano represents.
+var (
+	minNanoTime = time.Unix(0, math.MinInt64)
+	maxNanoTime = time.Unix(0, math.MaxInt64)
+)
+
+// time returns the value of a Field constructed by Time.
+func (f Field) time() time.Time {
+	if t, ok := f.iface.(time.Time); ok {
+		return t
+	}
+	t := time.Unix(0, f.num)
+	if loc, ok := f.iface.(*time.Location); ok && loc != nil {
+		t = t.In(loc)
+	}
+	return t
+}
+
+// Err constructs a Field with the key "error" from err. A nil err is
+// recorded as the string "<nil>".
+func Err(err error) Field {
+	return Field{Key: "error", t: errorField, iface: err}
+}
+
+// Any constructs a Field from an arbitrary value. Maps, slices, and arrays
+// are rendered deterministically, see renderAny, and all other values are
+// formatted in the manner of fmt.Print. Prefer the typed constructors where
+// possible.
+func Any(key string, val interface{}) Field {
+	return Field{Key: key, t: anyField, iface: val}
+}
+
+// Group constructs a Field that holds other fields under the key.
+//
+// In text output the fields are rendered with dotted keys, e.g.:
+//
+//	logger.Group("http", logger.Str("method", "GET"), logger.Int("status", 200))
+//
+// is rendered as "http.method=GET http.status=200". As with the slog
+// package, a Group with an empty key has its fields inlined into the
+// parent, and a Group with no fields is omitted entirely.
+func Group(key string, fields ...Field) Field {
+	return Field{Key: key, t: groupField, iface: fields}
+}
+
+// appendTo writes the field to buf as " key=value".
+func (f Field) appendTo(buf *buffer) {
+	f.appendToWithPrefix(buf, "")
+}
+
+// appendToWithPrefix writes the field to buf as " prefixkey=value", where
+// prefix is the dotted path of any enclosing groups.
+func (f Field) appendToWithPrefix(buf *buffer, prefix string) {
+	if f.t == groupField {
+		if f.Key != "" {
+			prefix = prefix + f.Key + "."
+		}
+		for _, child := range f.iface.([]Field) {
+			child.appendToWithPrefix(buf, prefix)
+		}
+		return
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(prefix)
+	buf.WriteString(f.Key)
+	buf.WriteByte('=')
+	switch f.t {
+	case stringField:
+		appendValue(buf, f.str)
+	case int64Field:
+		buf.Write(strconv.AppendInt(buf.tmp[:0], f.num, 10))
+	case float64Field:
+		buf.Write(strconv.AppendFloat(buf.tmp[:0], math.Float64frombits(uint64(f.num)), 'g', -1, 64))
+	case boolField:
+		buf.Write(strconv.AppendBool(buf.tmp[:0], f.num == 1))
+	case durationField:
+		buf.WriteString(time.Duration(f.num).String())
+	case timeField:
+		buf.Write(f.time().AppendFormat(buf.tmp[:0], time.RFC3339Nano))
+	case errorField:
+		if f.iface == nil {
+			buf.WriteString("<nil>")
+			return
+		}
+		appendValue(buf, f.iface.(error).Error())
+	default:
+		appendValue(buf, renderAny(f.iface))
+	}
+}
+
+// appendKeyValue writes " key=value" to buf, quoting the value if needed.
+func appendKeyValue(buf *buffer, key, value string) {
+	buf.WriteByte(' ')
+	buf.WriteString(key)
+	buf.WriteByte('=')
+	appendValue(buf, value)
+}
+
+// appendValue writes value to buf, quoting it if it is empty or contains
+// characters that would make the key=value pairs ambiguous.
+func appendValue(buf *buffer, value string) {
+	if value == "" || strings.ContainsAny(value, " \t\r\n\"=") {
+		buf.WriteString(strconv.Quote(value))
+		return
+	}
+	buf.WriteString(value)
+}
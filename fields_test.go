@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFieldRendering(t *testing.T) {
+	ts := time.Date(2006, 1, 2, 15, 4, 5, 67890, time.UTC)
+	tests := []struct {
+		field Field
+		want  string
+	}{
+		{Str("s", "plain"), " s=plain"},
+		{Str("s", "has space"), ` s="has space"`},
+		{Str("s", ""), ` s=""`},
+		{Int("i", -42), " i=-42"},
+		{Int64("i", 1<<40), " i=1099511627776"},
+		{Float64("f", 1.5), " f=1.5"},
+		{Bool("b", true), " b=true"},
+		{Bool("b", false), " b=false"},
+		{Dur("d", 1500*time.Millisecond), " d=1.5s"},
+		{Time("t", ts), " t=2006-01-02T15:04:05.00006789Z"},
+		{Time("t", time.Time{}), " t=0001-01-01T00:00:00Z"},
+		{Time("t", time.Date(2500, 1, 2, 3, 4, 5, 0, time.UTC)), " t=2500-01-02T03:04:05Z"},
+		{Err(errors.New("it broke")), ` error="it broke"`},
+		{Err(nil), " error=<nil>"},
+		{Any("a", []int{1, 2}), " a=[1,2]"},
+		{Group("http", Str("method", "GET"), Int("status", 200)), " http.method=GET http.status=200"},
+		{Group("a", Group("b", Int("c", 1)), Int("d", 2)), " a.b.c=1 a.d=2"},
+		{Group("", Int("inlined", 1)), " inlined=1"},
+		{Group("empty"), ""},
+	}
+	for _, tc := range tests {
+		buf := &buffer{}
+		tc.field.appendTo(buf)
+		if got := buf.String(); got != tc.want {
+			t.Errorf("Got %q want %q", got, tc.want)
+		}
+	}
+}
+
+func TestInfoFields(t *testing.T) {
+	newTestLogger()
+	testLogger.InfoFields("served", Str("path", "/index.html"), Int("status", 200))
+	if !strings.HasSuffix(contents(), "] served path=/index.html status=200\n") {
+		t.Errorf("Wrong output: %q", contents())
+	}
+	if !strings.HasPrefix(contents(), "I") {
+		t.Errorf("InfoFields has wrong character: %q", contents())
+	}
+}
+
+func TestDebugFields(t *testing.T) {
+	newTestLogger()
+	testLogger.DebugFields("test", Int("n", 1))
+	if contents() != "" {
+		t.Errorf("DebugFields should not be emitted by default: %q", contents())
+	}
+	testLogger.SetIncludeDebug(true)
+	testLogger.DebugFields("test", Int("n", 1))
+	if !strings.HasPrefix(contents(), "D") {
+		t.Errorf("DebugFields has wrong character: %q", contents())
+	}
+}
+
+// Test that the caller reported is the one calling the *Fields method.
+func TestFieldsWithoutMessage(t *testing.T) {
+	newTestLogger()
+	testLogger.ErrorFields("", Err(errors.New("it broke")), Int("n", 1))
+	testLogger.ErrorFields("\n", Int("n", 2))
+	testLogger.Error("")
+	lines := strings.Split(strings.TrimSuffix(contents(), "\n"), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], `] error="it broke" n=1`) || !strings.HasSuffix(lines[1], "] n=2") {
+		t.Errorf("Want a line of just the fields for each entry with fields, got %q", lines)
+	}
+}
+
+func TestFieldsHeader(t *testing.T) {
+	newTestLogger()
+	testLogger.WarningFields("test")
+	if !contains(" fields_test.go:", t) {
+		t.Errorf("Wrong caller: %q", contents())
+	}
+}
+
+func BenchmarkInfoFields(b *testing.B) {
+	l := NewFromOptions(&Options{SyncWriter: &discardWriter{}})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.InfoFields("request served", Str("path", "/index.html"), Int("status", 200), Dur("latency", time.Millisecond))
+	}
+}
+
+func BenchmarkInfoVarargs(b *testing.B) {
+	l := NewFromOptions(&Options{SyncWriter: &discardWriter{}})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Info("request served path=", "/index.html", " status=", 200, " latency=", time.Millisecond)
+	}
+}
+
+// discardWriter is a SyncWriter that throws away everything written.
+type discardWriter struct{}
+
+func (discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+
+func (discardWriter) Sync() error { return nil }
@@ -0,0 +1,665 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FileOptions control how a FileWriter rotates its file. The zero value
+// never rotates.
+type FileOptions struct {
+	// RotateEvery, if greater than zero, rotates the file at every multiple
+	// of it since midnight, e.g. time.Hour rotates hourly, and 24*time.Hour
+	// daily. It's at most 24 hours, and the boundaries start again at
+	// midnight, e.g. 10*time.Hour rotates at 00:00, 10:00, and 20:00. The
+	// file is rotated by renaming it to include the start of the period it
+	// covers, see TimePattern, and starting a new one.
+	RotateEvery time.Duration
+
+	// RotateOffset moves the boundaries later in the day, e.g. with a
+	// RotateEvery of 24*time.Hour an offset of 2*time.Hour rotates at 02:00
+	// instead of midnight. It's less than 24 hours.
+	RotateOffset time.Duration
+
+	// TimePattern is the time layout of the start of the period that's
+	// inserted before the extension of a rotated file, e.g. app.log is
+	// rotated to app.2006-01-02.log. The default depends on RotateEvery,
+	// "2006-01-02" for daily rotation, "2006-01-02T15" for hourly, and
+	// "2006-01-02T15-04" otherwise.
+	TimePattern string
+
+	// Location is the time zone of the boundaries and TimePattern. If nil
+	// the local time zone is used.
+	Location *time.Location
+
+	// Compress, if true, gzips each rotated file in the background, adding
+	// ".gz" to its name. The compressed data is written to a ".gz.tmp" file
+	// first, which is only renamed once complete, and the rotated file is
+	// only removed after that, so a crash never loses it. A ".gz.tmp" file
+	// left by a crash is removed, and the compression started again, by the
+	// next NewFileWriterFromOptions.
+	Compress bool
+
+	// MaxAge, if greater than zero, removes rotated files last modified
+	// longer than it ago.
+	MaxAge time.Duration
+
+	// MaxBackups, if greater than zero, removes the oldest rotated files
+	// beyond that many. A rotated file and its compressed copy count as
+	// one.
+	//
+	// Rotated files are removed, as selected by MaxAge and MaxBackups, after
+	// each rotation and compression, and by NewFileWriterFromOptions, for
+	// those left by previous runs. Files being compressed aren't removed
+	// until the compression completes.
+	MaxBackups int
+
+	// MaxTotalSize, if greater than zero, is the most bytes the file and
+	// the rotated files may take up in total. Before a write that would
+	// exceed it the oldest rotated files are removed, and if that's not
+	// enough, because the rest are being compressed or there are none, the
+	// file is truncated. Writes larger than MaxTotalSize fail. Other
+	// processes appending to the file, and the partial copies of files
+	// being compressed, aren't accounted for, unless Shared is set.
+	MaxTotalSize int64
+
+	// Shared, if true, coordinates with other processes appending to the
+	// same file that also set it, so they can rotate it without corrupting
+	// it or rotating it more than once. Each write holds an advisory lock
+	// on a file named by adding ".lock" to the name of the file, during
+	// which the FileWriter switches to the file at path if another process
+	// has rotated it since, rather than rotating it again. Rotated files
+	// another process is compressing aren't removed. Shared isn't supported
+	// on Windows and Plan 9, where NewFileWriterFromOptions fails.
+	Shared bool
+
+	// Diagnostics is where problems in the background, such as failing to
+	// compress a file, are reported. If nil then os.Stderr is used.
+	Diagnostics io.Writer
+
+	// Now, if not nil, is used instead of time.Now.
+	Now func() time.Time
+}
+
+// FileWriter is a SyncWriter that appends to a file, optionally rotating
+// it, see FileOptions.
+type FileWriter struct {
+	path string
+	o    FileOptions
+
+	// mu protects f, start, next, size, rotatedSize, and inFlight.
+	mu sync.Mutex
+	f  *os.File
+
+	// start is the start of the period the file covers, and next the start
+	// of the following one, when the file is rotated. Both are zero if the
+	// file isn't rotated.
+	start, next time.Time
+
+	// size is the size of the file, and rotatedSize that of the rotated
+	// files, as last seen, for MaxTotalSize.
+	size, rotatedSize int64
+
+	// compressing tracks the files being compressed, for Close, and
+	// inFlight holds their paths, so they aren't removed while being
+	// compressed.
+	compressing sync.WaitGroup
+	inFlight    map[string]bool
+
+	// lock is the file locked while writing if FileOptions.Shared is set,
+	// otherwise nil.
+	lock *os.File
+}
+
+// NewFileWriter returns a FileWriter that appends to the file at path,
+// creating it if needed.
+func NewFileWriter(path string) (*FileWriter, error) {
+	return NewFileWriterFromOptions(path, &FileOptions{})
+}
+
+// NewFileWriterFromOptions returns a FileWriter that appends to the file at
+// path, creating it if needed, and rotates it as selected by o. An existing
+// file is taken to cover the period of its modification time, so it's
+// rotated by the first write if that period is over.
+func NewFileWriterFromOptions(path string, o *FileOptions) (*FileWriter, error) {
+	if o.RotateEvery > 24*time.Hour {
+		return nil, fmt.Errorf("RotateEvery of %s is more than 24 hours", o.RotateEvery)
+	}
+	if o.RotateOffset < 0 || o.RotateOffset >= 24*time.Hour {
+		return nil, fmt.Errorf("RotateOffset of %s isn't within a day", o.RotateOffset)
+	}
+	w := &FileWriter{path: path, o: *o, inFlight: map[string]bool{}}
+	if w.o.Location == nil {
+		w.o.Location = time.Local
+	}
+	if w.o.Now == nil {
+		w.o.Now = time.Now
+	}
+	if w.o.Diagnostics == nil {
+		w.o.Diagnostics = os.Stderr
+	}
+	if w.o.TimePattern == "" {
+		switch {
+		case w.o.RotateEvery == 24*time.Hour:
+			w.o.TimePattern = "2006-01-02"
+		case w.o.RotateEvery%time.Hour == 0:
+			w.o.TimePattern = "2006-01-02T15"
+		default:
+			w.o.TimePattern = "2006-01-02T15-04"
+		}
+	}
+	if w.o.Shared {
+		lock, err := os.OpenFile(w.path+".lock", os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, err
+		}
+		if err := lockFile(lock); err != nil {
+			lock.Close()
+			return nil, err
+		}
+		defer unlockFile(lock)
+		w.lock = lock
+	}
+	if err := w.open(); err != nil {
+		w.closeLock()
+		return nil, err
+	}
+	if w.o.RotateEvery > 0 {
+		covers := w.o.Now()
+		if fi, err := w.f.Stat(); err == nil && fi.Size() > 0 {
+			covers = fi.ModTime()
+		}
+		w.start, w.next = w.period(covers)
+	}
+	if w.o.Compress {
+		w.resumeCompression()
+	}
+	w.mu.Lock()
+	w.prune()
+	w.mu.Unlock()
+	return w, nil
+}
+
+func (w *FileWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	w.f = f
+	w.size = 0
+	if fi, err := f.Stat(); err == nil {
+		w.size = fi.Size()
+	}
+	return nil
+}
+
+// period returns the start of the period that contains t, and of the one
+// that follows it.
+func (w *FileWriter) period(t time.Time) (time.Time, time.Time) {
+	t = t.In(w.o.Location)
+	dayStart := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, w.o.Location).Add(w.o.RotateOffset)
+	}
+	year, month, day := t.Date()
+	if t.Before(dayStart(year, month, day)) {
+		day--
+	}
+	first, nextDay := dayStart(year, month, day), dayStart(year, month, day+1)
+	start := first.Add(t.Sub(first) / w.o.RotateEvery * w.o.RotateEvery)
+	next := start.Add(w.o.RotateEvery)
+	if next.After(nextDay) {
+		next = nextDay
+	}
+	return start, next
+}
+
+// rotatedPath returns the path the file is renamed to when rotated, for the
+// period starting at start.
+func (w *FileWriter) rotatedPath(start time.Time) string {
+	ext := filepath.Ext(w.path)
+	base := strings.TrimSuffix(w.path, ext)
+	stamp := start.In(w.o.Location).Format(w.o.TimePattern)
+	path := base + "." + stamp + ext
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = fmt.Sprintf("%s.%s.%d%s", base, stamp, i, ext)
+	}
+}
+
+// rotate renames the file for the period that's over and starts a new one
+// for the period containing now. w.mu must be held.
+func (w *FileWriter) rotate(now time.Time) error {
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	// If the rename fails keep appending to the same file, rather than
+	// losing logs.
+	rotated := w.rotatedPath(w.start)
+	if err := os.Rename(w.path, rotated); err == nil && w.o.Compress {
+		w.startCompression(rotated)
+	}
+	w.prune()
+	w.start, w.next = w.period(now)
+	return w.open()
+}
+
+// defaultTimePatterns are the defaults of FileOptions.TimePattern, which the
+// files rotated by previous runs may have used.
+var defaultTimePatterns = []string{"2006-01-02", "2006-01-02T15", "2006-01-02T15-04"}
+
+// isRotated returns true if name, a base name in the directory of the file,
+// is that of a rotated file, possibly with suffix added, as named by
+// rotatedPath: the stamp of TimePattern, or of one of its defaults, and an
+// optional counter between the base name and the extension. Other files
+// that share the base name and extension, such as app.error.log for
+// app.log, aren't.
+func (w *FileWriter) isRotated(name, suffix string) bool {
+	ext := filepath.Ext(w.path)
+	base := strings.TrimSuffix(filepath.Base(w.path), ext)
+	if !strings.HasPrefix(name, base+".") || !strings.HasSuffix(name, ext+suffix) || len(name) <= len(base)+1+len(ext)+len(suffix) {
+		return false
+	}
+	stamp := name[len(base)+1 : len(name)-len(ext)-len(suffix)]
+	if w.isStamp(stamp) {
+		return true
+	}
+	i := strings.LastIndexByte(stamp, '.')
+	if i < 0 {
+		return false
+	}
+	if n, err := strconv.Atoi(stamp[i+1:]); err != nil || n < 1 {
+		return false
+	}
+	return w.isStamp(stamp[:i])
+}
+
+// isStamp returns true if stamp is the start of a period formatted with
+// TimePattern, or one of its defaults.
+func (w *FileWriter) isStamp(stamp string) bool {
+	for _, layout := range append([]string{w.o.TimePattern}, defaultTimePatterns...) {
+		if _, err := time.ParseInLocation(layout, stamp, w.o.Location); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// resumeCompression removes the ".gz.tmp" files left by compressions that
+// didn't complete, and compresses their rotated files again.
+func (w *FileWriter) resumeCompression() {
+	dir := filepath.Dir(w.path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		fmt.Fprintf(w.o.Diagnostics, "logger: can't resume compressing rotated files: %s\n", err)
+		return
+	}
+	for _, e := range entries {
+		if !w.isRotated(e.Name(), ".gz.tmp") {
+			continue
+		}
+		tmp := filepath.Join(dir, e.Name())
+		if w.lock != nil && compressionInProgress(tmp) {
+			continue
+		}
+		os.Remove(tmp)
+		rotated := strings.TrimSuffix(tmp, ".gz.tmp")
+		if _, err := os.Stat(rotated); err == nil {
+			w.startCompression(rotated)
+		}
+	}
+}
+
+// startCompression compresses the rotated file at path in the background.
+// w.mu must be held, or w not yet shared.
+func (w *FileWriter) startCompression(path string) {
+	w.compressing.Add(1)
+	w.inFlight[path] = true
+	go func() {
+		defer w.compressing.Done()
+		if err := compressFile(path, w.lock != nil); err != nil {
+			fmt.Fprintf(w.o.Diagnostics, "logger: failed to compress %s: %s\n", path, err)
+		}
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.inFlight, path)
+		w.prune()
+	}()
+}
+
+// backup is a rotated file, along with its compressed copy, if any.
+type backup struct {
+	paths   []string
+	modTime time.Time
+	size    int64
+
+	// compressing is true if the file is being compressed.
+	compressing bool
+}
+
+// backups returns the rotated files, newest first. w.mu must be held.
+func (w *FileWriter) backups() ([]*backup, error) {
+	dir := filepath.Dir(w.path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string]*backup{}
+	var ret []*backup
+	// Other processes sharing the file may be compressing a rotated file,
+	// which is only known from its ".gz.tmp" file.
+	sharedInFlight := map[string]bool{}
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasSuffix(name, ".gz.tmp") {
+			if w.lock != nil {
+				sharedInFlight[filepath.Join(dir, strings.TrimSuffix(name, ".gz.tmp"))] = true
+			}
+			continue
+		}
+		if !w.isRotated(name, "") && !w.isRotated(name, ".gz") {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		key := strings.TrimSuffix(path, ".gz")
+		b := byName[key]
+		if b == nil {
+			b = &backup{compressing: w.inFlight[key]}
+			byName[key] = b
+			ret = append(ret, b)
+		}
+		b.paths = append(b.paths, path)
+		b.size += fi.Size()
+		if fi.ModTime().After(b.modTime) {
+			b.modTime = fi.ModTime()
+		}
+	}
+	for key, b := range byName {
+		if sharedInFlight[key] {
+			b.compressing = true
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].modTime.After(ret[j].modTime)
+	})
+	return ret, nil
+}
+
+// remove removes the files of b, returning true if they're all gone.
+func (w *FileWriter) remove(b *backup) bool {
+	ok := true
+	for _, path := range b.paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(w.o.Diagnostics, "logger: can't remove old rotated file: %s\n", err)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// prune removes the rotated files selected by MaxAge and MaxBackups, other
+// than those being compressed, and updates rotatedSize. w.mu must be held.
+func (w *FileWriter) prune() {
+	if w.o.MaxAge <= 0 && w.o.MaxBackups <= 0 && w.o.MaxTotalSize <= 0 {
+		return
+	}
+	backups, err := w.backups()
+	if err != nil {
+		fmt.Fprintf(w.o.Diagnostics, "logger: can't remove old rotated files: %s\n", err)
+		return
+	}
+	now := w.o.Now()
+	w.rotatedSize = 0
+	for i, b := range backups {
+		if !b.compressing && (w.o.MaxBackups > 0 && i >= w.o.MaxBackups || w.o.MaxAge > 0 && now.Sub(b.modTime) > w.o.MaxAge) && w.remove(b) {
+			continue
+		}
+		w.rotatedSize += b.size
+	}
+	if w.o.MaxTotalSize > 0 && w.size+w.rotatedSize > w.o.MaxTotalSize {
+		w.enforceQuota(0)
+	}
+}
+
+// enforceQuota removes the oldest rotated files, other than those being
+// compressed, until n more bytes can be written without exceeding
+// MaxTotalSize, and then truncates the file if that's still not enough.
+// w.mu must be held.
+func (w *FileWriter) enforceQuota(n int64) error {
+	if n > w.o.MaxTotalSize {
+		return fmt.Errorf("logger: write of %d bytes exceeds MaxTotalSize", n)
+	}
+	backups, err := w.backups()
+	if err != nil {
+		return err
+	}
+	w.rotatedSize = 0
+	for _, b := range backups {
+		w.rotatedSize += b.size
+	}
+	for i := len(backups) - 1; i >= 0 && w.size+w.rotatedSize+n > w.o.MaxTotalSize; i-- {
+		if b := backups[i]; !b.compressing && w.remove(b) {
+			w.rotatedSize -= b.size
+		}
+	}
+	if w.size+w.rotatedSize+n <= w.o.MaxTotalSize {
+		return nil
+	}
+	if err := w.f.Truncate(0); err != nil {
+		return err
+	}
+	w.size = 0
+	return nil
+}
+
+// compressFile gzips the file at path to path.gz, removing path once the
+// compressed file is complete. On failure path is left as it is. If shared
+// is true the ".gz.tmp" file is locked while it's written, and the file
+// left alone if another process already holds the lock.
+func compressFile(path string, shared bool) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp := path + ".gz.tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if shared {
+		// The lock is released when out is closed.
+		if ok, err := tryLockFile(out); !ok {
+			out.Close()
+			return err
+		}
+	}
+	if err := out.Truncate(0); err != nil {
+		out.Close()
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if err == nil {
+		err = zw.Close()
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+".gz")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Remove(path)
+}
+
+// compressionInProgress returns true if another process holds the lock on
+// the ".gz.tmp" file at tmp, as it's compressing the rotated file.
+func compressionInProgress(tmp string) bool {
+	f, err := os.Open(tmp)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	ok, err := tryLockFile(f)
+	return !ok && err == nil
+}
+
+// followShared switches to the file at path if it's no longer the one
+// being written, because another process sharing it has rotated it, and
+// updates size with the writes of the others. w.mu and w.lock must be
+// held.
+func (w *FileWriter) followShared() error {
+	fi, err := w.f.Stat()
+	if err != nil {
+		return err
+	}
+	if pathFi, err := os.Stat(w.path); err == nil && os.SameFile(fi, pathFi) {
+		w.size = fi.Size()
+		return nil
+	}
+	old := w.f
+	if err := w.open(); err != nil {
+		return err
+	}
+	old.Close()
+	// The file was rotated because the period it covered was over, so the
+	// new one covers the current period.
+	if w.o.RotateEvery > 0 {
+		w.start, w.next = w.period(w.o.Now())
+	}
+	w.prune()
+	return nil
+}
+
+// closeLock closes w.lock, if any.
+func (w *FileWriter) closeLock() error {
+	if w.lock == nil {
+		return nil
+	}
+	return w.lock.Close()
+}
+
+// Write implements SyncWriter, rotating the file first if its period is
+// over.
+func (w *FileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.lock != nil {
+		if err := lockFile(w.lock); err != nil {
+			return 0, err
+		}
+		defer unlockFile(w.lock)
+		if err := w.followShared(); err != nil {
+			return 0, err
+		}
+	}
+	if !w.next.IsZero() {
+		if now := w.o.Now(); !now.Before(w.next) {
+			if err := w.rotate(now); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if w.o.MaxTotalSize > 0 && w.size+w.rotatedSize+int64(len(p)) > w.o.MaxTotalSize {
+		if err := w.enforceQuota(int64(len(p))); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Reopen opens the file at path again and switches to it, closing the one
+// being written, e.g. after logrotate has renamed it. It waits for any
+// write in progress, so no entry is split across the files. If the file
+// can't be opened the current one is kept.
+func (w *FileWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	old := w.f
+	if err := w.open(); err != nil {
+		return err
+	}
+	return old.Close()
+}
+
+// ReopenOnSignal calls Reopen each time the process receives one of sigs,
+// or SIGHUP if none are given, as sent by logrotate's postrotate scripts,
+// until stop is called. Failures are reported to FileOptions.Diagnostics.
+//
+//	w, err := logger.NewFileWriter("/var/log/app.log")
+//	...
+//	defer w.ReopenOnSignal()()
+func (w *FileWriter) ReopenOnSignal(sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ch:
+				if err := w.Reopen(); err != nil {
+					fmt.Fprintf(w.o.Diagnostics, "logger: failed to reopen %s: %s\n", w.path, err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+			<-stopped
+		})
+	}
+}
+
+// Sync implements SyncWriter.
+func (w *FileWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.f.Sync()
+}
+
+// Close closes the file, after waiting for any rotated files to be
+// compressed.
+func (w *FileWriter) Close() error {
+	w.compressing.Wait()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.f.Close()
+	if lockErr := w.closeLock(); err == nil {
+		err = lockErr
+	}
+	return err
+}
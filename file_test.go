@@ -0,0 +1,543 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestFileWriterPeriod(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 3, 10, hour, minute, 0, 0, time.UTC)
+	}
+	for _, test := range []struct {
+		every, offset time.Duration
+		t             time.Time
+		start, next   time.Time
+	}{
+		{24 * time.Hour, 0, at(13, 5), at(0, 0), at(24, 0)},
+		{time.Hour, 0, at(13, 5), at(13, 0), at(14, 0)},
+		{10 * time.Hour, 0, at(21, 0), at(20, 0), at(24, 0)},
+		{24 * time.Hour, 2 * time.Hour, at(1, 0), at(-22, 0), at(2, 0)},
+		{24 * time.Hour, 2 * time.Hour, at(3, 0), at(2, 0), at(26, 0)},
+		{15 * time.Minute, 0, at(13, 50), at(13, 45), at(14, 0)},
+	} {
+		w := &FileWriter{o: FileOptions{RotateEvery: test.every, RotateOffset: test.offset, Location: time.UTC}}
+		start, next := w.period(test.t)
+		if !start.Equal(test.start) || !next.Equal(test.next) {
+			t.Errorf("every %s offset %s at %s: got %s to %s, want %s to %s", test.every, test.offset, test.t, start, next, test.start, test.next)
+		}
+	}
+}
+
+func TestFileWriterRotate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	now := time.Date(2024, 3, 10, 23, 59, 0, 0, time.UTC)
+	w, err := NewFileWriterFromOptions(path, &FileOptions{
+		RotateEvery: 24 * time.Hour,
+		Location:    time.UTC,
+		Now:         func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("sunday\n"))
+	now = now.Add(2 * time.Minute)
+	w.Write([]byte("monday\n"))
+	w.Close()
+
+	for name, want := range map[string]string{
+		"app.2024-03-10.log": "sunday\n",
+		"app.log":            "monday\n",
+	} {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Errorf("%s: got %q, want %q", name, b, want)
+		}
+	}
+}
+
+func TestFileWriterRotatesOldFileOnStart(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	// A file from the previous run, for the same period, is kept.
+	if err := os.WriteFile(filepath.Join(dir, "app.2024-03-09.log"), []byte("older\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewFileWriterFromOptions(path, &FileOptions{
+		RotateEvery: 24 * time.Hour,
+		Location:    time.UTC,
+		Now:         func() time.Time { return time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("new\n"))
+	w.Close()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	want := []string{"app.2024-03-09.1.log", "app.2024-03-09.log", "app.log"}
+	if len(names) != len(want) {
+		t.Fatalf("got %q, want %q", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("got %q, want %q", names, want)
+		}
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "app.2024-03-09.1.log")); string(b) != "old\n" {
+		t.Errorf("got %q, want the old file", b)
+	}
+}
+
+func TestFileWriterInvalidOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if _, err := NewFileWriterFromOptions(path, &FileOptions{RotateEvery: 48 * time.Hour}); err == nil {
+		t.Error("RotateEvery over a day accepted")
+	}
+	if _, err := NewFileWriterFromOptions(path, &FileOptions{RotateEvery: time.Hour, RotateOffset: -time.Hour}); err == nil {
+		t.Error("negative RotateOffset accepted")
+	}
+}
+
+// readGzip returns the uncompressed contents of the gzip file at path.
+func readGzip(t *testing.T, path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestFileWriterShared(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+		t.Skip("not supported on " + runtime.GOOS)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	now := time.Date(2024, 3, 10, 23, 59, 0, 0, time.UTC)
+	o := &FileOptions{
+		RotateEvery: 24 * time.Hour,
+		Location:    time.UTC,
+		Now:         func() time.Time { return now },
+		Shared:      true,
+	}
+	// The locks of writers that open the file separately exclude each other
+	// as those of different processes do.
+	a, err := NewFileWriterFromOptions(path, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := NewFileWriterFromOptions(path, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	a.Write([]byte("a sunday\n"))
+	b.Write([]byte("b sunday\n"))
+	now = now.Add(2 * time.Minute)
+	a.Write([]byte("a monday\n"))
+	b.Write([]byte("b monday\n"))
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := "[app.2024-03-10.log app.log app.log.lock]"; fmt.Sprint(names) != want {
+		t.Errorf("got %v, want %s", names, want)
+	}
+	for name, want := range map[string]string{
+		"app.2024-03-10.log": "a sunday\nb sunday\n",
+		"app.log":            "a monday\nb monday\n",
+	} {
+		if got, _ := os.ReadFile(filepath.Join(dir, name)); string(got) != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestFileWriterSharedKeepsCompressionInProgress(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+		t.Skip("not supported on " + runtime.GOOS)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	rotated := filepath.Join(dir, "app.2024-03-09.log")
+	if err := os.WriteFile(rotated, []byte("saturday\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// As being written by another process compressing the rotated file.
+	tmp, err := os.Create(rotated + ".gz.tmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tmp.Close()
+	if ok, err := tryLockFile(tmp); !ok {
+		t.Fatal(err)
+	}
+	w, err := NewFileWriterFromOptions(path, &FileOptions{Compress: true, Shared: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if _, err := os.Stat(tmp.Name()); err != nil {
+		t.Errorf("the file being compressed by another process was removed: %v", err)
+	}
+	if _, err := os.Stat(rotated + ".gz"); !os.IsNotExist(err) {
+		t.Error("the file was compressed again")
+	}
+}
+
+func TestFileWriterCompress(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	now := time.Date(2024, 3, 10, 23, 59, 0, 0, time.UTC)
+	w, err := NewFileWriterFromOptions(path, &FileOptions{
+		RotateEvery: 24 * time.Hour,
+		Location:    time.UTC,
+		Compress:    true,
+		Now:         func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("sunday\n"))
+	now = now.Add(2 * time.Minute)
+	w.Write([]byte("monday\n"))
+	w.Close()
+
+	if got := readGzip(t, filepath.Join(dir, "app.2024-03-10.log.gz")); got != "sunday\n" {
+		t.Errorf("got %q, want the rotated file", got)
+	}
+	for _, name := range []string{"app.2024-03-10.log", "app.2024-03-10.log.gz.tmp"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s wasn't removed", name)
+		}
+	}
+}
+
+func TestFileWriterCompressResumes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	rotated := filepath.Join(dir, "app.2024-03-09.log")
+	// As left by a crash while compressing.
+	if err := os.WriteFile(rotated, []byte("saturday\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(rotated+".gz.tmp", []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewFileWriterFromOptions(path, &FileOptions{RotateEvery: 24 * time.Hour, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	if got := readGzip(t, rotated+".gz"); got != "saturday\n" {
+		t.Errorf("got %q, want the rotated file", got)
+	}
+	if _, err := os.Stat(rotated + ".gz.tmp"); !os.IsNotExist(err) {
+		t.Error("the partial file wasn't removed")
+	}
+}
+
+func TestFileWriterCompressFailureKeepsFile(t *testing.T) {
+	dir := t.TempDir()
+	rotated := filepath.Join(dir, "app.2024-03-09.log")
+	if err := os.WriteFile(rotated, []byte("saturday\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// The compressed file can't be renamed over a directory.
+	if err := os.Mkdir(rotated+".gz", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := compressFile(rotated, false); err == nil {
+		t.Fatal("want an error")
+	}
+	if b, err := os.ReadFile(rotated); err != nil || string(b) != "saturday\n" {
+		t.Errorf("the rotated file was lost: %q, %v", b, err)
+	}
+	if _, err := os.Stat(rotated + ".gz.tmp"); !os.IsNotExist(err) {
+		t.Error("the partial file wasn't removed")
+	}
+}
+
+// writeRotated writes the files named in dir, with modification times that
+// many hours before now.
+func writeRotated(t *testing.T, dir string, now time.Time, hoursAgo map[string]int) {
+	for name, hours := range hoursAgo {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		modTime := now.Add(-time.Duration(hours) * time.Hour)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// dirNames returns the sorted names of the files in dir.
+func dirNames(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestFileWriterMaxBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	now := time.Date(2024, 3, 10, 23, 59, 0, 0, time.UTC)
+	writeRotated(t, dir, now, map[string]int{
+		"app.2024-03-06.log":    96,
+		"app.2024-03-07.log.gz": 72,
+		"app.2024-03-08.log":    48,
+		"app.2024-03-08.log.gz": 47,
+		"app.2024-03-09.log":    24,
+		"other.log":             200,
+	})
+	w, err := NewFileWriterFromOptions(path, &FileOptions{
+		RotateEvery: 24 * time.Hour,
+		Location:    time.UTC,
+		MaxBackups:  2,
+		Now:         func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[app.2024-03-08.log app.2024-03-08.log.gz app.2024-03-09.log app.log other.log]"
+	if got := fmt.Sprint(dirNames(t, dir)); got != want {
+		t.Errorf("after the startup scan got %s, want %s", got, want)
+	}
+
+	w.Write([]byte("sunday\n"))
+	now = now.Add(2 * time.Minute)
+	w.Write([]byte("monday\n"))
+	w.Close()
+	want = "[app.2024-03-09.log app.2024-03-10.log app.log other.log]"
+	if got := fmt.Sprint(dirNames(t, dir)); got != want {
+		t.Errorf("after rotating got %s, want %s", got, want)
+	}
+}
+
+func TestFileWriterMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	writeRotated(t, dir, now, map[string]int{
+		"app.2024-03-01.log.gz": 220,
+		"app.2024-03-08.log":    50,
+		"app.2024-03-09.log":    20,
+	})
+	w, err := NewFileWriterFromOptions(path, &FileOptions{
+		MaxAge: 48 * time.Hour,
+		Now:    func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	want := "[app.2024-03-09.log app.log]"
+	if got := fmt.Sprint(dirNames(t, dir)); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestFileWriterKeepsUnrelatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	writeRotated(t, dir, now, map[string]int{
+		"app.error.log":           48,
+		"app.2024-03-08.log":      48,
+		"app.2024-03-08.2.log":    48,
+		"app.2024-03-08.x.log":    48,
+		"app.2024-03-08T10.log":   48,
+		"app.2024-03-08.log.gz":   48,
+		"app.2024-03-08.log.bak":  48,
+		"app.backup.2024.log":     48,
+		"other.2024-03-08.log":    48,
+		"app.2024-03-08.log.lock": 48,
+	})
+	w, err := NewFileWriterFromOptions(filepath.Join(dir, "app.log"), &FileOptions{
+		MaxAge: time.Hour,
+		Now:    func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	want := "[app.2024-03-08.log.bak app.2024-03-08.log.lock app.2024-03-08.x.log app.backup.2024.log app.error.log app.log other.2024-03-08.log]"
+	if got := fmt.Sprint(dirNames(t, dir)); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	// Without an extension the lock file of Shared shares the base name.
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+		return
+	}
+	dir = t.TempDir()
+	path := filepath.Join(dir, "app")
+	w, err = NewFileWriterFromOptions(path, &FileOptions{Shared: true, MaxAge: time.Nanosecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if got := fmt.Sprint(dirNames(t, dir)); got != "[app app.lock]" {
+		t.Errorf("got %s, want the lock file kept", got)
+	}
+}
+
+func TestFileWriterMaxTotalSize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	// 18 bytes each.
+	writeRotated(t, dir, now, map[string]int{
+		"app.2024-03-07.log": 72,
+		"app.2024-03-08.log": 48,
+		"app.2024-03-09.log": 24,
+	})
+	w, err := NewFileWriterFromOptions(path, &FileOptions{
+		MaxTotalSize: 50,
+		Now:          func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	want := "[app.2024-03-08.log app.2024-03-09.log app.log]"
+	if got := fmt.Sprint(dirNames(t, dir)); got != want {
+		t.Errorf("after the startup scan got %s, want %s", got, want)
+	}
+
+	w.Write([]byte("0123456789012345678\n"))
+	want = "[app.2024-03-09.log app.log]"
+	if got := fmt.Sprint(dirNames(t, dir)); got != want {
+		t.Errorf("after writing got %s, want %s", got, want)
+	}
+
+	// With no rotated files left the file is truncated.
+	w.Write([]byte("0123456789012345678901234567890\n"))
+	if b, _ := os.ReadFile(path); string(b) != "0123456789012345678901234567890\n" {
+		t.Errorf("got %q, want only the last write", b)
+	}
+	if got := fmt.Sprint(dirNames(t, dir)); got != "[app.log]" {
+		t.Errorf("got %s, want only the file", got)
+	}
+
+	if _, err := w.Write(make([]byte, 51)); err == nil {
+		t.Error("want an error for a write larger than MaxTotalSize")
+	}
+}
+
+func TestFileWriterReopenOnSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no SIGHUP on windows")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	w, err := NewFileWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	stop := w.ReopenOnSignal()
+	defer stop()
+
+	w.Write([]byte("before\n"))
+	// As logrotate does.
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Signal(syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; ; i++ {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if i == 100 {
+			t.Fatal("not reopened")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	w.Write([]byte("after\n"))
+	for name, want := range map[string]string{"app.log.1": "before\n", "app.log": "after\n"} {
+		if b, _ := os.ReadFile(filepath.Join(dir, name)); string(b) != want {
+			t.Errorf("%s: got %q, want %q", name, b, want)
+		}
+	}
+}
+
+func TestFileWriterReopenFailureKeepsFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	w, err := NewFileWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	os.Remove(path)
+	// The file can't be opened once a directory is in its place.
+	if err := os.Mkdir(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Reopen(); err == nil {
+		t.Fatal("want an error")
+	}
+	if _, err := w.Write([]byte("still written\n")); err != nil {
+		t.Errorf("the current file wasn't kept: %s", err)
+	}
+}
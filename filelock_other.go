@@ -0,0 +1,26 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+
+package logger
+
+import (
+	"errors"
+	"os"
+	"runtime"
+)
+
+var errLockUnsupported = errors.New("logger: FileOptions.Shared isn't supported on " + runtime.GOOS)
+
+// lockFile returns an error, as advisory locks aren't supported.
+func lockFile(f *os.File) error {
+	return errLockUnsupported
+}
+
+// tryLockFile returns an error, as advisory locks aren't supported.
+func tryLockFile(f *os.File) (bool, error) {
+	return false, errLockUnsupported
+}
+
+// unlockFile returns an error, as advisory locks aren't supported.
+func unlockFile(f *os.File) error {
+	return errLockUnsupported
+}
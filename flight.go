@@ -0,0 +1,101 @@
+package logger
+
+import "sync"
+
+// flightRecorder keeps the most recent entries logged, whether or not they
+// were written, see Options.FlightRecorder.
+type flightRecorder struct {
+	// mem is the state of the Logger whose Options.MaxMemory the entries
+	// are counted against.
+	mem *loggerState
+
+	mu sync.Mutex
+
+	// entries is a ring of at most size entries, the oldest at next once
+	// it's full.
+	entries []backlogEntry
+	size    int
+	next    int
+}
+
+// newFlightRecorder returns a flightRecorder keeping size entries, counted
+// against the Options.MaxMemory of mem, or nil if size isn't greater than
+// zero.
+func newFlightRecorder(size int, mem *loggerState) *flightRecorder {
+	if size <= 0 {
+		return nil
+	}
+	return &flightRecorder{mem: mem, size: size}
+}
+
+// add keeps e, discarding the oldest entry if there are already size, or
+// the oldest entries until there's room for it within Options.MaxMemory.
+func (f *flightRecorder) add(e backlogEntry) {
+	// Mark the header so that writing the entry doesn't keep it again, or
+	// write stack traces for a Fatal entry.
+	e.header.flight = true
+	e.header.record = true
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for !f.mem.reserveMemory(e.size()) {
+		if len(f.entries) == 0 {
+			f.mem.dropMemory()
+			return
+		}
+		entries := f.ordered()
+		f.mem.releaseMemory(entries[0].size())
+		f.mem.dropMemory()
+		f.entries, f.next = entries[1:], 0
+	}
+	if len(f.entries) < f.size {
+		f.entries = append(f.entries, e)
+		return
+	}
+	f.mem.releaseMemory(f.entries[f.next].size())
+	f.entries[f.next] = e
+	f.next = (f.next + 1) % f.size
+}
+
+// take removes and returns the entries kept, oldest first.
+func (f *flightRecorder) take() []backlogEntry {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entries := f.ordered()
+	for _, e := range entries {
+		f.mem.releaseMemory(e.size())
+	}
+	f.entries, f.next = nil, 0
+	return entries
+}
+
+// snapshot returns the entries kept, oldest first, still keeping them.
+func (f *flightRecorder) snapshot() []backlogEntry {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.ordered()
+}
+
+// ordered returns a copy of the entries, oldest first, and must be called
+// with mu held.
+func (f *flightRecorder) ordered() []backlogEntry {
+	return append(append([]backlogEntry(nil), f.entries[f.next:]...), f.entries[:f.next]...)
+}
+
+// writeFlight writes entries kept by the flight recorder, such as ahead of
+// a Fatal entry, with a "flight_recorder" field so they can be told apart
+// from those written when logged. Fatal entries among them, which were
+// logged with Options.Exit set, are written without stack traces.
+func (l *Logger) writeFlight(entries []backlogEntry) {
+	for _, e := range entries {
+		// Entries may be written by more than one dump at once, so they
+		// aren't modified.
+		fields := e.fields[:len(e.fields):len(e.fields)]
+		if len(e.l.fields) > 0 {
+			fields = append(e.l.fields[:len(e.l.fields):len(e.l.fields)], fields...)
+		}
+		buf := l.getBuffer()
+		buf.Write(e.msg)
+		e.l.emitFields(e.s, buf, e.header, append(fields, Bool("flight_recorder", true)))
+		l.putBuffer(buf)
+	}
+}
@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+// flightLines returns the lines of s written by the flight recorder.
+func flightLines(s string) []string {
+	var ret []string
+	for _, line := range strings.Split(s, "\n") {
+		if strings.HasSuffix(line, " flight_recorder=true") {
+			ret = append(ret, line)
+		}
+	}
+	return ret
+}
+
+func TestFlightRecorder(t *testing.T) {
+	b := &flushBuffer{}
+	l := NewFromOptions(&Options{SyncWriter: b, FlightRecorder: 3, MinSeverity: WarningSeverity, Exit: func(int) {}})
+	l.Warning("discarded")
+	l.Debug("debug")
+	l.With(Str("k", "v")).Info("info")
+	l.Error("error")
+	if got := b.String(); strings.Contains(got, "debug") || strings.Contains(got, "info") || strings.Contains(got, "flight_recorder") {
+		t.Fatalf("Filtered entries written before a Fatal: %q", got)
+	}
+
+	l.Fatal("crashed")
+	got := b.String()
+	lines := flightLines(got)
+	if len(lines) != 3 {
+		t.Fatalf("Got %q, want 3 entries from the flight recorder", lines)
+	}
+	for i, want := range []string{"] debug flight_recorder=true", "] info k=v flight_recorder=true", "] error flight_recorder=true"} {
+		if !strings.HasSuffix(lines[i], want) {
+			t.Errorf("Entry %d is %q, want the suffix %q", i, lines[i], want)
+		}
+	}
+	if lines[0][0] != 'D' || lines[1][0] != 'I' || lines[2][0] != 'E' {
+		t.Errorf("Wrong severities: %q", lines)
+	}
+	if i, j := strings.Index(got, "] error flight_recorder=true"), strings.Index(got, "] crashed\n"); i > j {
+		t.Errorf("Flight recorder written after the Fatal entry: %q", got)
+	}
+
+	// The recorder was emptied, and the first Fatal entry is written without
+	// its stack traces.
+	b.Reset()
+	l.Fatal("again")
+	if lines := flightLines(b.String()); len(lines) != 1 || !strings.HasSuffix(lines[0], "] crashed flight_recorder=true") {
+		t.Errorf("Got %q, want only the first Fatal entry", lines)
+	}
+	if n := strings.Count(b.String(), "goroutine "); n == 0 || strings.Count(b.String(), "] crashed") != 1 {
+		t.Errorf("Wrong stack traces: %q", b.String())
+	}
+}
+
+func TestFlightRecorderWithBacklog(t *testing.T) {
+	b := &flushBuffer{}
+	l := NewFromOptions(&Options{SyncWriter: b, FlightRecorder: 10, DebugBacklog: 10, Exit: func(int) {}})
+	l.Debug("debug")
+	l.Error("error")
+	l.Fatal("crashed")
+	lines := flightLines(b.String())
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "] debug flight_recorder=true") || !strings.HasSuffix(lines[1], "] error flight_recorder=true") {
+		t.Errorf("Backlog entries should be kept once: %q", lines)
+	}
+}
+
+func TestFlightRecorderRing(t *testing.T) {
+	f := newFlightRecorder(2, &loggerState{})
+	for i := 0; i < 5; i++ {
+		f.add(backlogEntry{header: &buffer{}, msg: []byte{byte('a' + i)}})
+	}
+	entries := f.take()
+	if len(entries) != 2 || string(entries[0].msg) != "d" || string(entries[1].msg) != "e" {
+		t.Errorf("Wrong entries: %v", entries)
+	}
+	if entries := f.take(); len(entries) != 0 {
+		t.Errorf("Entries not removed: %v", entries)
+	}
+	if newFlightRecorder(0, &loggerState{}) != nil {
+		t.Error("Flight recorder created for a size of 0")
+	}
+}
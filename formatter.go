@@ -0,0 +1,150 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Formatter renders log entries, for full control over the layout of the
+// lines written. Set Options.Formatter to use one.
+type Formatter interface {
+	// Format writes entry to buf, ending with a newline, as one or more
+	// lines which are written to the destination in a single write.
+	Format(entry Entry, buf *bytes.Buffer)
+}
+
+// GlogFormatter is the Formatter for the default glog style layout: every
+// line of the message is written with its own header and followed by the
+// fields as key=value pairs. A Logger without a Formatter produces the same
+// output without going through an Entry, which is faster.
+//
+// It's useful as the basis of a custom Formatter that only changes part of
+// the layout.
+type GlogFormatter struct {
+	// TimePrecision is the precision of the timestamp in the header.
+	TimePrecision TimePrecision
+}
+
+// Format implements Formatter.
+func (g GlogFormatter) Format(entry Entry, buf *bytes.Buffer) {
+	s := infoLog
+	if sev, err := ParseSeverity(entry.Severity); err == nil {
+		s = severity(sev)
+	}
+	header := formatGlogHeader(&buffer{}, s, entry.Time, g.TimePrecision, false, entry.PID, entry.File, entry.Line)
+	suffix := &buffer{}
+	for _, f := range entry.Fields {
+		f.appendTo(suffix)
+	}
+	written := false
+	for _, line := range strings.Split(entry.Message, "\n") {
+		// Don't emit blank lines.
+		if line == "" {
+			continue
+		}
+		buf.Write(header.Bytes())
+		buf.WriteString(line)
+		buf.Write(suffix.Bytes())
+		buf.WriteByte('\n')
+		written = true
+	}
+	if !written && len(entry.Fields) > 0 {
+		// An entry with fields but no message is written as a line of just
+		// the fields.
+		buf.Write(header.Bytes())
+		buf.Write(bytes.TrimPrefix(suffix.Bytes(), []byte(" ")))
+		buf.WriteByte('\n')
+	}
+}
+
+var (
+	// encoders are the Formatter factories registered by name, see
+	// RegisterEncoder.
+	encoders = map[string]func() Formatter{
+		"glog": func() Formatter { return GlogFormatter{} },
+	}
+
+	// encodersMu protects encoders.
+	encodersMu sync.RWMutex
+)
+
+// RegisterEncoder makes the Formatter returned by factory available as name
+// to NewEncoder, so it can be selected by a string in a config file or an
+// environment variable. It's intended to be called from an init function,
+// and panics if factory is nil or name is already registered. "glog" is
+// registered as GlogFormatter.
+func RegisterEncoder(name string, factory func() Formatter) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	if factory == nil {
+		panic("logger: RegisterEncoder factory is nil")
+	}
+	if _, ok := encoders[name]; ok {
+		panic("logger: RegisterEncoder called twice for " + name)
+	}
+	encoders[name] = factory
+}
+
+// NewEncoder returns a new Formatter from the factory registered as name,
+// for Options.Formatter.
+func NewEncoder(name string) (Formatter, error) {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	factory, ok := encoders[name]
+	if !ok {
+		names := make([]string, 0, len(encoders))
+		for n := range encoders {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown encoder %q, registered encoders are %q", name, names)
+	}
+	return factory(), nil
+}
+
+// emitFormatted writes out the message in buf along with any fields using
+// l.formatter, followed by a second entry holding the stack traces if s is
+// fatalLog.
+func (l *Logger) emitFormatted(s severity, buf, header *buffer, fields []Field) {
+	all := make([]Field, 0, len(l.stampFields)+len(fields)+1)
+	all = append(all, l.stampFields...)
+	all = append(all, fields...)
+	if l.messageHash {
+		all = append(all, Str("msg_hash", messageHash(buf.Bytes())))
+	}
+	entry := Entry{
+		Severity: s.name(),
+		Time:     header.time,
+		PID:      header.pid,
+		File:     header.file,
+		Line:     header.line,
+		Message:  buf.String(),
+		Fields:   all,
+	}
+	e := l.entryInfo(s, header, fields)
+	l.writeFormatted(e, entry)
+	if s == fatalLog && !header.record {
+		l.stacks(func(trace []byte) {
+			entry.Message = string(trace)
+		})
+		entry.Fields = l.stampFields
+		l.writeFormatted(e, entry)
+	}
+}
+
+// writeFormatted formats and writes entry, which is described by e.
+func (l *Logger) writeFormatted(e entryInfo, entry Entry) {
+	out := l.getBuffer()
+	defer l.putBuffer(out)
+	l.formatter.Format(entry, &out.Buffer)
+	if out.Len() == 0 {
+		return
+	}
+	l.write(e, out.Bytes())
+	atomic.AddUint64(&l.linesWritten, 1)
+	l.tap(e, out.Bytes())
+}
@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test that GlogFormatter produces the same output as a Logger without a
+// Formatter.
+func TestGlogFormatterMatchesDefault(t *testing.T) {
+	now := func() time.Time { return time.Date(2006, 1, 2, 15, 4, 5, 67890123, time.Local) }
+	metadata := &InstanceMetadata{Provider: "gce", InstanceID: "1", Zone: "z"}
+	logBoth := func(l *Logger) string {
+		l.WarningFields("first\nsecond", Str("k", "v v"), Group("g", Int("n", 1)))
+		l.ErrorFields("", Err(errors.New("it broke")))
+		l.Error("")
+		return l.w.(*flushBuffer).String()
+	}
+	want := logBoth(NewFromOptions(&Options{SyncWriter: &flushBuffer{}, Now: now, PID: 1234, InstanceMetadata: metadata, MessageHash: true}))
+	got := logBoth(NewFromOptions(&Options{SyncWriter: &flushBuffer{}, Now: now, PID: 1234, InstanceMetadata: metadata, MessageHash: true, Formatter: GlogFormatter{}}))
+	if got != want {
+		t.Errorf("Got %q want %q", got, want)
+	}
+}
+
+// upperFormatter writes the severity and the message in upper case.
+type upperFormatter struct{}
+
+func (upperFormatter) Format(entry Entry, buf *bytes.Buffer) {
+	buf.WriteString(entry.Severity + " " + strings.ToUpper(entry.Message))
+	for _, f := range entry.Fields {
+		buf.WriteString(" " + f.Key)
+	}
+	buf.WriteString("\n")
+}
+
+func TestFormatter(t *testing.T) {
+	exited := false
+	l := NewFromOptions(&Options{SyncWriter: &flushBuffer{}, Formatter: upperFormatter{}, Exit: func(int) { exited = true }})
+	l.InfoFields("hello", Int("a", 1), Int("b", 2))
+	if got, want := l.w.(*flushBuffer).String(), "INFO HELLO a b\n"; got != want {
+		t.Errorf("Got %q want %q", got, want)
+	}
+	l.Fatal("goodbye")
+	if got := l.w.(*flushBuffer).String(); !strings.Contains(got, "FATAL GOODBYE\nFATAL GOROUTINE ") || !exited {
+		t.Errorf("Wrong fatal output: %q", got)
+	}
+}
+
+// Test that the Entry passed to a Formatter reports the caller.
+func TestFormatterEntry(t *testing.T) {
+	var entries []Entry
+	l := NewFromOptions(&Options{SyncWriter: &flushBuffer{}, Formatter: formatterFunc(func(e Entry, buf *bytes.Buffer) {
+		entries = append(entries, e)
+	})})
+	l.Error("test")
+	if len(entries) != 1 || entries[0].File != "formatter_test.go" || entries[0].Line == 0 || entries[0].Severity != "ERROR" || entries[0].Time.IsZero() {
+		t.Errorf("Wrong entry: %#v", entries)
+	}
+	if got := l.w.(*flushBuffer).String(); got != "" {
+		t.Errorf("Nothing should be written for empty output: %q", got)
+	}
+}
+
+type formatterFunc func(Entry, *bytes.Buffer)
+
+func (f formatterFunc) Format(e Entry, buf *bytes.Buffer) { f(e, buf) }
+
+func TestRegisterEncoder(t *testing.T) {
+	RegisterEncoder("test-upper", func() Formatter { return upperFormatter{} })
+	defer func() {
+		encodersMu.Lock()
+		delete(encoders, "test-upper")
+		encodersMu.Unlock()
+	}()
+
+	f, err := NewEncoder("test-upper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.(upperFormatter); !ok {
+		t.Errorf("Wrong Formatter: %T", f)
+	}
+	if f, err := NewEncoder("glog"); err != nil || f != (GlogFormatter{}) {
+		t.Errorf("Wrong glog encoder: %v %v", f, err)
+	}
+	if _, err := NewEncoder("missing"); err == nil || !strings.Contains(err.Error(), `"test-upper"`) {
+		t.Errorf("Wrong error: %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for a duplicate name")
+		}
+	}()
+	RegisterEncoder("test-upper", func() Formatter { return upperFormatter{} })
+}
@@ -0,0 +1,628 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// maxForwardLineLength is the longest line a ForwardServer accepts.
+	maxForwardLineLength = 1024 * 1024
+
+	// forwardDialTimeout is how long a ForwardWriter waits to connect.
+	forwardDialTimeout = 5 * time.Second
+
+	// minReconnectBackoff and maxReconnectBackoff bound how long a writer
+	// that failed to connect waits before trying again, see
+	// reconnectBackoff.
+	minReconnectBackoff = 100 * time.Millisecond
+	maxReconnectBackoff = 10 * time.Second
+
+	// forwardSyncTimeout is how long ForwardWriter.Sync waits for acks.
+	forwardSyncTimeout = 5 * time.Second
+
+	// maxForwardPending is the number of unacknowledged frames a
+	// ForwardWriter keeps for resending, beyond which the oldest are dropped.
+	maxForwardPending = 10000
+
+	// maxForwardTokenLength is the longest token accepted in a hello.
+	maxForwardTokenLength = 4096
+)
+
+// Credentials configures the security of network sinks and servers.
+type Credentials struct {
+	// TLSConfig, if not nil, is used to make TLS connections.
+	//
+	// For mutual TLS, clients should set Certificates and RootCAs, and
+	// servers should set Certificates, ClientCAs, and ClientAuth to
+	// tls.RequireAndVerifyClientCert.
+	TLSConfig *tls.Config
+
+	// Token, if not empty, is a shared secret clients must present. Over HTTP
+	// it is sent as an "Authorization: Bearer" header. Without TLSConfig it
+	// is sent in the clear.
+	Token string
+}
+
+// tlsConfig returns the TLS config, allowing for a nil *Credentials.
+func (c *Credentials) tlsConfig() *tls.Config {
+	if c == nil {
+		return nil
+	}
+	return c.TLSConfig
+}
+
+// token returns the token, allowing for a nil *Credentials.
+func (c *Credentials) token() string {
+	if c == nil {
+		return ""
+	}
+	return c.Token
+}
+
+// validToken returns true if got matches the configured token.
+func (c *Credentials) validToken(got string) bool {
+	return subtle.ConstantTimeCompare([]byte(got), []byte(c.token())) == 1
+}
+
+// forwardMagic starts every connection from a ForwardWriter and identifies
+// the protocol version.
+var forwardMagic = [4]byte{'L', 'G', 'F', '1'}
+
+/*
+The TCP protocol between a ForwardWriter and a ForwardServer is framed so
+that the writer can tell what has been received and resend what hasn't.
+
+A connection starts with a hello from the writer:
+
+	magic     4 bytes, "LGF1"
+	clientid  8 bytes, random, identifies the writer across reconnects
+	acked     8 bytes, the highest seq the writer has had acked
+	tokenlen  2 bytes, the length of the token
+	token     tokenlen bytes, see Credentials.Token
+
+Followed by any number of frames, one per Write:
+
+	seq       8 bytes, starting at 1 and incremented for each frame
+	length    4 bytes, the length of the payload
+	payload   length bytes
+
+The server responds to each frame with an ack:
+
+	seq       8 bytes, the highest seq written to the sinks for this clientid
+
+All integers are big endian. Frames with a seq the server has already
+written, or that are covered by the acked value in the hello, are acked but
+not written again, so resends after a reconnect don't duplicate lines. Gaps
+in seq, from the writer dropping frames, are reported to the sinks.
+*/
+
+// ForwardServer accepts log lines from many processes, over TCP or HTTP, and
+// writes them to a single set of sinks. This allows centralizing the logs of
+// every process on a machine without running a separate collector.
+//
+// Over TCP the framed protocol spoken by ForwardWriter is used. Over HTTP
+// each POST body is treated as a sequence of lines.
+type ForwardServer struct {
+	sinks []SyncWriter
+	creds *Credentials
+
+	// mu serializes writes to the sinks so lines from different clients are
+	// never interleaved, and protects lastSeq.
+	mu sync.Mutex
+
+	// lastSeq is the last seq written for each client id.
+	lastSeq map[uint64]uint64
+
+	// listeners and conns track what to close on Close, under connsMu.
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	connsMu   sync.Mutex
+
+	wg sync.WaitGroup
+}
+
+// NewForwardServer returns a ForwardServer that writes to all of sinks.
+func NewForwardServer(sinks ...SyncWriter) *ForwardServer {
+	return NewForwardServerWithCredentials(nil, sinks...)
+}
+
+// NewForwardServerWithCredentials returns a ForwardServer that writes to all
+// of sinks and requires clients to present creds.
+//
+// Serve uses creds.TLSConfig to accept TLS connections, but ServeHTTP only
+// checks the token, TLS for HTTP is configured on the http.Server.
+func NewForwardServerWithCredentials(creds *Credentials, sinks ...SyncWriter) *ForwardServer {
+	return &ForwardServer{
+		sinks:     sinks,
+		creds:     creds,
+		lastSeq:   map[uint64]uint64{},
+		listeners: map[net.Listener]struct{}{},
+		conns:     map[net.Conn]struct{}{},
+	}
+}
+
+// Serve accepts connections on ln until ln is closed or Close is called. It
+// always returns a non-nil error.
+func (s *ForwardServer) Serve(ln net.Listener) error {
+	if cfg := s.creds.tlsConfig(); cfg != nil {
+		ln = tls.NewListener(ln, cfg)
+	}
+	s.connsMu.Lock()
+	s.listeners[ln] = struct{}{}
+	s.connsMu.Unlock()
+	defer func() {
+		s.connsMu.Lock()
+		delete(s.listeners, ln)
+		s.connsMu.Unlock()
+	}()
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		s.connsMu.Lock()
+		s.conns[c] = struct{}{}
+		s.connsMu.Unlock()
+		s.wg.Add(1)
+		go s.handle(c)
+	}
+}
+
+func (s *ForwardServer) handle(c net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.connsMu.Lock()
+		delete(s.conns, c)
+		s.connsMu.Unlock()
+		c.Close()
+	}()
+	r := bufio.NewReader(c)
+	var hello [20]byte
+	if _, err := io.ReadFull(r, hello[:]); err != nil {
+		return
+	}
+	if [4]byte{hello[0], hello[1], hello[2], hello[3]} != forwardMagic {
+		return
+	}
+	clientID := binary.BigEndian.Uint64(hello[4:12])
+	var tokenLength [2]byte
+	if _, err := io.ReadFull(r, tokenLength[:]); err != nil {
+		return
+	}
+	n := binary.BigEndian.Uint16(tokenLength[:])
+	if n > maxForwardTokenLength {
+		return
+	}
+	token := make([]byte, n)
+	if _, err := io.ReadFull(r, token); err != nil {
+		return
+	}
+	if !s.creds.validToken(string(token)) {
+		return
+	}
+	s.mu.Lock()
+	if acked := binary.BigEndian.Uint64(hello[12:]); acked > s.lastSeq[clientID] {
+		// Acked by a previous instance of the server.
+		s.lastSeq[clientID] = acked
+	}
+	s.mu.Unlock()
+
+	var frameHeader [12]byte
+	var ack [8]byte
+	ackFailed := false
+	for {
+		if _, err := io.ReadFull(r, frameHeader[:]); err != nil {
+			return
+		}
+		seq := binary.BigEndian.Uint64(frameHeader[:8])
+		length := binary.BigEndian.Uint32(frameHeader[8:])
+		if length > maxForwardLineLength {
+			return
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return
+		}
+		binary.BigEndian.PutUint64(ack[:], s.writeFrame(clientID, seq, payload))
+		if ackFailed {
+			continue
+		}
+		// Keep reading even if the writer stops reading acks, e.g. because it
+		// closed the connection, so that everything it sent is written.
+		if _, err := c.Write(ack[:]); err != nil {
+			ackFailed = true
+		}
+	}
+}
+
+// writeFrame writes payload to the sinks unless the frame has already been
+// written, and returns the last seq written for the client.
+func (s *ForwardServer) writeFrame(clientID, seq uint64, payload []byte) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	last := s.lastSeq[clientID]
+	if seq <= last {
+		return last
+	}
+	if seq > last+1 {
+		s.writeLocked([]byte(fmt.Sprintf("forward: lost %d lines from client %016x\n", seq-last-1, clientID)))
+	}
+	s.writeLocked(payload)
+	s.lastSeq[clientID] = seq
+	return seq
+}
+
+// ServeHTTP implements http.Handler, accepting POSTs whose bodies are log lines.
+func (s *ForwardServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Only POST is supported.", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.creds.validToken(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")) {
+		http.Error(w, "Invalid token.", http.StatusUnauthorized)
+		return
+	}
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 64*1024), maxForwardLineLength)
+	for scanner.Scan() {
+		if line := scanner.Bytes(); len(line) > 0 {
+			buf := make([]byte, 0, len(line)+1)
+			buf = append(buf, line...)
+			buf = append(buf, '\n')
+			s.mu.Lock()
+			s.writeLocked(buf)
+			s.mu.Unlock()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		http.Error(w, "Failed to read body.", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeLocked writes p to every sink. s.mu must be held.
+func (s *ForwardServer) writeLocked(p []byte) {
+	for _, sink := range s.sinks {
+		sink.Write(p)
+	}
+}
+
+// Sync syncs all the sinks, returning the first error encountered.
+func (s *ForwardServer) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ret error
+	for _, sink := range s.sinks {
+		if err := sink.Sync(); err != nil && ret == nil {
+			ret = err
+		}
+	}
+	return ret
+}
+
+// Close stops all listeners passed to Serve, closes all open connections,
+// waits for them to finish, and then syncs the sinks.
+func (s *ForwardServer) Close() error {
+	s.connsMu.Lock()
+	for ln := range s.listeners {
+		ln.Close()
+	}
+	for c := range s.conns {
+		c.Close()
+	}
+	s.connsMu.Unlock()
+	s.wg.Wait()
+	return s.Sync()
+}
+
+// reconnectBackoff tracks when a writer that failed to connect may try
+// again, so that while the destination is down writes fail immediately,
+// rather than each waiting up to forwardDialTimeout for a dial while
+// holding up every other log call. The wait doubles after each failure,
+// from minReconnectBackoff up to maxReconnectBackoff. It's protected by the
+// writer's mutex.
+type reconnectBackoff struct {
+	delay time.Duration
+	next  time.Time
+	err   error
+}
+
+// wait returns the error from the last attempt to connect if it's too soon
+// to try again, or nil.
+func (b *reconnectBackoff) wait() error {
+	if time.Now().Before(b.next) {
+		return b.err
+	}
+	return nil
+}
+
+// failed records that an attempt to connect failed with err.
+func (b *reconnectBackoff) failed(err error) {
+	b.delay *= 2
+	if b.delay < minReconnectBackoff {
+		b.delay = minReconnectBackoff
+	}
+	if b.delay > maxReconnectBackoff {
+		b.delay = maxReconnectBackoff
+	}
+	b.next = time.Now().Add(b.delay)
+	b.err = err
+}
+
+// connected records that an attempt to connect succeeded.
+func (b *reconnectBackoff) connected() {
+	*b = reconnectBackoff{}
+}
+
+// forwardFrame is a frame sent by a ForwardWriter that hasn't been acked.
+type forwardFrame struct {
+	seq     uint64
+	payload []byte
+}
+
+// ForwardWriter is a SyncWriter that sends log lines to a ForwardServer
+// over TCP. It connects lazily and reconnects if the connection fails,
+// waiting longer after each failure to connect, during which writes fail
+// without trying to connect.
+//
+// Every Write is kept until the server acknowledges it, and is resent after
+// reconnecting, so lines aren't lost when the server restarts. Only the most
+// recent maxForwardPending unacknowledged writes are kept, see Dropped.
+type ForwardWriter struct {
+	addr  string
+	id    uint64
+	creds *Credentials
+
+	// acked is the highest seq acked by the server, accessed atomically.
+	acked uint64
+
+	// dropped is the number of frames dropped, accessed atomically.
+	dropped uint64
+
+	// mu protects the fields below.
+	mu      sync.Mutex
+	conn    net.Conn
+	backoff reconnectBackoff
+	seq     uint64
+	pending []forwardFrame
+}
+
+// NewForwardWriter returns a ForwardWriter that sends to the ForwardServer
+// listening at addr.
+func NewForwardWriter(addr string) *ForwardWriter {
+	return NewForwardWriterWithCredentials(addr, nil)
+}
+
+// NewForwardWriterWithCredentials returns a ForwardWriter that sends to the
+// ForwardServer listening at addr, presenting creds.
+func NewForwardWriterWithCredentials(addr string, creds *Credentials) *ForwardWriter {
+	var id [8]byte
+	rand.Read(id[:])
+	return &ForwardWriter{
+		addr:  addr,
+		id:    binary.BigEndian.Uint64(id[:]),
+		creds: creds,
+	}
+}
+
+// Write implements SyncWriter. The write is queued for resending even if it
+// returns an error.
+func (f *ForwardWriter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.trimAckedLocked()
+	f.seq++
+	frame := forwardFrame{seq: f.seq, payload: append([]byte(nil), p...)}
+	f.pending = append(f.pending, frame)
+	if len(f.pending) > maxForwardPending {
+		f.pending = f.pending[1:]
+		atomic.AddUint64(&f.dropped, 1)
+	}
+
+	if f.conn != nil {
+		if err := writeForwardFrame(f.conn, frame); err == nil {
+			return len(p), nil
+		}
+		f.conn.Close()
+		f.conn = nil
+	}
+	// Connecting resends everything pending, including this frame.
+	if err := f.connectLocked(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// connectLocked connects to the server and resends all pending frames,
+// unless it's too soon after failing to. f.mu must be held.
+func (f *ForwardWriter) connectLocked() error {
+	if err := f.backoff.wait(); err != nil {
+		return err
+	}
+	if err := f.dialLocked(); err != nil {
+		f.backoff.failed(err)
+		return err
+	}
+	f.backoff.connected()
+	return nil
+}
+
+// dialLocked connects to the server and resends all pending frames. f.mu
+// must be held.
+func (f *ForwardWriter) dialLocked() error {
+	dialer := &net.Dialer{Timeout: forwardDialTimeout}
+	var conn net.Conn
+	var err error
+	if cfg := f.creds.tlsConfig(); cfg != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", f.addr, cfg)
+	} else {
+		conn, err = dialer.Dial("tcp", f.addr)
+	}
+	if err != nil {
+		return err
+	}
+	token := f.creds.token()
+	hello := make([]byte, 22+len(token))
+	copy(hello[:4], forwardMagic[:])
+	binary.BigEndian.PutUint64(hello[4:12], f.id)
+	binary.BigEndian.PutUint64(hello[12:20], atomic.LoadUint64(&f.acked))
+	binary.BigEndian.PutUint16(hello[20:22], uint16(len(token)))
+	copy(hello[22:], token)
+	if _, err := conn.Write(hello); err != nil {
+		conn.Close()
+		return err
+	}
+	for _, frame := range f.pending {
+		if err := writeForwardFrame(conn, frame); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+	f.conn = conn
+	go f.readAcks(conn)
+	return nil
+}
+
+func writeForwardFrame(w io.Writer, frame forwardFrame) error {
+	buf := make([]byte, 12+len(frame.payload))
+	binary.BigEndian.PutUint64(buf[:8], frame.seq)
+	binary.BigEndian.PutUint32(buf[8:12], uint32(len(frame.payload)))
+	copy(buf[12:], frame.payload)
+	_, err := w.Write(buf)
+	return err
+}
+
+// readAcks records the acks from the server until conn fails, at which point
+// conn is closed so the next Write reconnects.
+//
+// It never takes f.mu, since Write may be blocked holding f.mu waiting for
+// the server, which may in turn be blocked waiting for its acks to be read.
+func (f *ForwardWriter) readAcks(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	var ack [8]byte
+	for {
+		if _, err := io.ReadFull(r, ack[:]); err != nil {
+			conn.Close()
+			return
+		}
+		seq := binary.BigEndian.Uint64(ack[:])
+		for {
+			old := atomic.LoadUint64(&f.acked)
+			if seq <= old || atomic.CompareAndSwapUint64(&f.acked, old, seq) {
+				break
+			}
+		}
+	}
+}
+
+// Purge implements Purger, removing the writes that contain subject from
+// those kept until they're acknowledged. These may already have been sent,
+// in which case the server may still write them. Their sequence numbers are
+// kept, so the server doesn't report them as lost.
+func (f *ForwardWriter) Purge(subject string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.trimAckedLocked()
+	n := 0
+	for i := range f.pending {
+		if bytes.Contains(f.pending[i].payload, []byte(subject)) {
+			f.pending[i].payload = nil
+			n++
+		}
+	}
+	return n
+}
+
+// trimAckedLocked removes acked frames from pending. f.mu must be held.
+func (f *ForwardWriter) trimAckedLocked() {
+	acked := atomic.LoadUint64(&f.acked)
+	i := 0
+	for i < len(f.pending) && f.pending[i].seq <= acked {
+		i++
+	}
+	f.pending = f.pending[i:]
+}
+
+// errForwardUnacked is returned from Sync if the server didn't ack everything in time.
+var errForwardUnacked = errors.New("timed out waiting for the forward server to ack")
+
+// Sync implements SyncWriter by waiting for the server to acknowledge every
+// write, reconnecting if needed.
+func (f *ForwardWriter) Sync() error {
+	deadline := time.Now().Add(forwardSyncTimeout)
+	for {
+		f.mu.Lock()
+		f.trimAckedLocked()
+		done := len(f.pending) == 0
+		if !done && f.conn == nil {
+			f.connectLocked()
+		}
+		f.mu.Unlock()
+		if done {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errForwardUnacked
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Healthy implements HealthChecker. The ForwardWriter is unhealthy if it
+// isn't connected to the server while writes are pending, or if so many
+// writes are pending that it is dropping them.
+func (f *ForwardWriter) Healthy() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.trimAckedLocked()
+	if len(f.pending) >= maxForwardPending {
+		return fmt.Errorf("%d writes pending, dropping the oldest", len(f.pending))
+	}
+	if f.conn == nil && len(f.pending) > 0 {
+		return fmt.Errorf("not connected to %s with %d writes pending", f.addr, len(f.pending))
+	}
+	return nil
+}
+
+// QueueDepth implements QueueDepther, returning the number of writes that
+// haven't been acked.
+func (f *ForwardWriter) QueueDepth() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.trimAckedLocked()
+	return len(f.pending)
+}
+
+// Dropped returns the number of writes dropped, without being acked, because
+// too many writes were pending.
+func (f *ForwardWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&f.dropped)
+}
+
+// Close closes the connection to the ForwardServer. Writes that haven't been
+// acked will be lost, call Sync first to avoid that.
+func (f *ForwardWriter) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.conn == nil {
+		return nil
+	}
+	err := f.conn.Close()
+	f.conn = nil
+	return err
+}
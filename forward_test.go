@@ -0,0 +1,416 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a SyncWriter that is safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *syncBuffer) Sync() error {
+	return nil
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.String()
+}
+
+// waitForLines waits until s contains n lines.
+func waitForLines(t *testing.T, s *syncBuffer, n int) []string {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if got := s.String(); strings.Count(got, "\n") >= n {
+			return strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %d lines, got %q", n, s.String())
+	return nil
+}
+
+func TestForwardServerTCP(t *testing.T) {
+	sink := &syncBuffer{}
+	s := NewForwardServer(sink)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(ln)
+	defer s.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := NewForwardWriter(ln.Addr().String())
+			defer w.Close()
+			l := NewFromOptions(&Options{SyncWriter: w})
+			for j := 0; j < 10; j++ {
+				l.Info("forwarded")
+			}
+			if err := w.Sync(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	lines := waitForLines(t, sink, 30)
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "I") || !strings.HasSuffix(line, "] forwarded") {
+			t.Errorf("Lines interleaved or corrupted: %q", line)
+		}
+	}
+}
+
+func TestForwardServerHTTP(t *testing.T) {
+	sink := &syncBuffer{}
+	ts := httptest.NewServer(NewForwardServer(sink))
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL, "text/plain", strings.NewReader("line one\nline two"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Wrong status: %s", resp.Status)
+	}
+	if got, want := sink.String(), "line one\nline two\n"; got != want {
+		t.Errorf("Got %q want %q", got, want)
+	}
+
+	resp, err = http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Wrong status for GET: %s", resp.Status)
+	}
+}
+
+// Test that a ForwardWriter resends unacked writes after the server restarts.
+func TestForwardWriterReconnects(t *testing.T) {
+	sink := &syncBuffer{}
+	s := NewForwardServer(sink)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	go s.Serve(ln)
+
+	w := NewForwardWriter(addr)
+	defer w.Close()
+	if _, err := w.Write([]byte("first\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// Written while the server is down.
+	w.Write([]byte("second\n"))
+	w.Write([]byte("third\n"))
+
+	s = NewForwardServer(sink)
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("Could not reuse address: %s", err)
+	}
+	go s.Serve(ln)
+	defer s.Close()
+
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sink.String(), "first\nsecond\nthird\n"; got != want {
+		t.Errorf("Got %q want %q", got, want)
+	}
+}
+
+// Test that after failing to connect a ForwardWriter doesn't try again
+// until its backoff expires.
+func TestForwardWriterBackoff(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := ln.Addr().String()
+	ln.Close()
+	w := NewForwardWriter(down)
+	defer w.Close()
+	if _, err := w.Write([]byte("first\n")); err == nil {
+		t.Fatal("Write succeeded with nothing listening")
+	}
+
+	sink := &syncBuffer{}
+	s := NewForwardServer(sink)
+	ln, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(ln)
+	defer s.Close()
+	w.mu.Lock()
+	w.addr = ln.Addr().String()
+	w.mu.Unlock()
+	if _, err := w.Write([]byte("second\n")); err == nil {
+		t.Fatal("Write connected before the backoff expired")
+	}
+
+	w.mu.Lock()
+	if w.backoff.delay != minReconnectBackoff {
+		t.Errorf("Wrong backoff: %s", w.backoff.delay)
+	}
+	w.backoff.next = time.Time{}
+	w.mu.Unlock()
+	if _, err := w.Write([]byte("third\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sink.String(), "first\nsecond\nthird\n"; got != want {
+		t.Errorf("Got %q want %q", got, want)
+	}
+	if w.backoff != (reconnectBackoff{}) {
+		t.Errorf("Backoff not reset after connecting: %#v", w.backoff)
+	}
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	var b reconnectBackoff
+	if err := b.wait(); err != nil {
+		t.Fatalf("Want no wait before failing, got %v", err)
+	}
+	dialErr := errors.New("connection refused")
+	want := []time.Duration{minReconnectBackoff, 2 * minReconnectBackoff, 4 * minReconnectBackoff}
+	for _, delay := range want {
+		b.failed(dialErr)
+		if b.delay != delay {
+			t.Errorf("Got a delay of %s want %s", b.delay, delay)
+		}
+	}
+	if err := b.wait(); err != dialErr {
+		t.Errorf("Got %v waiting, want the last error", err)
+	}
+	for i := 0; i < 20; i++ {
+		b.failed(dialErr)
+	}
+	if b.delay != maxReconnectBackoff {
+		t.Errorf("Got a delay of %s, want it capped at %s", b.delay, maxReconnectBackoff)
+	}
+	b.connected()
+	if err := b.wait(); err != nil {
+		t.Errorf("Want no wait after connecting, got %v", err)
+	}
+}
+
+// Test that resent frames are not written twice and gaps are reported.
+func TestForwardServerDedupAndGaps(t *testing.T) {
+	sink := &syncBuffer{}
+	s := NewForwardServer(sink)
+	if got := s.writeFrame(7, 1, []byte("one\n")); got != 1 {
+		t.Errorf("Wrong ack: %d", got)
+	}
+	if got := s.writeFrame(7, 1, []byte("one\n")); got != 1 {
+		t.Errorf("Wrong ack for duplicate: %d", got)
+	}
+	if got := s.writeFrame(7, 4, []byte("four\n")); got != 4 {
+		t.Errorf("Wrong ack after gap: %d", got)
+	}
+	want := "one\nforward: lost 2 lines from client 0000000000000007\nfour\n"
+	if got := sink.String(); got != want {
+		t.Errorf("Got %q want %q", got, want)
+	}
+}
+
+func TestForwardWriterDropsOldest(t *testing.T) {
+	// Nothing is listening, so every write stays pending.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	w := NewForwardWriter(addr)
+	for i := 0; i < maxForwardPending+3; i++ {
+		w.Write([]byte("x\n"))
+	}
+	if got := w.Dropped(); got != 3 {
+		t.Errorf("Wrong number dropped, got %d want 3", got)
+	}
+	if w.pending[0].seq != 4 {
+		t.Errorf("Oldest should be dropped first, got seq %d", w.pending[0].seq)
+	}
+}
+
+// newTestCert returns a certificate for 127.0.0.1 signed by parent, or
+// self-signed if parent is nil.
+func newTestCert(t *testing.T, parent *tls.Certificate, isCA bool) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "logger test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	signer, signerKey := template, interface{}(key)
+	if parent != nil {
+		signer = parent.Leaf
+		signerKey = parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestForwardMutualTLS(t *testing.T) {
+	ca := newTestCert(t, nil, true)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+	serverCert := newTestCert(t, &ca, false)
+	clientCert := newTestCert(t, &ca, false)
+
+	sink := &syncBuffer{}
+	s := NewForwardServerWithCredentials(&Credentials{
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{serverCert},
+			ClientCAs:    pool,
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+		},
+		Token: "secret",
+	}, sink)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(ln)
+	defer s.Close()
+
+	w := NewForwardWriterWithCredentials(ln.Addr().String(), &Credentials{
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{clientCert},
+			RootCAs:      pool,
+		},
+		Token: "secret",
+	})
+	defer w.Close()
+	if _, err := w.Write([]byte("secure\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := sink.String(); got != "secure\n" {
+		t.Errorf("Got %q", got)
+	}
+
+	// Without a client certificate the handshake fails.
+	noCert := NewForwardWriterWithCredentials(ln.Addr().String(), &Credentials{
+		TLSConfig: &tls.Config{RootCAs: pool},
+		Token:     "secret",
+	})
+	defer noCert.Close()
+	noCert.Write([]byte("insecure\n"))
+	time.Sleep(50 * time.Millisecond)
+	if got := sink.String(); got != "secure\n" {
+		t.Errorf("Accepted a client without a certificate: %q", got)
+	}
+}
+
+func TestForwardToken(t *testing.T) {
+	sink := &syncBuffer{}
+	s := NewForwardServerWithCredentials(&Credentials{Token: "secret"}, sink)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(ln)
+	defer s.Close()
+
+	bad := NewForwardWriterWithCredentials(ln.Addr().String(), &Credentials{Token: "wrong"})
+	defer bad.Close()
+	bad.Write([]byte("rejected\n"))
+
+	good := NewForwardWriterWithCredentials(ln.Addr().String(), &Credentials{Token: "secret"})
+	defer good.Close()
+	good.Write([]byte("accepted\n"))
+	if err := good.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := sink.String(); got != "accepted\n" {
+		t.Errorf("Got %q", got)
+	}
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	req, err := http.NewRequest("POST", ts.URL, strings.NewReader("over http\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Wrong status without a token: %s", resp.Status)
+	}
+	req, err = http.NewRequest("POST", ts.URL, strings.NewReader("over http\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Wrong status with a token: %s", resp.Status)
+	}
+}
@@ -0,0 +1,32 @@
+package logger
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrShutdown is returned by WriteFrame and Healthy once the Logger has been
+// shut down, and by the writes of an AsyncWriter once it's closed.
+var ErrShutdown = errors.New("logger: shut down")
+
+// WriteFrame writes frame to the destination as is, in a single Write, for
+// pipelines where the Logger is only the transport for entries that are
+// already encoded, such as serialized protocol buffers:
+//
+//	b, _ := proto.Marshal(entry)
+//	err := l.WriteFrame(b)
+//
+// Unlike Raw, frame may hold any bytes. It isn't split into lines, given a
+// header or a newline, passed to Inspector tails, or counted as a line
+// written. Any delimiting needed to read the frames back, such as a length
+// prefix, is up to the caller. With Options.SeverityWriters it's written to
+// the Info destinations.
+//
+// WriteFrame returns the error from the destination, or ErrShutdown once
+// Shutdown has been called, as frames aren't fit to be written to stderr.
+func (l *Logger) WriteFrame(frame []byte) error {
+	if atomic.LoadInt32(&l.shutdown) == 1 {
+		return ErrShutdown
+	}
+	return l.write(entryInfo{s: infoLog}, frame)
+}
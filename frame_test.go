@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestWriteFrame(t *testing.T) {
+	w := &failingWriter{}
+	l := NewFromOptions(&Options{SyncWriter: w})
+	frame := []byte{0x0a, 0x03, 'a', '\n', 0xff, 0x00}
+	if err := l.WriteFrame(frame); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Bytes(); !bytes.Equal(got, frame) {
+		t.Errorf("got %q, want %q", got, frame)
+	}
+	if l.linesWritten != 0 {
+		t.Errorf("frame counted as %d lines", l.linesWritten)
+	}
+
+	diskFull := errors.New("disk full")
+	w.err = diskFull
+	if err := l.WriteFrame(frame); !errors.Is(err, diskFull) {
+		t.Errorf("got %v, want the write error", err)
+	}
+
+	w.err = nil
+	if err := l.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.WriteFrame(frame); err != ErrShutdown {
+		t.Errorf("got %v, want ErrShutdown", err)
+	}
+}
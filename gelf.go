@@ -0,0 +1,315 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GELFCompression selects how a GELFWriter compresses messages sent over
+// UDP.
+type GELFCompression int
+
+const (
+	// GELFUncompressed sends messages as they are.
+	GELFUncompressed GELFCompression = iota
+
+	// GELFGzip compresses messages with gzip.
+	GELFGzip
+
+	// GELFZlib compresses messages with zlib.
+	GELFZlib
+)
+
+const (
+	// defaultGELFChunkSize is the default GELFOptions.ChunkSize, which fits
+	// in the MTU of most networks.
+	defaultGELFChunkSize = 1420
+
+	// gelfChunkHeaderSize is the size of the header of each chunk: the
+	// magic bytes, the message id, and the sequence number and count.
+	gelfChunkHeaderSize = 12
+
+	// maxGELFChunks is the most chunks a message can be sent in.
+	maxGELFChunks = 128
+)
+
+// GELFOptions configures a GELFWriter.
+type GELFOptions struct {
+	// Network is "udp" or "tcp", or any of their variants accepted by
+	// net.Dial, "udp" by default.
+	Network string
+
+	// Addr is the address of the Graylog GELF input, e.g.
+	// "graylog.example.com:12201".
+	Addr string
+
+	// Host is the host written with each message, os.Hostname by default.
+	Host string
+
+	// Compression selects how messages are compressed over UDP. Graylog
+	// doesn't accept compressed messages over TCP, so it must be
+	// GELFUncompressed for TCP.
+	Compression GELFCompression
+
+	// ChunkSize is the most bytes sent in each UDP datagram, including the
+	// header of each chunk, 1420 by default. Larger messages are split into
+	// chunks, up to 128 of them, and those that need more are dropped.
+	ChunkSize int
+}
+
+// GELFWriter is a SyncWriter that sends each entry to Graylog as a GELF
+// message, at the level of its severity, with the same levels as
+// SyslogWriter. The short_message is the entry as formatted by the Logger,
+// without the trailing newline, and the fields of the entry, and its file,
+// line, and the name of its Logger, are sent as additional fields, e.g.:
+//
+//	{"version":"1.1","host":"web-1","short_message":"I0102 ...","timestamp":1704207845.123456,"level":6,"_file":"main.go","_line":10,"_path":"/"}
+//
+// Fields in groups are flattened into keys joined by ".". Numbers are sent
+// as numbers, and other values as strings. Writes that aren't of an entry,
+// such as those from Crash, are sent at the level of the header they start
+// with, or informational.
+//
+// It connects when created, and reconnects once after a write fails. Over
+// TCP messages are terminated by a null byte.
+type GELFWriter struct {
+	o      GELFOptions
+	stream bool
+
+	// mu protects conn and ids.
+	mu   sync.Mutex
+	conn net.Conn
+	ids  *rand.Rand
+}
+
+// NewGELFWriter returns a GELFWriter configured by o, connecting to check
+// the address.
+func NewGELFWriter(o *GELFOptions) (*GELFWriter, error) {
+	w := &GELFWriter{o: *o, ids: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	if w.o.Addr == "" {
+		return nil, errors.New("no GELF address")
+	}
+	if w.o.Network == "" {
+		w.o.Network = "udp"
+	}
+	w.stream = strings.HasPrefix(w.o.Network, "tcp")
+	if w.stream && w.o.Compression != GELFUncompressed {
+		return nil, errors.New("GELF over TCP can't be compressed")
+	}
+	if w.o.Host == "" {
+		w.o.Host, _ = os.Hostname()
+	}
+	if w.o.ChunkSize == 0 {
+		w.o.ChunkSize = defaultGELFChunkSize
+	}
+	if w.o.ChunkSize <= gelfChunkHeaderSize {
+		return nil, fmt.Errorf("GELF ChunkSize of %d is too small", w.o.ChunkSize)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.connectLocked(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *GELFWriter) connectLocked() error {
+	conn, err := net.DialTimeout(w.o.Network, w.o.Addr, forwardDialTimeout)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+// writeEntry implements entryWriter.
+func (w *GELFWriter) writeEntry(e entryInfo, p []byte) (int, error) {
+	return w.send(e, p)
+}
+
+// Write implements SyncWriter.
+func (w *GELFWriter) Write(p []byte) (int, error) {
+	e := entryInfo{s: infoLog}
+	if len(p) > 0 {
+		if fromChar, ok := severityFromChar(p[0]); ok {
+			e.s = fromChar
+		}
+	}
+	return w.send(e, p)
+}
+
+// send sends p, of the entry described by e, as a GELF message.
+func (w *GELFWriter) send(e entryInfo, p []byte) (int, error) {
+	msg, err := w.compress(w.message(e, strings.TrimRight(string(p), "\n")))
+	if err != nil {
+		return 0, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var packets [][]byte
+	if w.stream {
+		packets = [][]byte{append(msg, 0)}
+	} else if packets, err = w.chunksLocked(msg); err != nil {
+		return 0, err
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		if w.conn == nil {
+			if err = w.connectLocked(); err != nil {
+				continue
+			}
+		}
+		for _, packet := range packets {
+			if _, err = w.conn.Write(packet); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			return len(p), nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	return 0, err
+}
+
+// message returns the GELF message for msg, of the entry described by e.
+func (w *GELFWriter) message(e entryInfo, msg string) []byte {
+	now := e.time
+	if now.IsZero() {
+		now = timeNow()
+	}
+	buf := &buffer{}
+	buf.WriteString(`{"version":"1.1","host":`)
+	appendJSONString(buf, w.o.Host)
+	buf.WriteString(`,"short_message":`)
+	appendJSONString(buf, msg)
+	fmt.Fprintf(buf, `,"timestamp":%d.%06d,"level":%d`, now.Unix(), now.Nanosecond()/1000, syslogPriorities[e.s.base()])
+	if e.file != "" {
+		buf.WriteString(`,"_file":`)
+		appendJSONString(buf, e.file)
+		buf.WriteString(`,"_line":`)
+		buf.WriteString(strconv.Itoa(e.line))
+	}
+	if e.name != "" {
+		buf.WriteString(`,"_logger":`)
+		appendJSONString(buf, e.name)
+	}
+	var add func(prefix string, fields []Field)
+	add = func(prefix string, fields []Field) {
+		for _, f := range fields {
+			if f.t == groupField {
+				children, _ := f.iface.([]Field)
+				add(prefix+f.Key+".", children)
+				continue
+			}
+			buf.WriteByte(',')
+			appendJSONString(buf, gelfFieldName(prefix+f.Key))
+			buf.WriteByte(':')
+			switch v := math.Float64frombits(uint64(f.num)); {
+			case f.t == int64Field:
+				buf.WriteString(strconv.FormatInt(f.num, 10))
+			case f.t == float64Field && !math.IsNaN(v) && !math.IsInf(v, 0):
+				buf.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+			default:
+				appendJSONString(buf, fieldValue(f))
+			}
+		}
+	}
+	add("", e.fields)
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
+
+// gelfFieldName returns key as the name of a GELF additional field, which
+// starts with "_" followed by letters, digits, '_', '.', or '-', and isn't
+// "_id", which Graylog reserves.
+func gelfFieldName(key string) string {
+	b := []byte("_" + key)
+	for i, c := range b {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '-') {
+			b[i] = '_'
+		}
+	}
+	if string(b) == "_id" {
+		return "__id"
+	}
+	return string(b)
+}
+
+// compress returns msg compressed as selected by GELFOptions.Compression.
+func (w *GELFWriter) compress(msg []byte) ([]byte, error) {
+	var out bytes.Buffer
+	var zw io.WriteCloser
+	switch w.o.Compression {
+	case GELFGzip:
+		zw = gzip.NewWriter(&out)
+	case GELFZlib:
+		zw = zlib.NewWriter(&out)
+	default:
+		return msg, nil
+	}
+	if _, err := zw.Write(msg); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// chunksLocked returns msg as a single datagram if it fits in ChunkSize, or
+// otherwise split into GELF chunks. w.mu must be held.
+func (w *GELFWriter) chunksLocked(msg []byte) ([][]byte, error) {
+	if len(msg) <= w.o.ChunkSize {
+		return [][]byte{msg}, nil
+	}
+	size := w.o.ChunkSize - gelfChunkHeaderSize
+	n := (len(msg) + size - 1) / size
+	if n > maxGELFChunks {
+		return nil, fmt.Errorf("GELF message of %d bytes needs more than %d chunks", len(msg), maxGELFChunks)
+	}
+	id := make([]byte, 8)
+	w.ids.Read(id)
+	chunks := make([][]byte, 0, n)
+	for i := 0; i < n; i++ {
+		end := (i + 1) * size
+		if end > len(msg) {
+			end = len(msg)
+		}
+		chunk := make([]byte, 0, gelfChunkHeaderSize+end-i*size)
+		chunk = append(chunk, 0x1e, 0x0f)
+		chunk = append(chunk, id...)
+		chunk = append(chunk, byte(i), byte(n))
+		chunks = append(chunks, append(chunk, msg[i*size:end]...))
+	}
+	return chunks, nil
+}
+
+// Sync implements SyncWriter.
+func (w *GELFWriter) Sync() error {
+	return nil
+}
+
+// Close closes the connection.
+func (w *GELFWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
@@ -0,0 +1,188 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readGELF returns the next GELF message received by c, decoded.
+func readGELF(t *testing.T, c net.PacketConn) map[string]interface{} {
+	var msg map[string]interface{}
+	if err := json.Unmarshal([]byte(readPacket(t, c)), &msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestGELFWriter(t *testing.T) {
+	c, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	w, err := NewGELFWriter(&GELFOptions{Addr: c.LocalAddr().String(), Host: "web-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	now := time.Date(2024, 1, 2, 15, 4, 5, 123456000, time.UTC)
+	l := NewFromOptions(&Options{SyncWriter: w, Now: func() time.Time { return now }}).Named("db")
+
+	l.WarningFields("slow", Str("path", "/"), Int("status", 200), Float64("secs", 1.5), Bool("cached", false), Group("req", Str("id", "abc")), Str("id", "x"), Str("a b", "c"))
+	got := readGELF(t, c)
+	for key, want := range map[string]interface{}{
+		"version":   "1.1",
+		"host":      "web-1",
+		"timestamp": 1704207845.123456,
+		"level":     4.0,
+		"_file":     "gelf_test.go",
+		"_logger":   "db",
+		"_path":     "/",
+		"_status":   200.0,
+		"_secs":     1.5,
+		"_cached":   "false",
+		"_req.id":   "abc",
+		"__id":      "x",
+		"_a_b":      "c",
+	} {
+		if got[key] != want {
+			t.Errorf("%s is %#v, want %#v", key, got[key], want)
+		}
+	}
+	if msg, _ := got["short_message"].(string); !strings.HasPrefix(msg, "W0102 ") || !strings.Contains(msg, "] db: slow path=/") {
+		t.Errorf("Wrong short_message: %q", msg)
+	}
+	if line, _ := got["_line"].(float64); line == 0 {
+		t.Errorf("No line: %v", got)
+	}
+
+	// Not an entry, so the level comes from the header.
+	w.Write([]byte("E0102 crashed\n"))
+	if got := readGELF(t, c); got["level"] != 3.0 || got["short_message"] != "E0102 crashed" || got["_file"] != nil {
+		t.Errorf("Wrong message for a write: %v", got)
+	}
+}
+
+func TestGELFWriterChunks(t *testing.T) {
+	c, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	w, err := NewGELFWriter(&GELFOptions{Addr: c.LocalAddr().String(), Compression: GELFGzip, ChunkSize: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	l := NewFromOptions(&Options{SyncWriter: w})
+
+	// Random enough not to compress into a single chunk.
+	var long strings.Builder
+	for i := 0; i < 100; i++ {
+		long.WriteString(time.Duration(i * i * 7919).String())
+	}
+	l.Info(long.String())
+	var compressed []byte
+	var id []byte
+	for seq, total := 0, 1; seq < total; seq++ {
+		chunk := []byte(readPacket(t, c))
+		if len(chunk) > 100 || chunk[0] != 0x1e || chunk[1] != 0x0f || int(chunk[10]) != seq {
+			t.Fatalf("Bad chunk %d: %q", seq, chunk)
+		}
+		if id == nil {
+			id, total = chunk[2:10], int(chunk[11])
+		} else if !bytes.Equal(chunk[2:10], id) {
+			t.Fatalf("Chunk %d has id %x, want %x", seq, chunk[2:10], id)
+		}
+		compressed = append(compressed, chunk[12:]...)
+		if total < 2 {
+			t.Fatal("The message wasn't chunked")
+		}
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg map[string]interface{}
+	if err := json.Unmarshal(b, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := msg["short_message"].(string); !strings.HasSuffix(s, "] "+long.String()) {
+		t.Errorf("Wrong short_message: %q", s)
+	}
+
+	uncompressed, err := NewGELFWriter(&GELFOptions{Addr: c.LocalAddr().String(), ChunkSize: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer uncompressed.Close()
+	if _, err := uncompressed.Write(bytes.Repeat([]byte{'x'}, 128*88)); err == nil {
+		t.Error("No error for a message needing too many chunks")
+	}
+}
+
+func TestGELFWriterTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	messages := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			msg, err := r.ReadString(0)
+			if err != nil {
+				return
+			}
+			messages <- strings.TrimSuffix(msg, "\x00")
+		}
+	}()
+
+	w, err := NewSink("gelf://" + ln.Addr().String() + "?network=tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.(*GELFWriter).Close()
+	l := NewFromOptions(&Options{SyncWriter: w})
+	l.Info("one")
+	l.Error("two")
+	for _, want := range []string{"] one", "] two"} {
+		select {
+		case got := <-messages:
+			var msg map[string]interface{}
+			if err := json.Unmarshal([]byte(got), &msg); err != nil {
+				t.Fatal(err)
+			}
+			if s, _ := msg["short_message"].(string); !strings.HasSuffix(s, want) {
+				t.Errorf("Got %q, want the suffix %q", s, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+}
+
+func TestGELFSinkErrors(t *testing.T) {
+	for _, u := range []string{"gelf:", "gelf://127.0.0.1:1?compress=lz4", "gelf://127.0.0.1:1?network=tcp&compress=gzip"} {
+		if _, err := NewSink(u); err == nil {
+			t.Errorf("No error for %q", u)
+		}
+	}
+}
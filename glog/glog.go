@@ -0,0 +1,387 @@
+// Package glog is a drop-in replacement for github.com/golang/glog that
+// writes through a logger.Logger, so a codebase that uses glog can switch
+// its imports and get the sinks and encoders of this module:
+//
+//	import "github.com/jcgregorio/logger/glog"
+//
+//	glog.Infof("listening on %s", addr)
+//	glog.V(2).Info("cache miss")
+//	defer glog.Flush()
+//
+// The same flags as glog are registered on flag.CommandLine, and are
+// applied when the first log is written, so flag.Parse must be called
+// before logging, as with glog. Call SetLogger to use a Logger configured
+// in code instead.
+package glog
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jcgregorio/logger"
+)
+
+// Level is a verbosity level, as passed to V and set with the -v flag.
+type Level int32
+
+// Get implements flag.Getter.
+func (l *Level) Get() interface{} {
+	return *l
+}
+
+// Set implements flag.Value.
+func (l *Level) Set(value string) error {
+	v, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt32((*int32)(l), int32(v))
+	if cur := current(); cur != nil {
+		cur.l.SetVerbosity(int(v))
+	}
+	return nil
+}
+
+// String implements flag.Value.
+func (l *Level) String() string {
+	return strconv.FormatInt(int64(atomic.LoadInt32((*int32)(l))), 10)
+}
+
+// moduleSpec is the value of the -vmodule flag.
+type moduleSpec struct {
+	mu   sync.Mutex
+	spec string
+}
+
+// Set implements flag.Value.
+func (m *moduleSpec) Set(value string) error {
+	if cur := current(); cur != nil {
+		if err := cur.l.SetVModule(value); err != nil {
+			return err
+		}
+	} else if err := logger.New().SetVModule(value); err != nil {
+		// Only validate it, it's applied once the Logger is built.
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.spec = value
+	return nil
+}
+
+// String implements flag.Value.
+func (m *moduleSpec) String() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.spec
+}
+
+// severityFlag is the value of the -stderrthreshold flag, which glog
+// accepts either as a name or a number.
+type severityFlag struct {
+	s int32
+}
+
+// severityNames are the glog severities, indexed by their number.
+var severityNames = []string{"INFO", "WARNING", "ERROR", "FATAL"}
+
+// Set implements flag.Value.
+func (f *severityFlag) Set(value string) error {
+	for i, name := range severityNames {
+		if strings.EqualFold(value, name) {
+			atomic.StoreInt32(&f.s, int32(i))
+			return nil
+		}
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil || v < 0 || v >= len(severityNames) {
+		return fmt.Errorf("unknown severity %q", value)
+	}
+	atomic.StoreInt32(&f.s, int32(v))
+	return nil
+}
+
+// String implements flag.Value.
+func (f *severityFlag) String() string {
+	return strconv.Itoa(int(atomic.LoadInt32(&f.s)))
+}
+
+var (
+	verbosity       Level
+	vmodule         moduleSpec
+	stderrThreshold = severityFlag{s: 2}
+	logToStderr     = flag.Bool("logtostderr", false, "log to standard error instead of files")
+	alsoLogToStderr = flag.Bool("alsologtostderr", false, "log to standard error as well as files")
+	logDir          = flag.String("log_dir", "", "If non-empty, write log files in this directory")
+)
+
+func init() {
+	flag.Var(&verbosity, "v", "log level for V logs")
+	flag.Var(&vmodule, "vmodule", "comma-separated list of pattern=N settings for file-filtered logging")
+	flag.Var(&stderrThreshold, "stderrthreshold", "logs at or above this threshold go to stderr")
+	// Stack traces at a given line aren't supported.
+	flag.String("log_backtrace_at", "", "accepted for compatibility with glog, and ignored")
+}
+
+// state is the Logger the package functions write to.
+type state struct {
+	// l is the Logger that was set or built from the flags.
+	l *logger.Logger
+
+	// l1 skips one more stack level than l, to skip the package functions.
+	l1 *logger.Logger
+}
+
+var (
+	// cur holds the *state, once built.
+	cur atomic.Value
+
+	// mu serializes building and replacing the state.
+	mu sync.Mutex
+)
+
+// current returns the current state, or nil if it hasn't been built yet.
+func current() *state {
+	s, _ := cur.Load().(*state)
+	return s
+}
+
+// get returns the current state, building it from the flags if needed.
+func get() *state {
+	if s := current(); s != nil {
+		return s
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if s := current(); s != nil {
+		return s
+	}
+	s := fromFlags()
+	cur.Store(s)
+	return s
+}
+
+// fromFlags builds the state as selected by the flags: everything goes to
+// stderr for -logtostderr, otherwise to <program>.log in -log_dir, or the
+// temp directory, along with stderr for logs at or above -stderrthreshold,
+// or all logs for -alsologtostderr.
+func fromFlags() *state {
+	var dest logger.SyncWriter = os.Stderr
+	var dests map[logger.Severity]logger.SyncWriter
+	if !*logToStderr {
+		dir := *logDir
+		if dir == "" {
+			dir = os.TempDir()
+		}
+		path := filepath.Join(dir, filepath.Base(os.Args[0])+".log")
+		if f, err := logger.NewFileWriter(path); err != nil {
+			fmt.Fprintf(os.Stderr, "glog: can't create log file, logging to stderr: %s\n", err)
+		} else {
+			dest = f
+			threshold := logger.InfoSeverity + logger.Severity(atomic.LoadInt32(&stderrThreshold.s))
+			if *alsoLogToStderr {
+				threshold = logger.InfoSeverity
+			}
+			// glog has no Debug logs, so keying the file on Debug lets
+			// stderr be keyed on Info for -alsologtostderr.
+			dests = map[logger.Severity]logger.SyncWriter{
+				logger.DebugSeverity: f,
+				threshold:            os.Stderr,
+			}
+		}
+	}
+	l := logger.NewFromOptions(&logger.Options{
+		SyncWriter:      dest,
+		SeverityWriters: dests,
+		Verbosity:       int(atomic.LoadInt32((*int32)(&verbosity))),
+		VModule:         vmodule.String(),
+	})
+	return newState(l)
+}
+
+func newState(l *logger.Logger) *state {
+	return &state{l: l, l1: l.WithDepth(1)}
+}
+
+// SetLogger makes the package functions write to l, instead of the Logger
+// built from the flags. Passing nil goes back to the Logger built from the
+// flags the next time a log is written. The -v and -vmodule flags are
+// applied to l when they're set.
+func SetLogger(l *logger.Logger) {
+	mu.Lock()
+	defer mu.Unlock()
+	if l == nil {
+		cur.Store((*state)(nil))
+		return
+	}
+	cur.Store(newState(l))
+}
+
+// Flush flushes the current Logger, see logger.Logger.Flush.
+func Flush() {
+	if s := current(); s != nil {
+		s.l.Flush()
+	}
+}
+
+// sprintln formats args as fmt.Sprintln does, without the newline.
+func sprintln(args []interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
+
+// Verbose is returned by V. It's a bool so it can be tested directly, as
+// with glog:
+//
+//	if glog.V(2) {
+//		glog.Info(expensiveDump())
+//	}
+type Verbose bool
+
+// V reports whether the verbosity, set with -v, or the level set for the
+// calling file with -vmodule, is at least level.
+func V(level Level) Verbose {
+	return Verbose(get().l1.V(int(level)).Enabled())
+}
+
+// Info is equivalent to the package's Info if v is true.
+func (v Verbose) Info(args ...interface{}) {
+	if v {
+		get().l1.Info(args...)
+	}
+}
+
+// Infoln is equivalent to the package's Infoln if v is true.
+func (v Verbose) Infoln(args ...interface{}) {
+	if v {
+		get().l1.Info(sprintln(args))
+	}
+}
+
+// Infof is equivalent to the package's Infof if v is true.
+func (v Verbose) Infof(format string, args ...interface{}) {
+	if v {
+		get().l1.Infof(format, args...)
+	}
+}
+
+// Info logs at Info, formatting args as fmt.Print does.
+func Info(args ...interface{}) {
+	get().l1.Info(args...)
+}
+
+// InfoDepth is Info, reporting the caller depth frames above the caller.
+func InfoDepth(depth int, args ...interface{}) {
+	get().l1.WithDepth(depth).Info(args...)
+}
+
+// Infoln logs at Info, formatting args as fmt.Println does.
+func Infoln(args ...interface{}) {
+	get().l1.Info(sprintln(args))
+}
+
+// Infof logs at Info, formatting args as fmt.Printf does.
+func Infof(format string, args ...interface{}) {
+	get().l1.Infof(format, args...)
+}
+
+// Warning logs at Warning, formatting args as fmt.Print does.
+func Warning(args ...interface{}) {
+	get().l1.Warning(args...)
+}
+
+// WarningDepth is Warning, reporting the caller depth frames above the
+// caller.
+func WarningDepth(depth int, args ...interface{}) {
+	get().l1.WithDepth(depth).Warning(args...)
+}
+
+// Warningln logs at Warning, formatting args as fmt.Println does.
+func Warningln(args ...interface{}) {
+	get().l1.Warning(sprintln(args))
+}
+
+// Warningf logs at Warning, formatting args as fmt.Printf does.
+func Warningf(format string, args ...interface{}) {
+	get().l1.Warningf(format, args...)
+}
+
+// Error logs at Error, formatting args as fmt.Print does.
+func Error(args ...interface{}) {
+	get().l1.Error(args...)
+}
+
+// ErrorDepth is Error, reporting the caller depth frames above the caller.
+func ErrorDepth(depth int, args ...interface{}) {
+	get().l1.WithDepth(depth).Error(args...)
+}
+
+// Errorln logs at Error, formatting args as fmt.Println does.
+func Errorln(args ...interface{}) {
+	get().l1.Error(sprintln(args))
+}
+
+// Errorf logs at Error, formatting args as fmt.Printf does.
+func Errorf(format string, args ...interface{}) {
+	get().l1.Errorf(format, args...)
+}
+
+// Fatal logs at Fatal, formatting args as fmt.Print does, followed by the
+// stack traces of all goroutines, then exits with status 255.
+func Fatal(args ...interface{}) {
+	get().l1.Fatal(args...)
+}
+
+// FatalDepth is Fatal, reporting the caller depth frames above the caller.
+func FatalDepth(depth int, args ...interface{}) {
+	get().l1.WithDepth(depth).Fatal(args...)
+}
+
+// Fatalln is Fatal, formatting args as fmt.Println does.
+func Fatalln(args ...interface{}) {
+	get().l1.Fatal(sprintln(args))
+}
+
+// Fatalf is Fatal, formatting args as fmt.Printf does.
+func Fatalf(format string, args ...interface{}) {
+	get().l1.Fatalf(format, args...)
+}
+
+// osExit is os.Exit, stubbed out for testing.
+var osExit = os.Exit
+
+// Exit logs args, formatted as fmt.Print does, then flushes and exits with
+// status 1, without stack traces. Unlike glog it's logged as an Error, as
+// the Logger always writes stack traces for Fatal logs.
+func Exit(args ...interface{}) {
+	get().l1.Error(args...)
+	exit()
+}
+
+// ExitDepth is Exit, reporting the caller depth frames above the caller.
+func ExitDepth(depth int, args ...interface{}) {
+	get().l1.WithDepth(depth).Error(args...)
+	exit()
+}
+
+// Exitln is Exit, formatting args as fmt.Println does.
+func Exitln(args ...interface{}) {
+	get().l1.Error(sprintln(args))
+	exit()
+}
+
+// Exitf is Exit, formatting args as fmt.Printf does.
+func Exitf(format string, args ...interface{}) {
+	get().l1.Errorf(format, args...)
+	exit()
+}
+
+func exit() {
+	Flush()
+	osExit(1)
+}
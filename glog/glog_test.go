@@ -0,0 +1,164 @@
+package glog
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jcgregorio/logger"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Sync() error {
+	return nil
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func useBuffer(t *testing.T) *syncBuffer {
+	b := &syncBuffer{}
+	SetLogger(logger.NewFromOptions(&logger.Options{SyncWriter: b}))
+	t.Cleanup(func() { SetLogger(nil) })
+	return b
+}
+
+var lineNumberRegex = regexp.MustCompile(`glog_test.go:\d+\]`)
+
+func TestInfo(t *testing.T) {
+	b := useBuffer(t)
+	Info("a", 1)
+	Infoln("b", 2)
+	Infof("c=%d", 3)
+	Warning("d")
+	Errorf("e")
+	got := lineNumberRegex.ReplaceAllString(b.String(), "glog_test.go:N]")
+	for _, want := range []string{
+		"glog_test.go:N] a1\n",
+		"glog_test.go:N] b 2\n",
+		"glog_test.go:N] c=3\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in %q", want, got)
+		}
+	}
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	if len(lines) != 5 || lines[3][0] != 'W' || lines[4][0] != 'E' {
+		t.Errorf("wrong severities: %q", got)
+	}
+}
+
+func helper() {
+	InfoDepth(1, "from helper")
+}
+
+func TestInfoDepth(t *testing.T) {
+	b := useBuffer(t)
+	_, _, line, _ := runtime.Caller(0)
+	helper()
+	want := fmt.Sprintf("glog_test.go:%d] from helper", line+1)
+	if got := b.String(); !strings.Contains(got, want) {
+		t.Errorf("got %q, want the caller of helper, %q", got, want)
+	}
+}
+
+func TestV(t *testing.T) {
+	b := useBuffer(t)
+	if err := verbosity.Set("2"); err != nil {
+		t.Fatal(err)
+	}
+	defer verbosity.Set("0")
+
+	if !V(2) {
+		t.Error("V(2) not enabled at -v=2")
+	}
+	if V(3) {
+		t.Error("V(3) enabled at -v=2")
+	}
+	V(2).Infof("shown %d", 2)
+	V(3).Info("hidden")
+	if got := b.String(); !strings.Contains(got, "] shown 2\n") || strings.Contains(got, "hidden") {
+		t.Errorf("wrong V output: %q", got)
+	}
+}
+
+func TestVModule(t *testing.T) {
+	useBuffer(t)
+	if err := vmodule.Set("glog_test=3"); err != nil {
+		t.Fatal(err)
+	}
+	defer vmodule.Set("")
+	if !V(3) {
+		t.Error("V(3) not enabled by -vmodule")
+	}
+	if err := vmodule.Set("glog_test=x"); err == nil {
+		t.Error("invalid -vmodule accepted")
+	}
+}
+
+func TestStderrThreshold(t *testing.T) {
+	var f severityFlag
+	for value, want := range map[string]string{"WARNING": "1", "error": "2", "3": "3"} {
+		if err := f.Set(value); err != nil {
+			t.Fatal(err)
+		}
+		if got := f.String(); got != want {
+			t.Errorf("Set(%q) = %s, want %s", value, got, want)
+		}
+	}
+	if err := f.Set("VERBOSE"); err == nil {
+		t.Error("unknown severity accepted")
+	}
+}
+
+func TestLogDir(t *testing.T) {
+	dir := t.TempDir()
+	*logDir = dir
+	defer func() { *logDir = "" }()
+	SetLogger(nil)
+	defer SetLogger(nil)
+
+	Warning("to a file")
+	Flush()
+	b, err := os.ReadFile(filepath.Join(dir, filepath.Base(os.Args[0])+".log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); got[0] != 'W' || !strings.HasSuffix(got, "] to a file\n") {
+		t.Errorf("wrong log file contents: %q", got)
+	}
+}
+
+func TestExit(t *testing.T) {
+	b := useBuffer(t)
+	code := -1
+	osExit = func(c int) { code = c }
+	defer func() { osExit = os.Exit }()
+
+	Exitf("bye %s", "now")
+	if code != 1 {
+		t.Errorf("exit code %d, want 1", code)
+	}
+	if got := b.String(); !strings.HasSuffix(got, "] bye now\n") || strings.Contains(got, "goroutine") {
+		t.Errorf("wrong Exit output: %q", got)
+	}
+}
@@ -2,4 +2,6 @@ module github.com/jcgregorio/logger
 
 go 1.18
 
-require github.com/jcgregorio/slog v0.0.0-20190423190439-e6f2d537f900
+require go.opentelemetry.io/otel/trace v1.14.0
+
+require go.opentelemetry.io/otel v1.14.0 // indirect
@@ -0,0 +1,29 @@
+package logger
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+)
+
+// goroutineID returns the id of the calling goroutine.
+//
+// Go deliberately doesn't expose goroutine ids, so this parses the first
+// line of the goroutine's stack trace, which looks like:
+//
+//	goroutine 123 [running]:
+//
+// It returns 0 if the id can't be determined.
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, err := strconv.ParseUint(string(b), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
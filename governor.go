@@ -0,0 +1,171 @@
+package logger
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// QueueDepther may be implemented by a SyncWriter that queues writes, to
+// report how many are queued, and is consulted by a Governor.
+type QueueDepther interface {
+	// QueueDepth returns the number of writes that are queued.
+	QueueDepth() int
+}
+
+// Governor raises the minimum severity of a Logger while its destination
+// is saturated, so a struggling pipeline isn't swamped by Debug and Info
+// entries, and restores it once the pressure subsides, see
+// Options.Governor. Raising and restoring it are both logged, at
+// WarningSeverity, or RaiseTo if that's higher.
+type Governor struct {
+	// MaxLatency, if greater than zero, is the average time writes to the
+	// destination may take before it's considered saturated.
+	MaxLatency time.Duration
+
+	// MaxQueueDepth, if greater than zero, is the number of queued writes
+	// the destination may report, if it implements QueueDepther, before
+	// it's considered saturated.
+	MaxQueueDepth int
+
+	// RaiseTo is the minimum severity while the destination is saturated.
+	// If it's less than WarningSeverity then WarningSeverity is used. It's
+	// at most ErrorSeverity.
+	RaiseTo Severity
+
+	// Cooldown is how long the destination must not be saturated before the
+	// minimum severity is restored. If zero then 10 seconds is used.
+	Cooldown time.Duration
+}
+
+// governorLatencyWeight is the weight of the previous average in the
+// average write latency, so a single slow write doesn't raise the minimum
+// severity.
+const governorLatencyWeight = 7
+
+// governor is the state of an Options.Governor.
+type governor struct {
+	g Governor
+
+	// raised is 1 while the minimum severity is raised, accessed atomically.
+	raised int32
+
+	// mu protects the fields below.
+	mu sync.Mutex
+
+	// avg is the average write latency.
+	avg time.Duration
+
+	// raisedAt is when the minimum severity was raised, and saturatedAt
+	// when the destination was last seen to be saturated.
+	raisedAt, saturatedAt time.Time
+}
+
+// newGovernor returns the state for g, or nil if g is nil.
+func newGovernor(g *Governor) *governor {
+	if g == nil {
+		return nil
+	}
+	ret := &governor{g: *g}
+	if ret.g.RaiseTo.less(WarningSeverity) {
+		ret.g.RaiseTo = WarningSeverity
+	}
+	if ErrorSeverity.less(ret.g.RaiseTo) {
+		ret.g.RaiseTo = ErrorSeverity
+	}
+	if ret.g.Cooldown <= 0 {
+		ret.g.Cooldown = 10 * time.Second
+	}
+	return ret
+}
+
+// governorTransition is a change of the minimum severity to be logged.
+type governorTransition struct {
+	msg    string
+	fields []Field
+}
+
+// observe records a write to w that took latency, and returns the
+// transition, if it raised the minimum severity.
+func (g *governor) observe(w SyncWriter, latency time.Duration, now time.Time) *governorTransition {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.avg = (g.avg*governorLatencyWeight + latency) / (governorLatencyWeight + 1)
+	depth := 0
+	if qd, ok := w.(QueueDepther); ok {
+		depth = qd.QueueDepth()
+	}
+	if (g.g.MaxLatency <= 0 || g.avg <= g.g.MaxLatency) && (g.g.MaxQueueDepth <= 0 || depth <= g.g.MaxQueueDepth) {
+		return nil
+	}
+	g.saturatedAt = now
+	if !atomic.CompareAndSwapInt32(&g.raised, 0, 1) {
+		return nil
+	}
+	g.raisedAt = now
+	return &governorTransition{
+		msg: "log level raised",
+		fields: []Field{
+			Str("min_severity", g.g.RaiseTo.String()),
+			Dur("latency", g.avg),
+			Int("queue_depth", depth),
+		},
+	}
+}
+
+// restore returns the transition if the minimum severity is raised and the
+// destination hasn't been saturated for Cooldown, restoring it.
+func (g *governor) restore(now time.Time) *governorTransition {
+	if atomic.LoadInt32(&g.raised) == 0 {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if now.Sub(g.saturatedAt) < g.g.Cooldown || !atomic.CompareAndSwapInt32(&g.raised, 1, 0) {
+		return nil
+	}
+	// Start afresh, rather than from the latency that raised it.
+	g.avg = 0
+	return &governorTransition{
+		msg:    "log level restored",
+		fields: []Field{Dur("raised_for", now.Sub(g.raisedAt))},
+	}
+}
+
+// raisedAbove returns true if the minimum severity is raised above s.
+func (g *governor) raisedAbove(s severity) bool {
+	return atomic.LoadInt32(&g.raised) == 1 && Severity(s).less(g.g.RaiseTo)
+}
+
+// governed returns true if an entry of severity s should be dropped because
+// the Governor has raised the minimum severity.
+func (l *Logger) governed(s severity) bool {
+	if l.governor == nil {
+		return false
+	}
+	l.logTransition(l.governor.restore(l.timeNow()))
+	return l.governor.raisedAbove(s)
+}
+
+// observeWrite passes a write to the destination, which started at start,
+// to the Governor. l.wMu mustn't be held, as a transition is logged.
+func (l *Logger) observeWrite(start time.Time) {
+	now := l.timeNow()
+	l.logTransition(l.governor.observe(l.writer(), now.Sub(start), now))
+}
+
+// logTransition logs t, if not nil, without the fields or name of l, as
+// made by the Governor.
+func (l *Logger) logTransition(t *governorTransition) {
+	if t == nil {
+		return
+	}
+	_, file, line, _ := runtime.Caller(0)
+	s := WarningSeverity
+	if s.less(l.governor.g.RaiseTo) {
+		s = l.governor.g.RaiseTo
+	}
+	root := &Logger{loggerState: l.loggerState}
+	root.WithCaller(file, line).logDepth(severity(s), 0, []byte(t.msg), t.fields)
+}
@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowWriter is a flushBuffer whose writes take delay, as measured by the
+// clock now, and which reports depth as its queue depth.
+type slowWriter struct {
+	flushBuffer
+	now   *time.Time
+	delay time.Duration
+	depth int
+}
+
+func (s *slowWriter) Write(p []byte) (int, error) {
+	*s.now = s.now.Add(s.delay)
+	return s.flushBuffer.Write(p)
+}
+
+func (s *slowWriter) QueueDepth() int {
+	return s.depth
+}
+
+func TestGovernorLatency(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	w := &slowWriter{now: &now, delay: 50 * time.Millisecond}
+	l := NewFromOptions(&Options{
+		SyncWriter: w,
+		Governor:   &Governor{MaxLatency: 10 * time.Millisecond},
+		Now:        func() time.Time { return now },
+	})
+	l.Info("first")
+	l.Info("saturated")
+	if got := w.String(); !strings.Contains(got, "] log level raised min_severity=WARNING latency=") {
+		t.Fatalf("Raising not logged: %q", got)
+	}
+	l.Info("dropped")
+	l.Warning("kept")
+	if got := w.String(); strings.Contains(got, "dropped") || !strings.Contains(got, "kept") {
+		t.Errorf("Wrong entries while raised: %q", got)
+	}
+
+	w.delay = 0
+	l.Info("still dropped")
+	now = now.Add(10 * time.Second)
+	l.Info("restored")
+	got := w.String()
+	if strings.Contains(got, "still dropped") || !strings.Contains(got, "] log level restored raised_for=10.") || !strings.HasSuffix(got, "] restored\n") {
+		t.Errorf("Not restored: %q", got)
+	}
+	if !strings.Contains(got, "W0310") || !strings.Contains(got, " governor.go:") {
+		t.Errorf("Transitions should be Warnings from the Governor: %q", got)
+	}
+}
+
+func TestGovernorQueueDepth(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	w := &slowWriter{now: &now, depth: 500}
+	l := NewFromOptions(&Options{
+		SyncWriter: w,
+		Governor:   &Governor{MaxQueueDepth: 100, RaiseTo: ErrorSeverity, Cooldown: time.Minute},
+		Now:        func() time.Time { return now },
+	})
+	l.Info("first")
+	l.Warning("dropped")
+	l.Error("kept")
+	got := w.String()
+	if !strings.Contains(got, "E0310") || !strings.Contains(got, "] log level raised min_severity=ERROR latency=0s queue_depth=500\n") {
+		t.Errorf("Raising not logged as an Error: %q", got)
+	}
+	if strings.Contains(got, "dropped") || !strings.Contains(got, "kept") {
+		t.Errorf("Wrong entries while raised: %q", got)
+	}
+}
@@ -0,0 +1,17 @@
+package logger
+
+import "hash/fnv"
+
+// messageHash returns a short hash of the rendered message, suitable as a key
+// for grouping identical events.
+func messageHash(msg []byte) string {
+	h := fnv.New32a()
+	h.Write(msg)
+	var hex [8]byte
+	sum := h.Sum32()
+	for i := len(hex) - 1; i >= 0; i-- {
+		hex[i] = "0123456789abcdef"[sum&0xf]
+		sum >>= 4
+	}
+	return string(hex[:])
+}
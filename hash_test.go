@@ -0,0 +1,30 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMessageHash(t *testing.T) {
+	l := NewFromOptions(&Options{
+		SyncWriter:  &flushBuffer{},
+		MessageHash: true,
+	})
+	l.Info("foo bar")
+	l.Infof("foo %s", "bar")
+	l.Info("something else")
+	lines := strings.Split(l.w.(*flushBuffer).String(), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Wrong number of lines, got: %d want: 4", len(lines))
+	}
+	want := " msg_hash=" + messageHash([]byte("foo bar"))
+	if !strings.HasSuffix(lines[0], want) || !strings.HasSuffix(lines[1], want) {
+		t.Errorf("Identical messages should have identical hashes: %q", lines[:2])
+	}
+	if strings.HasSuffix(lines[2], want) {
+		t.Errorf("Different messages should have different hashes: %q", lines[2])
+	}
+	if got := messageHash([]byte("foo bar")); len(got) != 8 {
+		t.Errorf("Hash should be 8 characters: %q", got)
+	}
+}
@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// HealthChecker may be implemented by a SyncWriter to report on its own
+// health, e.g. if it is unable to keep up, and is consulted by
+// Logger.Healthy.
+type HealthChecker interface {
+	// Healthy returns nil if the SyncWriter is working normally, or an error
+	// describing the problem otherwise.
+	Healthy() error
+}
+
+// Dropper may be implemented by a SyncWriter that drops writes, such as
+// AsyncWriter and ForwardWriter, to report how many, and is consulted by
+// Logger.DroppedWrites.
+type Dropper interface {
+	// Dropped returns the number of writes dropped.
+	Dropped() uint64
+}
+
+// recordWriteResult tracks the result of a write to the destination for Healthy.
+func (l *Logger) recordWriteResult(err error) {
+	if err == nil {
+		// Avoid taking the lock in the common case of every write succeeding.
+		if atomic.LoadInt32(&l.writeFailing) == 0 {
+			return
+		}
+		l.healthMu.Lock()
+		l.writeErr = nil
+		atomic.StoreInt32(&l.writeFailing, 0)
+		l.healthMu.Unlock()
+		return
+	}
+	l.healthMu.Lock()
+	l.writeErr = err
+	atomic.StoreInt32(&l.writeFailing, 1)
+	l.healthMu.Unlock()
+}
+
+// Healthy returns nil if logs are being written normally, and an error
+// otherwise, suitable for including in a service's health checks.
+//
+// The Logger is unhealthy if it has been shut down, with ErrShutdown, if
+// the most recent write to the destination failed, or if the destination
+// implements HealthChecker and reports itself as unhealthy.
+func (l *Logger) Healthy() error {
+	if atomic.LoadInt32(&l.shutdown) == 1 {
+		return ErrShutdown
+	}
+	if atomic.LoadInt32(&l.writeFailing) == 1 {
+		l.healthMu.Lock()
+		err := l.writeErr
+		l.healthMu.Unlock()
+		if err != nil {
+			return fmt.Errorf("writing logs: %w", err)
+		}
+	}
+	if hc, ok := l.writer().(HealthChecker); ok {
+		if err := hc.Healthy(); err != nil {
+			return fmt.Errorf("log destination: %w", err)
+		}
+	}
+	return nil
+}
+
+// DroppedWrites returns the number of writes the destination has dropped,
+// if it implements Dropper, or zero otherwise, e.g. to export as a metric.
+func (l *Logger) DroppedWrites() uint64 {
+	if d, ok := l.writer().(Dropper); ok {
+		return d.Dropped()
+	}
+	return 0
+}
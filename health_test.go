@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+// failingWriter is a SyncWriter whose writes fail while err is set.
+type failingWriter struct {
+	flushBuffer
+	err error
+}
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	return f.flushBuffer.Write(p)
+}
+
+func TestHealthy(t *testing.T) {
+	w := &failingWriter{}
+	l := NewFromOptions(&Options{SyncWriter: w})
+	l.Info("ok")
+	if err := l.Healthy(); err != nil {
+		t.Errorf("Expected healthy, got %s", err)
+	}
+
+	diskFull := errors.New("disk full")
+	w.err = diskFull
+	l.Info("fails")
+	if err := l.Healthy(); !errors.Is(err, diskFull) {
+		t.Errorf("Expected the write error, got %v", err)
+	}
+
+	w.err = nil
+	l.Info("recovered")
+	if err := l.Healthy(); err != nil {
+		t.Errorf("Expected healthy after recovering, got %s", err)
+	}
+
+	l.Shutdown(context.Background())
+	if err := l.Healthy(); !errors.Is(err, ErrShutdown) {
+		t.Errorf("Expected unhealthy after Shutdown, got %v", err)
+	}
+}
+
+func TestHealthyForwardWriter(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	w := NewForwardWriter(addr)
+	l := NewFromOptions(&Options{SyncWriter: w})
+	if err := l.Healthy(); err != nil {
+		t.Errorf("Expected healthy with nothing pending, got %s", err)
+	}
+	l.Info("nobody is listening")
+	if err := l.Healthy(); err == nil {
+		t.Error("Expected unhealthy with writes pending and no connection.")
+	}
+}
@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"bytes"
+	"sync"
+)
+
+// ANSI escape sequences used by the repeat highlighter.
+const (
+	ansiDim   = "\x1b[2m"
+	ansiBold  = "\x1b[1m"
+	ansiReset = "\x1b[0m"
+)
+
+// repeatHighlighter compares each message to the previous one, to make logs
+// from tight loops readable on a terminal. See Options.HighlightRepeats.
+type repeatHighlighter struct {
+	// mu protects prev.
+	mu sync.Mutex
+
+	// prev is the previous message.
+	prev []byte
+}
+
+// isSeparator returns true for the bytes that separate tokens.
+func isSeparator(c byte) bool {
+	return c == ' ' || c == '\n'
+}
+
+// tokens splits msg into runs of non-separator bytes.
+func tokens(msg []byte) [][]byte {
+	return bytes.FieldsFunc(msg, func(r rune) bool { return r == ' ' || r == '\n' })
+}
+
+// highlight writes msg to out with ANSI escapes added. If msg is identical
+// to the previous message it is dimmed entirely and highlight returns true.
+// If msg has the same number of tokens as the previous message, and some of
+// them are the same, those are dimmed and the ones that changed are made
+// bold.
+// Otherwise msg is written unchanged.
+func (r *repeatHighlighter) highlight(msg []byte, out *buffer) bool {
+	r.mu.Lock()
+	prev := r.prev
+	r.prev = append([]byte(nil), msg...)
+	r.mu.Unlock()
+	prevTokens := tokens(prev)
+
+	if bytes.Equal(prev, msg) {
+		out.WriteString(ansiDim)
+		out.Write(msg)
+		out.WriteString(ansiReset)
+		return true
+	}
+	msgTokens := tokens(msg)
+	if len(msgTokens) != len(prevTokens) || !anyEqual(msgTokens, prevTokens) {
+		out.Write(msg)
+		return false
+	}
+	i := 0
+	for start := 0; start < len(msg); {
+		if isSeparator(msg[start]) {
+			out.WriteByte(msg[start])
+			start++
+			continue
+		}
+		end := start
+		for end < len(msg) && !isSeparator(msg[end]) {
+			end++
+		}
+		if bytes.Equal(msg[start:end], prevTokens[i]) {
+			out.WriteString(ansiDim)
+		} else {
+			out.WriteString(ansiBold)
+		}
+		out.Write(msg[start:end])
+		out.WriteString(ansiReset)
+		i++
+		start = end
+	}
+	return false
+}
+
+// anyEqual returns true if a and b have an equal token at the same position.
+func anyEqual(a, b [][]byte) bool {
+	for i := range a {
+		if bytes.Equal(a[i], b[i]) {
+			return true
+		}
+	}
+	return false
+}
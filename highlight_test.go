@@ -0,0 +1,44 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlightRepeats(t *testing.T) {
+	r := &repeatHighlighter{}
+	highlight := func(msg string) (string, bool) {
+		buf := &buffer{}
+		repeat := r.highlight([]byte(msg), buf)
+		return buf.String(), repeat
+	}
+	if got, repeat := highlight("processed 1 items"); got != "processed 1 items" || repeat {
+		t.Errorf("First message should be unchanged: %q", got)
+	}
+	want := ansiDim + "processed" + ansiReset + " " + ansiBold + "2" + ansiReset + " " + ansiDim + "items" + ansiReset
+	if got, repeat := highlight("processed 2 items"); got != want || repeat {
+		t.Errorf("Got %q want %q", got, want)
+	}
+	if got, repeat := highlight("processed 2 items"); got != ansiDim+"processed 2 items"+ansiReset || !repeat {
+		t.Errorf("Repeat should be dimmed: %q", got)
+	}
+	if got, _ := highlight("something completely different"); got != "something completely different" {
+		t.Errorf("Different token counts should be unchanged: %q", got)
+	}
+}
+
+func TestHighlightRepeatsLogger(t *testing.T) {
+	l := NewFromOptions(&Options{
+		SyncWriter:       &flushBuffer{},
+		HighlightRepeats: true,
+	})
+	l.Info("tick")
+	l.Info("tick")
+	lines := strings.Split(l.w.(*flushBuffer).String(), "\n")
+	if strings.Contains(lines[0], ansiDim) {
+		t.Errorf("First line should not be dimmed: %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], ansiDim+"I") || !strings.HasSuffix(lines[1], ansiDim+"tick"+ansiReset) {
+		t.Errorf("Repeated line should be dimmed entirely: %q", lines[1])
+	}
+}
@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// redacted replaces sensitive values, matching url.URL.Redacted.
+const redacted = "xxxxx"
+
+// sensitiveHeaders are the request headers whose values are never logged.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	"Proxy-Authorization": true,
+	"X-Api-Key":           true,
+}
+
+// sensitiveParams are the substrings of query parameter names whose values
+// are never logged.
+var sensitiveParams = []string{"token", "key", "secret", "password", "signature", "auth"}
+
+// LoggingTransport is an http.RoundTripper that logs every outbound request
+// it sends, with the method, the URL, the response status, and the latency,
+// e.g.:
+//
+//	client := &http.Client{Transport: &logger.LoggingTransport{Logger: l}}
+//
+// Passwords in the URL and the values of query parameters that look like
+// credentials, such as "access_token" or "api_key", are replaced with
+// "xxxxx". Requests that fail without a response are logged as Warnings.
+// The file and line reported are those of the caller of RoundTrip, which is
+// usually within net/http.
+type LoggingTransport struct {
+	// Logger is where the requests are logged.
+	Logger *Logger
+
+	// Next sends the requests. If nil then http.DefaultTransport is used.
+	Next http.RoundTripper
+
+	// LogHeaders, if true, also logs the request headers, in a "header"
+	// Group. The values of the Authorization, Cookie, Proxy-Authorization,
+	// and X-Api-Key headers are replaced with "xxxxx".
+	LogHeaders bool
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *LoggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	start := t.Logger.timeNow()
+	resp, err := next.RoundTrip(req)
+	latency := t.Logger.timeNow().Sub(start)
+
+	fields := []Field{
+		Str("method", req.Method),
+		Str("url", sanitizeURL(req.URL)),
+	}
+	if t.LogHeaders {
+		fields = append(fields, headerFields(req.Header))
+	}
+	if err != nil {
+		fields = append(fields, Dur("latency", latency), Err(err))
+		t.Logger.logDepth(warningLog, 0, []byte("http request failed"), fields)
+		return resp, err
+	}
+	fields = append(fields, Int("status", resp.StatusCode), Dur("latency", latency))
+	t.Logger.logDepth(infoLog, 0, []byte("http request"), fields)
+	return resp, nil
+}
+
+// sanitizeURL returns u as a string with the password and the values of
+// sensitive query parameters redacted.
+func sanitizeURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	cp := *u
+	if cp.RawQuery != "" {
+		query := cp.Query()
+		for name, values := range query {
+			if isSensitiveParam(name) {
+				for i := range values {
+					values[i] = redacted
+				}
+			}
+		}
+		cp.RawQuery = query.Encode()
+	}
+	return cp.Redacted()
+}
+
+func isSensitiveParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range sensitiveParams {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// headerFields returns h as a "header" Group, with the keys sorted and the
+// values of sensitive headers redacted.
+func headerFields(h http.Header) Field {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fields := make([]Field, len(keys))
+	for i, k := range keys {
+		value := strings.Join(h[k], ",")
+		if sensitiveHeaders[http.CanonicalHeaderKey(k)] {
+			value = redacted
+		}
+		fields[i] = Str(k, value)
+	}
+	return Group("header", fields...)
+}
@@ -0,0 +1,348 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// tapBufferSize is the number of lines buffered for each tap before lines
+// start being dropped for that tap.
+const tapBufferSize = 1024
+
+// tapFilter selects the lines sent to a tap, see the Inspector's tail
+// command. A nil *tapFilter matches every line.
+type tapFilter struct {
+	// route matches the severity, file, name and fields of the entry.
+	route Route
+
+	// re, if not nil, matches the line.
+	re *regexp.Regexp
+}
+
+// parseTapFilter parses the terms of a filter, see Inspector. A filter
+// without terms is nil.
+func parseTapFilter(terms []string) (*tapFilter, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	f := &tapFilter{}
+	for _, term := range terms {
+		key, value, ok := strings.Cut(term, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("filter %q isn't of the form key=value", term)
+		}
+		switch key {
+		case "level":
+			s, err := ParseSeverity(value)
+			if err != nil {
+				return nil, err
+			}
+			f.route.MinSeverity = s
+		case "re":
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, err
+			}
+			f.re = re
+		case "file":
+			if _, err := path.Match(value, ""); err != nil {
+				return nil, fmt.Errorf("bad file pattern %q: %s", value, err)
+			}
+			f.route.File = value
+		case "name":
+			f.route.Name = value
+		default:
+			if f.route.Fields == nil {
+				f.route.Fields = map[string]string{}
+			}
+			f.route.Fields[key] = value
+		}
+	}
+	return f, nil
+}
+
+// matches returns true if line, of the entry described by e, should be
+// sent to the tap. re is matched without the trailing newline, so "$"
+// matches the end of the line.
+func (f *tapFilter) matches(e entryInfo, line []byte) bool {
+	if f == nil {
+		return true
+	}
+	return f.route.matches(e) && (f.re == nil || f.re.Match(bytes.TrimSuffix(line, []byte("\n"))))
+}
+
+// addTap returns a channel that receives a copy of every line written that
+// matches f.
+func (l *Logger) addTap(f *tapFilter) chan []byte {
+	c := make(chan []byte, tapBufferSize)
+	l.tapsMu.Lock()
+	defer l.tapsMu.Unlock()
+	if l.taps == nil {
+		l.taps = map[chan []byte]*tapFilter{}
+	}
+	l.taps[c] = f
+	atomic.StoreInt32(&l.numTaps, int32(len(l.taps)))
+	return c
+}
+
+// setTapFilter replaces the filter of the tap c with f.
+func (l *Logger) setTapFilter(c chan []byte, f *tapFilter) {
+	l.tapsMu.Lock()
+	defer l.tapsMu.Unlock()
+	if _, ok := l.taps[c]; ok {
+		l.taps[c] = f
+	}
+}
+
+// removeTap stops c from receiving lines and closes it.
+func (l *Logger) removeTap(c chan []byte) {
+	l.tapsMu.Lock()
+	defer l.tapsMu.Unlock()
+	if _, ok := l.taps[c]; !ok {
+		return
+	}
+	delete(l.taps, c)
+	close(c)
+	for line := range c {
+		l.releaseMemory(len(line))
+	}
+	atomic.StoreInt32(&l.numTaps, int32(len(l.taps)))
+}
+
+// tap sends a copy of line, of the entry described by e, to every tap
+// whose filter it matches. Taps that aren't keeping up miss lines rather
+// than slowing down logging.
+func (l *Logger) tap(e entryInfo, line []byte) {
+	if atomic.LoadInt32(&l.numTaps) == 0 {
+		return
+	}
+	var cp []byte
+	l.tapsMu.Lock()
+	defer l.tapsMu.Unlock()
+	for c, f := range l.taps {
+		if !f.matches(e, line) {
+			continue
+		}
+		if cp == nil {
+			cp = append([]byte(nil), line...)
+		}
+		if !l.reserveMemory(len(cp)) {
+			l.dropMemory()
+			continue
+		}
+		select {
+		case c <- cp:
+		default:
+			l.releaseMemory(len(cp))
+		}
+	}
+}
+
+// Inspector serves a Logger's state over a unix domain socket, for debugging
+// processes that don't expose an HTTP port.
+//
+// The protocol is line based, so any client that can talk to a unix socket,
+// such as "nc -U" or "socat", can be used. The commands are:
+//
+//	state        Prints the current settings of the Logger.
+//	debug on     Starts emitting Debug logs.
+//	debug off    Stops emitting Debug logs.
+//	tail [filter]  Streams every log line matching filter until the
+//	               connection is closed. While streaming, sending
+//	               "filter [filter]" replaces the filter, answered by
+//	               "ok" or an error in the stream.
+//
+// A filter is a space separated list of terms, all of which must match,
+// which is evaluated before lines are sent so that tailing a busy process
+// over a slow link only sends the lines of interest:
+//
+//	level=<severity>  Entries at or above the severity, e.g. level=warning.
+//	re=<regexp>       Lines matching the regular expression, which can't
+//	                  contain spaces, so use \s.
+//	file=<pattern>    Entries from files whose base names match the
+//	                  path.Match pattern, e.g. file=http_*.go.
+//	name=<name>       Entries of the Logger with the name, see Named, and
+//	                  of those Named from it.
+//	<key>=<value>     Entries with a top level field of key whose value
+//	                  renders as value, e.g. user=alice.
+//
+// For example "tail level=error name=billing" streams the Errors and
+// Fatals of the "billing" Logger.
+type Inspector struct {
+	l    *Logger
+	ln   net.Listener
+	path string
+
+	// wg tracks the goroutines serving connections.
+	wg sync.WaitGroup
+
+	// conns are the open connections, maintained under connsMu.
+	conns   map[net.Conn]struct{}
+	connsMu sync.Mutex
+}
+
+// NewInspector starts serving l over a unix domain socket at path. Any
+// existing file at path is removed first.
+func NewInspector(l *Logger, path string) (*Inspector, error) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("removing stale socket: %s", err)
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	i := &Inspector{
+		l:     l,
+		ln:    ln,
+		path:  path,
+		conns: map[net.Conn]struct{}{},
+	}
+	i.wg.Add(1)
+	go i.serve()
+	return i, nil
+}
+
+// Close stops the Inspector, closing all open connections and removing the socket.
+func (i *Inspector) Close() error {
+	err := i.ln.Close()
+	i.connsMu.Lock()
+	for c := range i.conns {
+		c.Close()
+	}
+	i.connsMu.Unlock()
+	i.wg.Wait()
+	os.Remove(i.path)
+	return err
+}
+
+func (i *Inspector) serve() {
+	defer i.wg.Done()
+	for {
+		c, err := i.ln.Accept()
+		if err != nil {
+			return
+		}
+		i.connsMu.Lock()
+		i.conns[c] = struct{}{}
+		i.connsMu.Unlock()
+		i.wg.Add(1)
+		go i.handle(c)
+	}
+}
+
+func (i *Inspector) handle(c net.Conn) {
+	defer i.wg.Done()
+	defer func() {
+		i.connsMu.Lock()
+		delete(i.conns, c)
+		i.connsMu.Unlock()
+		c.Close()
+	}()
+	scanner := bufio.NewScanner(c)
+	for scanner.Scan() {
+		cmd := strings.Fields(scanner.Text())
+		if len(cmd) == 0 {
+			continue
+		}
+		switch cmd[0] {
+		case "state":
+			i.writeState(c)
+		case "debug":
+			if len(cmd) != 2 || (cmd[1] != "on" && cmd[1] != "off") {
+				fmt.Fprintln(c, "error: usage: debug on|off")
+				continue
+			}
+			i.l.SetIncludeDebug(cmd[1] == "on")
+			fmt.Fprintln(c, "ok")
+		case "tail":
+			f, err := parseTapFilter(cmd[1:])
+			if err != nil {
+				fmt.Fprintf(c, "error: %s\n", err)
+				continue
+			}
+			i.tail(c, scanner, f)
+			return
+		default:
+			fmt.Fprintf(c, "error: unknown command %q\n", cmd[0])
+		}
+	}
+}
+
+func (i *Inspector) writeState(w io.Writer) {
+	l := i.l
+	l.freeListMu.Lock()
+	freeBuffers := l.bufferCacheLen()
+	l.freeListMu.Unlock()
+	fmt.Fprintf(w, "include_debug: %v\n", l.IncludeDebug())
+	fmt.Fprintf(w, "depth_delta: %d\n", l.depthDelta)
+	fmt.Fprintf(w, "max_line_length: %d\n", l.maxLineLength)
+	fmt.Fprintf(w, "message_hash: %v\n", l.messageHash)
+	fmt.Fprintf(w, "writer: %T\n", l.writer())
+	fmt.Fprintf(w, "lines_written: %d\n", atomic.LoadUint64(&l.linesWritten))
+	fmt.Fprintf(w, "free_buffers: %d\n", freeBuffers)
+	fmt.Fprintf(w, "tails: %d\n", atomic.LoadInt32(&l.numTaps))
+	mem := l.MemoryStats()
+	fmt.Fprintf(w, "memory_used: %d\n", mem.Used)
+	fmt.Fprintf(w, "memory_max: %d\n", mem.Max)
+	fmt.Fprintf(w, "memory_dropped: %d\n", mem.Dropped)
+	fmt.Fprintln(w, "ok")
+}
+
+// tail copies lines matching f to c, reading filter commands from scanner,
+// until either c is closed or the Inspector is closed.
+func (i *Inspector) tail(c net.Conn, scanner *bufio.Scanner, f *tapFilter) {
+	lines := i.l.addTap(f)
+	defer i.l.removeTap(lines)
+
+	// Read commands until the client goes away, which is detected by the
+	// read failing.
+	cmds := make(chan []string)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		defer close(done)
+		for scanner.Scan() {
+			select {
+			case cmds <- strings.Fields(scanner.Text()):
+			case <-stopped:
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case line := <-lines:
+			i.l.releaseMemory(len(line))
+			if _, err := c.Write(line); err != nil {
+				return
+			}
+		case cmd := <-cmds:
+			if len(cmd) == 0 {
+				continue
+			}
+			if cmd[0] != "filter" {
+				fmt.Fprintf(c, "error: unknown command %q while tailing\n", cmd[0])
+				continue
+			}
+			f, err := parseTapFilter(cmd[1:])
+			if err != nil {
+				fmt.Fprintf(c, "error: %s\n", err)
+				continue
+			}
+			i.l.setTapFilter(lines, f)
+			fmt.Fprintln(c, "ok")
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package logger
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestInspector(t *testing.T) (*Logger, *Inspector, net.Conn, *bufio.Reader) {
+	l := NewFromOptions(&Options{
+		SyncWriter: &flushBuffer{},
+	})
+	i, err := NewInspector(l, filepath.Join(t.TempDir(), "inspect.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := net.Dial("unix", i.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l, i, c, bufio.NewReader(c)
+}
+
+// readUntilOk returns all the lines read up to and including "ok".
+func readUntilOk(t *testing.T, r *bufio.Reader) string {
+	ret := ""
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		ret += line
+		if line == "ok\n" || strings.HasPrefix(line, "error:") {
+			return ret
+		}
+	}
+}
+
+func TestInspectorStateAndDebug(t *testing.T) {
+	l, i, c, r := newTestInspector(t)
+	defer i.Close()
+
+	fmt.Fprintln(c, "state")
+	if state := readUntilOk(t, r); !strings.Contains(state, "include_debug: false\n") {
+		t.Errorf("Wrong state: %q", state)
+	}
+
+	fmt.Fprintln(c, "debug on")
+	if resp := readUntilOk(t, r); resp != "ok\n" {
+		t.Errorf("Wrong response: %q", resp)
+	}
+	if !l.IncludeDebug() {
+		t.Error("Debug should have been turned on.")
+	}
+
+	fmt.Fprintln(c, "debug sideways")
+	if resp := readUntilOk(t, r); !strings.HasPrefix(resp, "error:") {
+		t.Errorf("Expected an error: %q", resp)
+	}
+
+	fmt.Fprintln(c, "bogus")
+	if resp := readUntilOk(t, r); !strings.HasPrefix(resp, "error:") {
+		t.Errorf("Expected an error: %q", resp)
+	}
+}
+
+func TestInspectorTail(t *testing.T) {
+	l, i, c, r := newTestInspector(t)
+	defer i.Close()
+
+	fmt.Fprintln(c, "tail")
+	// Wait for the tap to be registered.
+	for atomic.LoadInt32(&l.numTaps) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	l.Info("hello")
+	line, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(line, "] hello\n") {
+		t.Errorf("Wrong tailed line: %q", line)
+	}
+
+	c.Close()
+	for atomic.LoadInt32(&l.numTaps) != 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestInspectorTailFilter(t *testing.T) {
+	l, i, c, r := newTestInspector(t)
+	defer i.Close()
+
+	fmt.Fprintln(c, "tail level=bogus")
+	if resp := readUntilOk(t, r); !strings.HasPrefix(resp, "error:") {
+		t.Errorf("Expected an error: %q", resp)
+	}
+
+	fmt.Fprintln(c, "tail level=warning user=alice")
+	for atomic.LoadInt32(&l.numTaps) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	l.WarningFields("wrong user", Str("user", "bob"))
+	l.InfoFields("too low", Str("user", "alice"))
+	l.With(Str("user", "alice")).Error("shown")
+	line, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(line, "] shown user=alice\n") {
+		t.Errorf("Wrong tailed line: %q", line)
+	}
+
+	fmt.Fprintln(c, `filter re=^I.*\sok$ name=db`)
+	if resp := readUntilOk(t, r); resp != "ok\n" {
+		t.Fatalf("Wrong response to filter: %q", resp)
+	}
+	l.Info("not ok")
+	l.Named("db").Warning("ok")
+	l.Named("db").Info("ok")
+	line, err = r.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line[0] != 'I' || !strings.HasSuffix(line, "] db: ok\n") {
+		t.Errorf("Wrong tailed line: %q", line)
+	}
+
+	fmt.Fprintln(c, "filter re=(")
+	if resp := readUntilOk(t, r); !strings.HasPrefix(resp, "error:") {
+		t.Errorf("Expected an error: %q", resp)
+	}
+	fmt.Fprintln(c, "state")
+	if resp := readUntilOk(t, r); !strings.HasPrefix(resp, "error:") {
+		t.Errorf("Expected an error: %q", resp)
+	}
+}
+
+func TestParseTapFilter(t *testing.T) {
+	if f, err := parseTapFilter(nil); f != nil || err != nil {
+		t.Errorf("Got %v %v for no terms", f, err)
+	}
+	f, err := parseTapFilter([]string{"level=ERROR", "file=http_*.go", "k=v", "re=x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := entryInfo{s: errorLog, file: "http_server.go", fields: []Field{Str("k", "v")}}
+	if !f.matches(e, []byte("x")) {
+		t.Error("Filter doesn't match")
+	}
+	for _, bad := range []entryInfo{
+		{s: warningLog, file: "http_server.go", fields: []Field{Str("k", "v")}},
+		{s: errorLog, file: "main.go", fields: []Field{Str("k", "v")}},
+		{s: errorLog, file: "http_server.go", fields: []Field{Str("k", "w")}},
+	} {
+		if f.matches(bad, []byte("x")) {
+			t.Errorf("Filter matches %+v", bad)
+		}
+	}
+	if f.matches(e, []byte("y")) {
+		t.Error("Filter matches a line not matching re")
+	}
+	for _, bad := range []string{"level", "=x", "file=[", "re=("} {
+		if _, err := parseTapFilter([]string{bad}); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
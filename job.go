@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Job tracks a run of a batch job, such as a cron job, counting the entries
+// of each severity logged during the run, so a single summary entry can be
+// written at the end:
+//
+//	job := l.StartJob("nightly-import")
+//	jl := job.Logger()
+//	for _, r := range records {
+//		if err := process(r); err != nil {
+//			jl.Errorf("processing %s: %s", r.ID, err)
+//		}
+//		job.AddItems(1)
+//	}
+//	job.End()
+//
+// which streams the Error lines as usual and then writes:
+//
+//	nightly-import finished job=nightly-import duration=1m2.5s items=10000 warnings=0 errors=3
+type Job struct {
+	l     *Logger
+	name  string
+	start time.Time
+
+	// counts are the number of entries logged of each severity, accessed
+	// atomically.
+	counts [fatalLog + 1]uint64
+
+	// items is the number of items processed, accessed atomically.
+	items int64
+
+	// ended is 1 once End has been called, accessed atomically.
+	ended int32
+}
+
+// StartJob starts tracking a run of the named job.
+func (l *Logger) StartJob(name string) *Job {
+	j := &Job{name: name, start: l.timeNow()}
+	cp := *l
+	cp.job = j
+	j.l = &cp
+	return j
+}
+
+// Logger returns the Logger whose entries are counted by the Job, along
+// with those of any Loggers derived from it by With.
+func (j *Job) Logger() *Logger {
+	return j.l
+}
+
+// AddItems records that n more items have been processed.
+func (j *Job) AddItems(n int64) {
+	atomic.AddInt64(&j.items, n)
+}
+
+// count records an entry of severity s.
+func (j *Job) count(s severity) {
+	atomic.AddUint64(&j.counts[s.base()], 1)
+}
+
+// End writes the summary of the run, as an Error if any Errors were logged
+// and as Info otherwise. Only the first call to End logs anything.
+func (j *Job) End() {
+	if !atomic.CompareAndSwapInt32(&j.ended, 0, 1) {
+		return
+	}
+	errors := atomic.LoadUint64(&j.counts[errorLog])
+	fields := []Field{
+		Str("job", j.name),
+		Dur("duration", j.l.timeNow().Sub(j.start)),
+		Int64("items", atomic.LoadInt64(&j.items)),
+		Int64("warnings", int64(atomic.LoadUint64(&j.counts[warningLog]))),
+		Int64("errors", int64(errors)),
+	}
+	s := infoLog
+	if errors > 0 {
+		s = errorLog
+	}
+	// Don't count the summary itself.
+	cp := *j.l
+	cp.job = nil
+	cp.logDepth(s, 0, []byte(j.name+" finished"), fields)
+}
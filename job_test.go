@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJob(t *testing.T) {
+	defer func(now func() time.Time) { timeNow = now }(timeNow)
+	start := time.Now()
+	timeNow = func() time.Time { return start }
+
+	newTestLogger()
+	job := testLogger.StartJob("import")
+	jl := job.Logger()
+	jl.Info("starting")
+	jl.With("record", 7).Warning("skipped")
+	jl.Error("failed")
+	jl.Error("failed again")
+	testLogger.Error("not part of the job")
+	job.AddItems(10)
+	job.AddItems(5)
+	timeNow = func() time.Time { return start.Add(90 * time.Second) }
+	job.End()
+	job.End()
+
+	lines := strings.Split(strings.TrimSpace(contents()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("Wrong number of lines: %q", lines)
+	}
+	summary := lines[5]
+	if !strings.HasPrefix(summary, "E") || !strings.HasSuffix(summary, "] import finished job=import duration=1m30s items=15 warnings=1 errors=2") {
+		t.Errorf("Wrong summary: %q", summary)
+	}
+	if !strings.Contains(summary, " job_test.go:") {
+		t.Errorf("Wrong caller: %q", summary)
+	}
+}
+
+func TestJobWithoutErrors(t *testing.T) {
+	newTestLogger()
+	job := testLogger.StartJob("cleanup")
+	job.Logger().Warning("nothing to do")
+	job.End()
+	if !strings.HasPrefix(strings.Split(contents(), "\n")[1], "I") || !contains(" warnings=1 errors=0\n", t) {
+		t.Errorf("Wrong summary: %q", contents())
+	}
+}
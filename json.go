@@ -0,0 +1,466 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// Format selects how log entries are written.
+type Format int
+
+const (
+	// TextFormat writes each entry as a glog style header followed by the
+	// message and any fields as key=value pairs.
+	TextFormat Format = iota
+
+	// JSONFormat writes each entry as a single line JSON object, e.g.:
+	//
+	//	{"schema_version":2,"severity":"INFO","timestamp":"2006-01-02T15:04:05.067890Z","pid":1234,"caller":{"file":"main.go","line":10,"function":"serve","package":"example.com/server"},"message":"served","path":"/index.html"}
+	//
+	// The "caller" object's function and package are those of the calling
+	// function, and are left out if they're unknown, e.g. for WithCaller.
+	// Fields follow the message as members of the object, with Groups as
+	// nested objects. Multi-line messages are kept in a single entry, and
+	// are never split to fit Options.MaxLineLength. Fatal entries have a
+	// "stack" member holding the stack traces of all goroutines. The
+	// standard members can be renamed with Options.EncoderConfig.
+	JSONFormat
+)
+
+// JSONSchemaVersion is the version of the set of members written in each
+// JSONFormat entry, which is written as its "schema_version" member. It is
+// incremented whenever members are added, renamed, or change meaning, with
+// a converter added to UpgradeJSONEntry, so pipelines can migrate safely.
+//
+// Version 1 added schema_version itself. Entries without it are version 0.
+// Version 2 moved the "file" and "line" members into the "caller" object.
+const JSONSchemaVersion = 2
+
+// jsonUpgrades converts an entry of schema version i to version i+1.
+var jsonUpgrades = []func(entry map[string]interface{}){
+	// Version 1 only added schema_version.
+	func(entry map[string]interface{}) {},
+	// Version 2 moved file and line into caller.
+	func(entry map[string]interface{}) {
+		caller := map[string]interface{}{}
+		for _, key := range []string{"file", "line"} {
+			if v, ok := entry[key]; ok {
+				caller[key] = v
+				delete(entry, key)
+			}
+		}
+		entry["caller"] = caller
+	},
+}
+
+// UpgradeJSONEntry converts entry, a JSONFormat entry decoded with
+// encoding/json, from the schema version it was written with to
+// JSONSchemaVersion, in place.
+func UpgradeJSONEntry(entry map[string]interface{}) error {
+	version := 0
+	if v, ok := entry["schema_version"]; ok {
+		f, ok := v.(float64)
+		if !ok || f != math.Trunc(f) || f < 0 {
+			return fmt.Errorf("invalid schema_version %v", v)
+		}
+		version = int(f)
+	}
+	if version > JSONSchemaVersion {
+		return fmt.Errorf("schema_version %d is newer than %d", version, JSONSchemaVersion)
+	}
+	for ; version < JSONSchemaVersion; version++ {
+		jsonUpgrades[version](entry)
+	}
+	entry["schema_version"] = float64(JSONSchemaVersion)
+	return nil
+}
+
+// EncoderConfig renames the standard members of JSONFormat entries, so they
+// match the schema an existing pipeline expects without post-processing,
+// e.g. {SeverityKey: "level", TimestampKey: "ts", MessageKey: "msg"}. An
+// empty name keeps the default. "schema_version" can't be renamed, and
+// entries with renamed members aren't understood by UpgradeJSONEntry or
+// CaptureLogs.
+type EncoderConfig struct {
+	// SeverityKey replaces "severity".
+	SeverityKey string
+
+	// TimestampKey replaces "timestamp".
+	TimestampKey string
+
+	// PIDKey replaces "pid".
+	PIDKey string
+
+	// CallerKey replaces "caller".
+	CallerKey string
+
+	// MessageKey replaces "message".
+	MessageKey string
+
+	// StackKey replaces "stack", which holds the stack traces of Fatal
+	// entries.
+	StackKey string
+}
+
+// withDefaults returns c with the empty names replaced by the defaults.
+func (c EncoderConfig) withDefaults() EncoderConfig {
+	for _, k := range []struct {
+		name *string
+		def  string
+	}{
+		{&c.SeverityKey, "severity"},
+		{&c.TimestampKey, "timestamp"},
+		{&c.PIDKey, "pid"},
+		{&c.CallerKey, "caller"},
+		{&c.MessageKey, "message"},
+		{&c.StackKey, "stack"},
+	} {
+		if *k.name == "" {
+			*k.name = k.def
+		}
+	}
+	return c
+}
+
+// jsonTimeLayouts are the layouts of the JSON timestamp, indexed by
+// TimePrecision.
+var jsonTimeLayouts = []string{
+	MicrosecondPrecision: "2006-01-02T15:04:05.000000Z07:00",
+	SecondPrecision:      "2006-01-02T15:04:05Z07:00",
+	MillisecondPrecision: "2006-01-02T15:04:05.000Z07:00",
+	NanosecondPrecision:  "2006-01-02T15:04:05.000000000Z07:00",
+}
+
+// jsonHeader returns a buffer holding the start of a JSON entry, up to and
+// including the "message" key. pc is that of the calling function, or 0 if
+// it's unknown.
+func (l *Logger) jsonHeader(s severity, now time.Time, pid int, file string, line int, pc uintptr) *buffer {
+	layout := jsonTimeLayouts[MicrosecondPrecision]
+	if int(l.timePrecision) < len(jsonTimeLayouts) {
+		layout = jsonTimeLayouts[l.timePrecision]
+	}
+	keys := &l.jsonKeys
+	buf := l.getBuffer()
+	buf.WriteString(`{"schema_version":`)
+	buf.Write(strconv.AppendInt(buf.tmp[:0], JSONSchemaVersion, 10))
+	appendJSONKey(buf, keys.SeverityKey)
+	appendJSONString(buf, s.name())
+	appendJSONKey(buf, keys.TimestampKey)
+	buf.WriteByte('"')
+	buf.Write(now.AppendFormat(buf.tmp[:0], layout))
+	buf.WriteByte('"')
+	appendJSONKey(buf, keys.PIDKey)
+	buf.Write(strconv.AppendInt(buf.tmp[:0], int64(pid), 10))
+	appendJSONKey(buf, keys.CallerKey)
+	buf.WriteString(`{"file":`)
+	appendJSONString(buf, file)
+	buf.WriteString(`,"line":`)
+	buf.Write(strconv.AppendInt(buf.tmp[:0], int64(line), 10))
+	if pkg, function := funcName(pc); function != "" {
+		buf.WriteString(`,"function":`)
+		appendJSONString(buf, function)
+		buf.WriteString(`,"package":`)
+		appendJSONString(buf, pkg)
+	}
+	buf.WriteByte('}')
+	appendJSONKey(buf, keys.MessageKey)
+	return buf
+}
+
+// appendJSONKey writes key to buf as the name of a member that follows
+// another, i.e. as ,"key":.
+func appendJSONKey(buf *buffer, key string) {
+	buf.WriteByte(',')
+	appendJSONString(buf, key)
+	buf.WriteByte(':')
+}
+
+// funcName returns the import path of the package of the function holding
+// pc, and the function's name within it, e.g. "example.com/server" and
+// "(*Server).serve". It returns empty strings if pc is 0 or unknown.
+func funcName(pc uintptr) (pkg, function string) {
+	if pc == 0 {
+		return "", ""
+	}
+	f := runtime.FuncForPC(pc)
+	if f == nil {
+		return "", ""
+	}
+	name := f.Name()
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return "", name
+	}
+	return name[:slash+1+dot], name[slash+1+dot+1:]
+}
+
+// emitJSON writes out the message in buf, along with any fields, as a JSON
+// entry that starts with header.
+func (l *Logger) emitJSON(s severity, buf, header *buffer, fields []Field) {
+	msg := buf.Bytes()
+	for len(msg) > 0 && msg[len(msg)-1] == '\n' {
+		msg = msg[:len(msg)-1]
+	}
+	out := l.getBuffer()
+	defer l.putBuffer(out)
+	out.Write(header.Bytes())
+	appendJSONString(out, string(msg))
+	for _, f := range l.stampFields {
+		f.appendJSON(out, true)
+	}
+	for _, f := range fields {
+		f.appendJSON(out, true)
+	}
+	if l.messageHash {
+		Str("msg_hash", messageHash(buf.Bytes())).appendJSON(out, true)
+	}
+	if s == fatalLog && !header.record {
+		appendJSONKey(out, l.jsonKeys.StackKey)
+		l.stacks(func(trace []byte) {
+			appendJSONString(out, string(trace))
+		})
+	}
+	out.WriteString("}\n")
+
+	e := l.entryInfo(s, header, fields)
+	l.write(e, out.Bytes())
+	atomic.AddUint64(&l.linesWritten, 1)
+	l.tap(e, out.Bytes())
+}
+
+// appendJSON writes the field to buf as "key":value, preceded by a comma if
+// sep is true. Groups are written as nested objects, or have their fields
+// inlined if their key is empty. It returns false if nothing was written,
+// which is the case for a Group with no fields.
+func (f Field) appendJSON(buf *buffer, sep bool) bool {
+	if f.t == groupField {
+		children := f.iface.([]Field)
+		if len(children) == 0 {
+			return false
+		}
+		if f.Key == "" {
+			wrote := false
+			for _, child := range children {
+				if child.appendJSON(buf, sep || wrote) {
+					wrote = true
+				}
+			}
+			return wrote
+		}
+		if sep {
+			buf.WriteByte(',')
+		}
+		appendJSONString(buf, f.Key)
+		buf.WriteString(":{")
+		wrote := false
+		for _, child := range children {
+			if child.appendJSON(buf, wrote) {
+				wrote = true
+			}
+		}
+		buf.WriteByte('}')
+		return true
+	}
+
+	if sep {
+		buf.WriteByte(',')
+	}
+	appendJSONString(buf, f.Key)
+	buf.WriteByte(':')
+	switch f.t {
+	case stringField:
+		appendJSONString(buf, f.str)
+	case int64Field:
+		buf.Write(strconv.AppendInt(buf.tmp[:0], f.num, 10))
+	case float64Field:
+		v := math.Float64frombits(uint64(f.num))
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			// Not representable as a JSON number.
+			appendJSONString(buf, strconv.FormatFloat(v, 'g', -1, 64))
+		} else {
+			buf.Write(strconv.AppendFloat(buf.tmp[:0], v, 'g', -1, 64))
+		}
+	case boolField:
+		buf.Write(strconv.AppendBool(buf.tmp[:0], f.num == 1))
+	case durationField:
+		appendJSONString(buf, time.Duration(f.num).String())
+	case timeField:
+		buf.WriteByte('"')
+		buf.Write(f.time().AppendFormat(buf.tmp[:0], time.RFC3339Nano))
+		buf.WriteByte('"')
+	case errorField:
+		if f.iface == nil {
+			buf.WriteString("null")
+			break
+		}
+		appendJSONString(buf, f.iface.(error).Error())
+	default:
+		appendJSONAny(buf, reflect.ValueOf(f.iface), 0)
+	}
+	return true
+}
+
+// appendJSONAny writes rv to buf as JSON, for an Any field. Slices, arrays,
+// and maps are walked with the caps of renderAny: only the first
+// maxCollectionElements elements are written, followed by a "...+N"
+// element, or a "...":"+N" member for maps, noting how many were left out,
+// and those nested deeper than maxCollectionDepth are written as "[...]"
+// or "{...}". Map keys are rendered as by renderAny, and sorted. Other
+// values are written by encoding/json, or rendered as by renderAny into a
+// string if it fails.
+func appendJSONAny(buf *buffer, rv reflect.Value, depth int) {
+	switch rv.Kind() {
+	case reflect.Invalid:
+		buf.WriteString("null")
+		return
+	case reflect.Interface, reflect.Ptr:
+		if rv.IsNil() {
+			buf.WriteString("null")
+			return
+		}
+		if k := rv.Elem().Kind(); rv.Kind() == reflect.Interface || k == reflect.Slice || k == reflect.Array || k == reflect.Map {
+			appendJSONAny(buf, rv.Elem(), depth)
+			return
+		}
+	case reflect.Slice, reflect.Map, reflect.Chan, reflect.Func:
+		if rv.IsNil() {
+			buf.WriteString("null")
+			return
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			// Leave []byte to encoding/json, as base64.
+			break
+		}
+		if depth >= maxCollectionDepth {
+			appendJSONString(buf, "[...]")
+			return
+		}
+		buf.WriteByte('[')
+		n := rv.Len()
+		for i := 0; i < n && i < maxCollectionElements; i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			appendJSONAny(buf, rv.Index(i), depth+1)
+		}
+		if n > maxCollectionElements {
+			buf.WriteByte(',')
+			appendJSONString(buf, "...+"+strconv.Itoa(n-maxCollectionElements))
+		}
+		buf.WriteByte(']')
+		return
+	case reflect.Map:
+		if depth >= maxCollectionDepth {
+			appendJSONString(buf, "{...}")
+			return
+		}
+		type kv struct {
+			key   string
+			value reflect.Value
+		}
+		pairs := make([]kv, 0, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			var key strings.Builder
+			renderValue(&key, iter.Key(), depth+1)
+			pairs = append(pairs, kv{key: key.String(), value: iter.Value()})
+		}
+		sort.Slice(pairs, func(i, j int) bool { return pairs[i].key < pairs[j].key })
+		buf.WriteByte('{')
+		for i, p := range pairs {
+			if i == maxCollectionElements {
+				break
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			appendJSONString(buf, p.key)
+			buf.WriteByte(':')
+			appendJSONAny(buf, p.value, depth+1)
+		}
+		if len(pairs) > maxCollectionElements {
+			buf.WriteString(`,"...":`)
+			appendJSONString(buf, "+"+strconv.Itoa(len(pairs)-maxCollectionElements))
+		}
+		buf.WriteByte('}')
+		return
+	}
+
+	v := rv.Interface()
+	b, err := json.Marshal(v)
+	if err != nil {
+		appendJSONString(buf, renderAny(v))
+		return
+	}
+	buf.Write(b)
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString writes s to buf as a JSON string, replacing invalid UTF-8
+// with U+FFFD in the manner of encoding/json.
+func appendJSONString(buf *buffer, s string) {
+	buf.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' {
+				i++
+				continue
+			}
+			buf.WriteString(s[start:i])
+			switch b {
+			case '"', '\\':
+				buf.WriteByte('\\')
+				buf.WriteByte(b)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			default:
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hexDigits[b>>4])
+				buf.WriteByte(hexDigits[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\ufffd`)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 and U+2029 are valid JSON but break JavaScript parsers.
+		if r == '\u2028' || r == '\u2029' {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\u202`)
+			buf.WriteByte(hexDigits[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
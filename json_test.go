@@ -0,0 +1,194 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newJSONLogger(o *Options) *Logger {
+	o.SyncWriter = &flushBuffer{}
+	o.Format = JSONFormat
+	o.Now = func() time.Time { return time.Date(2006, 1, 2, 15, 4, 5, 67890123, time.UTC) }
+	o.PID = 1234
+	return NewFromOptions(o)
+}
+
+func TestJSONFormat(t *testing.T) {
+	l := newJSONLogger(&Options{})
+	l.InfoFields("served\n", Str("path", "/index.html"), Int("status", 200), Group("http", Bool("tls", true)))
+	got := l.w.(*flushBuffer).String()
+	if !strings.HasPrefix(got, `{"schema_version":2,"severity":"INFO","timestamp":"2006-01-02T15:04:05.067890Z","pid":1234,"caller":{"file":"json_test.go","line":`) {
+		t.Errorf("Wrong header: %q", got)
+	}
+	if !strings.Contains(got, `,"function":"TestJSONFormat","package":"github.com/jcgregorio/logger"},"message":`) {
+		t.Errorf("Wrong caller: %q", got)
+	}
+	if !strings.HasSuffix(got, `,"message":"served","path":"/index.html","status":200,"http":{"tls":true}}`+"\n") {
+		t.Errorf("Wrong message and fields: %q", got)
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(got), &entry); err != nil {
+		t.Errorf("Not valid JSON: %s", err)
+	}
+}
+
+func TestJSONEncoderConfig(t *testing.T) {
+	l := newJSONLogger(&Options{
+		EncoderConfig: EncoderConfig{SeverityKey: "level", TimestampKey: "ts", CallerKey: "src", MessageKey: "msg", StackKey: "trace"},
+		Exit:          func(int) {},
+	})
+	l.InfoFields("served", Int("status", 200))
+	l.Fatal("goodbye")
+	lines := strings.Split(strings.TrimSuffix(l.w.(*flushBuffer).String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected one line per entry, got %q", lines)
+	}
+	if !strings.HasPrefix(lines[0], `{"schema_version":2,"level":"INFO","ts":"2006-01-02T15:04:05.067890Z","pid":1234,"src":{"file":"json_test.go",`) {
+		t.Errorf("Wrong header: %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[0], `},"msg":"served","status":200}`) {
+		t.Errorf("Wrong message and fields: %q", lines[0])
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatalf("Not valid JSON: %s", err)
+	}
+	if trace, _ := entry["trace"].(string); entry["msg"] != "goodbye" || !strings.Contains(trace, "goroutine ") {
+		t.Errorf("Wrong fatal entry: %q", lines[1])
+	}
+}
+
+func TestJSONMultiLineAndFatal(t *testing.T) {
+	l := newJSONLogger(&Options{Exit: func(int) {}})
+	l.Error("first\nsecond")
+	l.Fatal("goodbye")
+	lines := strings.Split(strings.TrimSuffix(l.w.(*flushBuffer).String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected one line per entry, got %q", lines)
+	}
+	var entry struct {
+		Message string
+		Stack   string
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil || entry.Message != "first\nsecond" {
+		t.Errorf("Wrong multi-line entry %q: %v", lines[0], err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil || !strings.Contains(entry.Stack, "goroutine ") {
+		t.Errorf("Fatal entry missing stack %q: %v", lines[1], err)
+	}
+}
+
+func TestJSONFieldValues(t *testing.T) {
+	ts := time.Date(2006, 1, 2, 15, 4, 5, 67890, time.UTC)
+	tests := []struct {
+		field Field
+		want  string
+	}{
+		{Str("s", "quote \" and \\ and \x01"), `,"s":"quote \" and \\ and \u0001"`},
+		{Str("s", "bad \xff utf8"), `,"s":"bad \ufffd utf8"`},
+		{Float64("f", 1.5), `,"f":1.5`},
+		{Float64("f", math.Inf(1)), `,"f":"+Inf"`},
+		{Dur("d", 1500*time.Millisecond), `,"d":"1.5s"`},
+		{Time("t", ts), `,"t":"2006-01-02T15:04:05.00006789Z"`},
+		{Time("t", time.Time{}), `,"t":"0001-01-01T00:00:00Z"`},
+		{Err(errors.New("it broke")), `,"error":"it broke"`},
+		{Err(nil), `,"error":null`},
+		{Any("a", map[string]int{"b": 1}), `,"a":{"b":1}`},
+		{Any("a", map[int]chan int{1: nil}), `,"a":{"1":null}`},
+		{Any("a", &[]int{1}), `,"a":[1]`},
+		{Any("a", []byte("hi")), `,"a":"aGk="`},
+		{Group("", Int("inlined", 1)), `,"inlined":1`},
+		{Group("empty"), ``},
+		{Group("g", Group("empty"), Int("n", 1)), `,"g":{"n":1}`},
+	}
+	for _, tc := range tests {
+		buf := &buffer{}
+		tc.field.appendJSON(buf, true)
+		if got := buf.String(); got != tc.want {
+			t.Errorf("Got %q want %q", got, tc.want)
+		}
+	}
+}
+
+func TestJSONAnyCaps(t *testing.T) {
+	render := func(v interface{}) string {
+		buf := &buffer{}
+		Any("a", v).appendJSON(buf, false)
+		if !json.Valid(buf.Bytes()[len(`"a":`):]) {
+			t.Errorf("Invalid JSON: %q", buf.String())
+		}
+		return buf.String()
+	}
+
+	long := make([]int, maxCollectionElements+5)
+	got := render(long)
+	if !strings.HasSuffix(got, `,"...+5"]`) {
+		t.Errorf("Long slice not elided: %q", got)
+	}
+	if n := strings.Count(got, "0"); n != maxCollectionElements {
+		t.Errorf("Wrong number of elements written: %d", n)
+	}
+
+	m := map[string]int{}
+	for i := 0; i < maxCollectionElements+1; i++ {
+		m[fmt.Sprintf("k%03d", i)] = i
+	}
+	if got := render(m); !strings.HasPrefix(got, `"a":{"k000":0,`) || !strings.HasSuffix(got, `,"...":"+1"}`) {
+		t.Errorf("Long map not elided: %q", got)
+	}
+
+	var deep interface{} = 1
+	for i := 0; i < maxCollectionDepth+2; i++ {
+		deep = []interface{}{deep}
+	}
+	if got := render(deep); !strings.Contains(got, `"[...]"`) {
+		t.Errorf("Deep nesting not capped: %q", got)
+	}
+}
+
+func TestJSONTimePrecisionAndMetadata(t *testing.T) {
+	l := newJSONLogger(&Options{
+		TimePrecision:    MillisecondPrecision,
+		InstanceMetadata: &InstanceMetadata{Provider: "gce", InstanceID: "1", Zone: "z", Tags: map[string]string{"env": "prod"}},
+		MessageHash:      true,
+	})
+	l.Warning("hello")
+	got := l.w.(*flushBuffer).String()
+	if !strings.Contains(got, `"timestamp":"2006-01-02T15:04:05.067Z"`) {
+		t.Errorf("Wrong timestamp: %q", got)
+	}
+	if !strings.Contains(got, `"message":"hello","provider":"gce","instance_id":"1","zone":"z","tag":{"env":"prod"},"msg_hash":"`) {
+		t.Errorf("Wrong metadata: %q", got)
+	}
+	entries := l.CaptureLogs(func() { l.Info("captured") })
+	if len(entries) != 1 || entries[0].Severity != "INFO" || entries[0].Message != "captured" || entries[0].File != "json_test.go" {
+		t.Errorf("Wrong captured entries: %#v", entries)
+	}
+}
+
+func TestUpgradeJSONEntry(t *testing.T) {
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(`{"severity":"INFO","file":"main.go","line":3,"message":"old"}`), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpgradeJSONEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+	caller, _ := entry["caller"].(map[string]interface{})
+	if entry["schema_version"] != float64(JSONSchemaVersion) || entry["message"] != "old" || caller["file"] != "main.go" || caller["line"] != float64(3) || entry["file"] != nil {
+		t.Errorf("Wrong upgraded entry: %v", entry)
+	}
+	for _, bad := range []string{`{"schema_version":99}`, `{"schema_version":"1"}`, `{"schema_version":1.5}`} {
+		if err := json.Unmarshal([]byte(bad), &entry); err != nil {
+			t.Fatal(err)
+		}
+		if err := UpgradeJSONEntry(entry); err == nil {
+			t.Errorf("Expected an error for %s", bad)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// Lifecycle selects what's in the entries written when the process starts
+// and exits, see Options.Lifecycle.
+type Lifecycle struct {
+	// Env are the names of the environment variables that are included in
+	// the "process started" entry, if they're set. The others are left out,
+	// as they may hold secrets.
+	Env []string
+
+	// OmitArgs, if true, leaves the command line arguments out of the
+	// "process started" entry.
+	OmitArgs bool
+}
+
+// logStarted writes the "process started" entry, reporting the call site
+// depth frames above the caller of logStarted.
+func (l *Logger) logStarted(depth int) {
+	var fields []Field
+	if !l.lifecycle.OmitArgs {
+		fields = append(fields, Any("args", os.Args[1:]))
+	}
+	var env []Field
+	for _, name := range l.lifecycle.Env {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, Str(name, value))
+		}
+	}
+	fields = append(fields, Group("env", env...))
+	build := []Field{Str("go", runtime.Version())}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		build = append(build, Str("path", info.Main.Path), Str("version", info.Main.Version))
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				build = append(build, Str("revision", setting.Value))
+			}
+		}
+	}
+	fields = append(fields, Group("build", build...))
+	l.logDepth(infoLog, depth, []byte("process started"), fields)
+}
+
+// logExiting writes the "process exiting" entry, with the reason and the
+// time since the Logger was created, as made from the given line of file.
+// It's only written once.
+func (l *Logger) logExiting(reason, file string, line int) {
+	if l.lifecycle == nil || !atomic.CompareAndSwapInt32(&l.exitLogged, 0, 1) {
+		return
+	}
+	l.WithCaller(file, line).logDepth(infoLog, 0, []byte("process exiting"), []Field{
+		Str("reason", reason),
+		Dur("uptime", l.timeNow().Sub(l.started)),
+	})
+}
@@ -0,0 +1,74 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLifecycle(t *testing.T) {
+	defer func(previous func() time.Time) { timeNow = previous }(timeNow)
+	now := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	os.Setenv("LIFECYCLE_TEST_SET", "yes")
+	defer os.Unsetenv("LIFECYCLE_TEST_SET")
+
+	b := &flushBuffer{}
+	_, _, line, _ := runtime.Caller(0)
+	l := NewFromOptions(&Options{SyncWriter: b, Lifecycle: &Lifecycle{Env: []string{"LIFECYCLE_TEST_SET", "LIFECYCLE_TEST_UNSET"}}})
+	started := b.String()
+	for _, want := range []string{
+		fmt.Sprintf(" lifecycle_test.go:%d] process started args=", line+1),
+		" env.LIFECYCLE_TEST_SET=yes",
+		" build.go=" + runtime.Version(),
+	} {
+		if !strings.Contains(started, want) {
+			t.Errorf("Missing %q in %q", want, started)
+		}
+	}
+	if strings.Contains(started, "LIFECYCLE_TEST_UNSET") {
+		t.Errorf("Unset variable included: %q", started)
+	}
+
+	now = now.Add(90 * time.Second)
+	_, _, line, _ = runtime.Caller(0)
+	l.Shutdown(context.Background())
+	want := fmt.Sprintf(" lifecycle_test.go:%d] process exiting reason=shutdown uptime=1m30s\n", line+1)
+	if got := strings.TrimPrefix(b.String(), started); !strings.HasSuffix(got, want) {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+	l.Shutdown(context.Background())
+	if n := strings.Count(b.String(), "process exiting"); n != 1 {
+		t.Errorf("Got %d process exiting entries, want 1", n)
+	}
+}
+
+func TestLifecycleFatal(t *testing.T) {
+	defer func(exit func(int)) { osExit = exit }(osExit)
+	osExit = func(int) {}
+
+	b := &flushBuffer{}
+	l := NewFromOptions(&Options{SyncWriter: b, Lifecycle: &Lifecycle{OmitArgs: true}})
+	if strings.Contains(b.String(), "args=") {
+		t.Errorf("Args not omitted: %q", b.String())
+	}
+	_, _, line, _ := runtime.Caller(0)
+	l.Fatal("boom")
+	want := fmt.Sprintf(" lifecycle_test.go:%d] process exiting reason=fatal uptime=", line+1)
+	if got := b.String(); !strings.Contains(got, want) || strings.Index(got, want) < strings.Index(got, "goroutine") {
+		t.Errorf("Missing %q after the stacks in %q", want, got)
+	}
+}
+
+func TestLifecycleOff(t *testing.T) {
+	b := &flushBuffer{}
+	l := NewFromOptions(&Options{SyncWriter: b})
+	l.Shutdown(context.Background())
+	if b.String() != "" {
+		t.Errorf("Lifecycle entries written by default: %q", b.String())
+	}
+}
@@ -4,14 +4,15 @@ package logger
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
-
-	"github.com/jcgregorio/slog"
 )
 
 var (
@@ -41,6 +42,30 @@ const (
 
 const severityChar = "DIWEF"
 
+// TimePrecision is the precision of the timestamps written in log headers.
+type TimePrecision int
+
+// These are the precisions available for Options.TimePrecision.
+const (
+	MicrosecondPrecision TimePrecision = iota
+	SecondPrecision
+	MillisecondPrecision
+	NanosecondPrecision
+)
+
+// digits returns the number of fractional second digits written for p.
+func (p TimePrecision) digits() int {
+	switch p {
+	case SecondPrecision:
+		return 0
+	case MillisecondPrecision:
+		return 3
+	case NanosecondPrecision:
+		return 9
+	}
+	return 6
+}
+
 var severityName = []string{
 	debugLog:   "DEBUG",
 	infoLog:    "INFO",
@@ -50,16 +75,32 @@ var severityName = []string{
 }
 
 func New() *Logger {
-	return &Logger{w: os.Stdout}
+	return NewFromOptions(&Options{})
 }
 
 // Options is passed to NewFromOptions to control some aspects of the created
 // Logger.
 type Options struct {
 	// SyncWriter is the destination to write logs to. If left nil then os.Stdout
-	// will be used.
+	// will be used, or in a child process started by StartChild the pipe to
+	// the parent.
 	SyncWriter SyncWriter
 
+	// SeverityWriters, if not empty, replaces SyncWriter with a destination
+	// per severity, in the manner of glog's log file per severity. Each
+	// entry is written to the destinations of its severity and of all less
+	// severe ones, e.g. with:
+	//
+	//	SeverityWriters: map[logger.Severity]logger.SyncWriter{
+	//		logger.InfoSeverity:  infoFile,
+	//		logger.ErrorSeverity: errorFile,
+	//	}
+	//
+	// Warnings are only written to infoFile, while errors are written to
+	// both. Entries less severe than all of them, Debug ones here, aren't
+	// written anywhere.
+	SeverityWriters map[Severity]SyncWriter
+
 	// IncludeDebug is true will emit Debug/Debugf logs, otherwise those logs are ignored.
 	IncludeDebug bool
 
@@ -67,27 +108,324 @@ type Options struct {
 	//
 	// Useful if Logger is going to be wrapped inside another logging module.
 	DepthDelta int
+
+	// InstanceMetadata, if not nil, is stamped onto every log line as
+	// key=value pairs. See FetchInstanceMetadata.
+	InstanceMetadata *InstanceMetadata
+
+	// MaxLineLength is the maximum length in bytes of a single written log
+	// line. Longer lines are split into parts, each ending with a " part=N/M"
+	// marker so collectors can reassemble them. If zero then
+	// DockerMaxLineLength is used when writing to stdout or stderr from
+	// within a container, and no limit otherwise. A negative value disables
+	// splitting.
+	MaxLineLength int
+
+	// MessageHash, if true, adds a msg_hash=<hash> pair to every line, where
+	// the hash is computed over the rendered message, so identical events
+	// can be grouped downstream without comparing the full text.
+	MessageHash bool
+
+	// ErrorDigest, if not nil, is fed every Error log. See NewErrorDigest.
+	ErrorDigest *ErrorDigest
+
+	// StdLogHeader, if not nil, replaces the glog style header with the one
+	// written by the standard library's log package, for byte-identical
+	// output when migrating from a log.Logger.
+	StdLogHeader *StdLogHeader
+
+	// HighlightRepeats, if true, uses ANSI escapes to dim a log line that
+	// repeats the previous message, and for messages that only differ from
+	// the previous one in some words, such as counts or ids, dims the words
+	// that are the same and makes the ones that changed bold. Intended for
+	// reading the logs of tight loops on a terminal during development.
+	HighlightRepeats bool
+
+	// StrictGlog, if true, makes the header match the one written by the C++
+	// glog library exactly, for parsers that validate the format strictly.
+	// The thread id is taken to be the id of the logging goroutine, and is
+	// padded to 5 characters, instead of the process id padded to 7
+	// characters used by default.
+	StrictGlog bool
+
+	// TimePrecision is the precision of the timestamp in the header. The
+	// default is MicrosecondPrecision.
+	TimePrecision TimePrecision
+
+	// Location, if not nil, is the time zone timestamps are written in,
+	// instead of the local time zone.
+	Location *time.Location
+
+	// MaxMemory, if greater than zero, bounds the number of bytes the Logger
+	// holds on to between log calls, in reusable buffers, in lines queued
+	// for Inspector tails, in writes queued for AsyncQueueSize, and in
+	// entries kept for DebugBacklog and FlightRecorder. Once reached,
+	// buffers are released instead of being kept for reuse, lines are
+	// dropped instead of being queued for tails, writes are queued as
+	// selected by AsyncQueueFull, as if the queue were full, and the oldest
+	// entries kept are discarded to make room for new ones. See
+	// MemoryStats.
+	MaxMemory int64
+
+	// Governor, if not nil, raises the minimum severity while the
+	// destination is saturated, see Governor.
+	Governor *Governor
+
+	// ExitCodes maps severities to the exit status returned by ExitCode
+	// once an entry of that severity has been written. If nil then
+	// {ErrorSeverity: 1} is used.
+	ExitCodes map[Severity]int
+
+	// FlushInterval, if greater than zero, calls Flush that often from a
+	// background goroutine, as glog's flush daemon does, so buffered
+	// entries reach the destination even when nothing else syncs it. The
+	// goroutine stops when the Logger is shut down or closed.
+	FlushInterval time.Duration
+
+	// Fallback, if not nil, is where entries are written once the Logger
+	// has been shut down or closed, instead of os.Stderr.
+	Fallback SyncWriter
+
+	// AsyncQueueSize, if greater than zero, makes the writes to the
+	// destination from a background goroutine, queueing up to that many,
+	// see AsyncWriter. Shutdown, and exiting after a Fatal log, wait for
+	// the queued writes.
+	AsyncQueueSize int
+
+	// AsyncQueueFull selects what's done with an entry when the queue of
+	// AsyncQueueSize is full, or MaxMemory is reached. The default waits
+	// for room. See DroppedWrites for the number dropped.
+	AsyncQueueFull QueueFullPolicy
+
+	// DebugBacklog, if greater than zero, keeps the last DebugBacklog Debug
+	// entries of each goroutine that aren't written, because Debug logs
+	// aren't enabled, and writes them ahead of the next Error or Fatal
+	// entry logged by the same goroutine, with a "backlog" field of true.
+	// This gives the context of an error without writing every Debug
+	// entry. The Debug entries are then always formatted, and the
+	// goroutine identified, so it has a cost even while there are no
+	// errors.
+	DebugBacklog int
+
+	// FlightRecorder, if greater than zero, keeps the last FlightRecorder
+	// entries logged, of every severity, including those that aren't
+	// written because they're Debug entries that aren't enabled or below
+	// the minimum severity, and writes them ahead of a Fatal entry, with a
+	// "flight_recorder" field of true, for the context of a crash beyond
+	// its stack traces. V logs that aren't enabled aren't kept. Every
+	// entry is then copied, and Debug entries are always formatted.
+	FlightRecorder int
+
+	// EmitHook, if not nil, is called around the writing of each entry,
+	// with its duration and size, see EmitHook.
+	EmitHook *EmitHook
+
+	// Lifecycle, if not nil, writes a "process started" entry when the
+	// Logger is created, with the command line arguments, the environment
+	// variables in Lifecycle.Env, and the Go version and module build
+	// information, as fields. It also writes a "process exiting" entry,
+	// with the reason and the time since the start as fields, before
+	// exiting for a Fatal log and in Shutdown.
+	Lifecycle *Lifecycle
+
+	// MarkContinuations, if true, writes the lines after the first of a
+	// multi-line entry, including the stack traces of a Fatal log and the
+	// parts of a line split for MaxLineLength, with the severity letter of
+	// their header in lower case, e.g. 'i' for Info. Parsers can then join
+	// them back into a single entry, as CaptureLogs does, and tools that
+	// count lines don't count them as entries. It's ignored with
+	// StdLogHeader, which has no severity letter.
+	MarkContinuations bool
+
+	// MaxStackSize, if greater than zero, bounds the size in bytes of the
+	// stack traces written for Fatal logs, instead of the default of
+	// 1.6MB, and the buffer they're captured in is allocated up front, so a
+	// Fatal log doesn't need to allocate one when memory is short. Stack
+	// traces that don't fit are truncated, ending with a line saying so.
+	MaxStackSize int
+
+	// Diagnostics is where the Logger reports problems with itself, such as
+	// a hook registered with OnFatal failing. If nil then os.Stderr is used.
+	Diagnostics io.Writer
+
+	// OccurrenceCounter, if true, prefixes the messages logged by the EveryN
+	// and Once methods, such as InfoEveryN, with the number of times the call
+	// site has been reached, e.g. "[x473] ", so a reader knows how many
+	// occurrences a sampled line represents.
+	OccurrenceCounter bool
+
+	// KeysAndValues selects how mistakes in the keysAndValues passed to With
+	// and the *w methods, such as Infow, are handled.
+	KeysAndValues KVPolicy
+
+	// MinSeverity drops entries below it, except for Fatal ones. The
+	// LOGGER_LEVEL environment variable overrides it, see LevelEnvVar.
+	MinSeverity Severity
+
+	// Verbosity is the level at and below which the logs from V are
+	// written, see SetVerbosity.
+	Verbosity int
+
+	// VModule sets per-file verbosity levels, see SetVModule. If invalid the
+	// problem is reported to Diagnostics and it's ignored.
+	VModule string
+
+	// Format selects how entries are written, TextFormat by default. With
+	// JSONFormat, StdLogHeader, StrictGlog, and HighlightRepeats are
+	// ignored.
+	Format Format
+
+	// EncoderConfig renames the standard members of JSONFormat entries.
+	EncoderConfig EncoderConfig
+
+	// Formatter, if not nil, renders every entry, overriding Format,
+	// StdLogHeader, StrictGlog, TimePrecision, MaxLineLength, and
+	// HighlightRepeats. See GlogFormatter for the default layout.
+	Formatter Formatter
+
+	// Now, if not nil, is used instead of time.Now for the timestamps in the
+	// headers, and for the durations logged by Span and Progress.
+	Now func() time.Time
+
+	// Exit, if not nil, is called instead of os.Exit after a Fatal log.
+	Exit func(code int)
+
+	// PID, if not zero, is written in the header instead of the process id.
+	PID int
 }
 
 func NewFromOptions(o *Options) *Logger {
 	var w SyncWriter = os.Stdout
-	if o.SyncWriter != nil {
+	if len(o.SeverityWriters) > 0 {
+		w = newSeverityWriters(o.SeverityWriters)
+	} else if o.SyncWriter != nil {
 		w = o.SyncWriter
 	}
-	return &Logger{
-		w:            w,
-		includeDebug: o.IncludeDebug,
-		depthDelta:   o.DepthDelta,
+	format, formatter, jsonKeys := o.Format, o.Formatter, o.EncoderConfig
+	if len(o.SeverityWriters) == 0 && o.SyncWriter == nil {
+		if p := parentWriter(); p != nil {
+			// The parent decodes the entries, see StartChild.
+			w, format, formatter, jsonKeys = p, JSONFormat, nil, EncoderConfig{}
+		}
+	}
+	lineLength := maxLineLength(o, w)
+	var async *AsyncWriter
+	if o.AsyncQueueSize > 0 {
+		async = NewAsyncWriterWithPolicy(w, o.AsyncQueueSize, o.AsyncQueueFull)
+		w = async
+	}
+	ret := &Logger{loggerState: &loggerState{
+		w:                 w,
+		includeDebug:      boolToInt32(o.IncludeDebug),
+		verbosity:         int32(o.Verbosity),
+		minSeverity:       int32(o.MinSeverity),
+		kvPolicy:          o.KeysAndValues,
+		stamp:             o.InstanceMetadata.stamp(),
+		stampFields:       o.InstanceMetadata.fields(),
+		maxLineLength:     lineLength,
+		messageHash:       o.MessageHash,
+		errorDigest:       o.ErrorDigest,
+		stdLogHeader:      o.StdLogHeader,
+		strictGlog:        o.StrictGlog,
+		timePrecision:     o.TimePrecision,
+		location:          o.Location,
+		maxMemory:         o.MaxMemory,
+		format:            format,
+		formatter:         formatter,
+		jsonKeys:          jsonKeys.withDefaults(),
+		occurrenceCounter: o.OccurrenceCounter,
+		markContinuations: o.MarkContinuations && o.StdLogHeader == nil,
+		lifecycle:         o.Lifecycle,
+		governor:          newGovernor(o.Governor),
+		emitHook:          o.EmitHook,
+		fallback:          o.Fallback,
+		exitCodes:         o.ExitCodes,
+		maxSeverity:       -1,
+		diagnostics:       o.Diagnostics,
+		now:               o.Now,
+		exit:              o.Exit,
+		pid:               o.PID,
+	}, depthDelta: o.DepthDelta}
+	ret.debugBacklog = newDebugBacklog(o.DebugBacklog, ret.loggerState)
+	ret.flight = newFlightRecorder(o.FlightRecorder, ret.loggerState)
+	if async != nil {
+		async.mem = ret.loggerState
+	}
+	if o.HighlightRepeats && format != JSONFormat && formatter == nil {
+		ret.highlighter = &repeatHighlighter{}
+	}
+	ret.crashTemplate = ret.newCrashTemplate()
+	ret.maxStackSize = defaultMaxStackSize
+	if o.MaxStackSize > 0 {
+		ret.maxStackSize = o.MaxStackSize
+		ret.stackBuf = make([]byte, o.MaxStackSize)
 	}
+	if err := ret.SetVModule(o.VModule); err != nil {
+		ret.diagnosef("%s", err)
+	}
+	ret.applyLevelEnv()
+	if o.FlushInterval > 0 {
+		ret.flushStop = make(chan struct{})
+		go ret.flushEvery(o.FlushInterval)
+	}
+	ret.started = ret.timeNow()
+	if ret.lifecycle != nil {
+		ret.logStarted(1)
+	}
+	return ret
 }
 
 // Logger collects all the global state of the logging setup.
 //
-// *Logger implements the slog.Logger interface.
+// *Logger implements Interface, and so the slog.Logger interface.
 type Logger struct {
+	// loggerState is shared with the Loggers derived from this one by With.
+	*loggerState
+
+	// fields are added to every entry, see With.
+	fields []Field
+
+	// job, if not nil, counts the entries written, see StartJob.
+	job *Job
+
+	// name identifies the component logging, see Named.
+	name string
+
+	// registry, if not nil, holds the severity overrides for name.
+	registry *Registry
+
+	// caller, if not nil, is reported instead of the calling file and line,
+	// see WithCaller.
+	caller *caller
+
+	// depthDelta is the number of extra stack levels to look up when
+	// reporting the calling function, see Options.DepthDelta and WithDepth.
+	depthDelta int
+
+	// sampledOut is true if the logs of the request the Logger is for were
+	// sampled out, see Sample.
+	sampledOut bool
+}
+
+// loggerState is the state of a Logger.
+type loggerState struct {
 	w SyncWriter
 
-	includeDebug bool
+	// includeDebug is 1 if Debug logs are emitted, accessed atomically.
+	includeDebug int32
+
+	// vmodule holds a *vmodule, see SetVModule.
+	vmodule atomic.Value
+
+	// verbosity is the level used by V, accessed atomically.
+	verbosity int32
+
+	// minSeverity is the Severity below which entries are dropped, accessed
+	// atomically.
+	minSeverity int32
+
+	// kvPolicy is Options.KeysAndValues.
+	kvPolicy KVPolicy
 
 	// freeList is a list of byte buffers, maintained under freeListMu.
 	freeList *buffer
@@ -97,8 +435,181 @@ type Logger struct {
 	// for better parallelization.
 	freeListMu sync.Mutex
 
-	// DepthDelta is the number of extra stack levels to look up when reporting the calling function.
-	depthDelta int
+	// stamp is appended to every log line, see Options.InstanceMetadata.
+	stamp []byte
+
+	// stampFields are the Fields rendered in stamp, for JSONFormat.
+	stampFields []Field
+
+	// maxLineLength is the longest line to write, or 0 for no limit.
+	maxLineLength int
+
+	// messageHash is true if a hash of each message should be appended.
+	messageHash bool
+
+	// errorDigest, if not nil, is fed every Error log.
+	errorDigest *ErrorDigest
+
+	// stdLogHeader, if not nil, the header to use instead of the glog header.
+	stdLogHeader *StdLogHeader
+
+	// strictGlog is true if the header should use the goroutine id as thread id.
+	strictGlog bool
+
+	// highlighter, if not nil, highlights repeated messages.
+	highlighter *repeatHighlighter
+
+	// timePrecision is the precision of the timestamp in the header.
+	timePrecision TimePrecision
+
+	// format selects how entries are written.
+	format Format
+
+	// formatter, if not nil, renders every entry.
+	formatter Formatter
+
+	// jsonKeys are the names of the standard members of JSONFormat entries,
+	// see Options.EncoderConfig.
+	jsonKeys EncoderConfig
+
+	// location, if not nil, is the time zone of the timestamps.
+	location *time.Location
+
+	// memUsed is the number of bytes held between log calls, accessed
+	// atomically, and bounded by maxMemory if it's greater than zero.
+	memUsed   int64
+	maxMemory int64
+
+	// memDropped counts what's dropped to stay within maxMemory, accessed
+	// atomically.
+	memDropped uint64
+
+	// diagnostics is where problems with the Logger itself are reported.
+	diagnostics io.Writer
+
+	// fatalHooks are the hooks registered with OnFatal, maintained under
+	// fatalHooksMu.
+	fatalHooks   []fatalHook
+	fatalHooksMu sync.Mutex
+
+	// crashTemplate is the header template used by Crash.
+	crashTemplate []byte
+
+	// maxStackSize bounds the size of the stack traces, see
+	// Options.MaxStackSize.
+	maxStackSize int
+
+	// stackMu protects stackBuf, which holds the last stack traces, and is
+	// kept for reuse.
+	stackMu  sync.Mutex
+	stackBuf []byte
+
+	// now, exit, and pid override time.Now, os.Exit, and the process id.
+	// See Options.
+	now  func() time.Time
+	exit func(code int)
+	pid  int
+
+	// deprecations records the call sites Deprecated has already logged for.
+	deprecations sync.Map
+
+	// occurrences counts the calls from each call site of the EveryN and
+	// Once methods, keyed by program counter.
+	occurrences sync.Map
+
+	// occurrenceCounter is true if sampled messages are prefixed with their
+	// occurrence count.
+	occurrenceCounter bool
+
+	// governor, if not nil, is the state of Options.Governor.
+	governor *governor
+
+	// fallback, if not nil, is Options.Fallback.
+	fallback SyncWriter
+
+	// flushStop, if not nil, is closed on shutdown to stop the goroutine
+	// started for Options.FlushInterval.
+	flushStop chan struct{}
+
+	// exitCodes is Options.ExitCodes, and maxSeverity the highest Severity
+	// written, or -1 if none has been, accessed atomically, for ExitCode.
+	exitCodes   map[Severity]int
+	maxSeverity int32
+
+	// debugBacklog, if not nil, keeps the Debug entries that aren't
+	// written, see Options.DebugBacklog.
+	debugBacklog *debugBacklog
+
+	// flight, if not nil, keeps the most recent entries, see
+	// Options.FlightRecorder.
+	flight *flightRecorder
+
+	// emitHook, if not nil, instruments the writing of entries, see
+	// Options.EmitHook.
+	emitHook *EmitHook
+
+	// lifecycle selects the process lifecycle entries, see
+	// Options.Lifecycle, which are only written if it's not nil. started is
+	// when the Logger was created, and exitLogged is 1 once the "process
+	// exiting" entry has been written, accessed atomically.
+	lifecycle  *Lifecycle
+	started    time.Time
+	exitLogged int32
+
+	// markContinuations is true if continuation lines have a lower case
+	// severity letter, see Options.MarkContinuations.
+	markContinuations bool
+
+	// linesWritten is the number of lines written, accessed atomically.
+	linesWritten uint64
+
+	// taps receive a copy of every line written that matches their
+	// filter, maintained under tapsMu.
+	taps   map[chan []byte]*tapFilter
+	tapsMu sync.Mutex
+
+	// numTaps is len(taps), accessed atomically so the common case of no
+	// taps doesn't need to take tapsMu.
+	numTaps int32
+
+	// wMu is held for reading while writing to w, and for writing by
+	// Shutdown, so that w isn't closed in the middle of a write.
+	wMu sync.RWMutex
+
+	// shutdown is 1 once Shutdown has been called, accessed atomically.
+	shutdown int32
+
+	// writeFailing is 1 if the last write to w failed, accessed atomically.
+	writeFailing int32
+
+	// writeErr is the error from the last failed write, under healthMu.
+	writeErr error
+	healthMu sync.Mutex
+}
+
+func boolToInt32(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// SetIncludeDebug controls if Debug/Debugf logs are emitted, and is safe to
+// call while the Logger is being used.
+func (l *Logger) SetIncludeDebug(include bool) {
+	atomic.StoreInt32(&l.includeDebug, boolToInt32(include))
+}
+
+// IncludeDebug returns true if Debug/Debugf logs are being emitted. For a
+// Logger from a Registry, any override set with Registry.SetLevel takes
+// precedence over SetIncludeDebug.
+func (l *Logger) IncludeDebug() bool {
+	if l.registry != nil {
+		if min, ok := l.registry.Level(l.name); ok {
+			return !DebugSeverity.less(min)
+		}
+	}
+	return atomic.LoadInt32(&l.includeDebug) == 1
 }
 
 // buffer holds a byte Buffer for reuse. The zero value is ready for use.
@@ -106,6 +617,35 @@ type buffer struct {
 	bytes.Buffer
 	tmp  [64]byte // temporary byte array for creating headers.
 	next *buffer
+
+	// time, pid, file, and line record what the header is for, which is
+	// needed when Options.Formatter is set, in which case header doesn't
+	// render it.
+	time time.Time
+	pid  int
+	file string
+	line int
+
+	// record is true for the header of an entry passed to LogRecord, which
+	// is written without stack traces even if it's Fatal.
+	record bool
+
+	// unwritten is true for the header of a Debug entry that isn't
+	// enabled, which is only kept for Options.DebugBacklog or
+	// Options.FlightRecorder, rather than written.
+	unwritten bool
+
+	// flight is true for the header of an entry that's already kept by
+	// Options.FlightRecorder.
+	flight bool
+
+	// ctx is the context the entry was logged with, by the *Ctx functions,
+	// for Options.EmitHook.
+	ctx context.Context
+
+	// written, if not nil, counts the bytes of the entry written to the
+	// destination, for Options.EmitHook.
+	written *int
 }
 
 // getBuffer returns a new, ready-to-use buffer.
@@ -119,7 +659,13 @@ func (l *Logger) getBuffer() *buffer {
 	if b == nil {
 		b = new(buffer)
 	} else {
+		l.releaseMemory(b.Cap())
 		b.next = nil
+		b.record = false
+		b.unwritten = false
+		b.flight = false
+		b.ctx = nil
+		b.written = nil
 		b.Reset()
 	}
 	return b
@@ -131,6 +677,9 @@ func (l *Logger) putBuffer(b *buffer) {
 		// Let big buffers die a natural death.
 		return
 	}
+	if !l.reserveMemory(b.Cap()) {
+		return
+	}
 	l.freeListMu.Lock()
 	b.next = l.freeList
 	l.freeList = b
@@ -149,6 +698,45 @@ func (l *Logger) bufferCacheLen() int {
 
 var timeNow = time.Now // Stubbed out for testing.
 
+// timeNow returns the current time, from Options.Now if it was given, in
+// Options.Location if it was given.
+func (l *Logger) timeNow() time.Time {
+	now := timeNow
+	if l.now != nil {
+		now = l.now
+	}
+	if l.location != nil {
+		return now().In(l.location)
+	}
+	return now()
+}
+
+// timeLocation returns the time zone timestamps are written in.
+func (l *Logger) timeLocation() *time.Location {
+	if l.location != nil {
+		return l.location
+	}
+	return time.Local
+}
+
+// processID returns the id written in the header, from Options.PID if it
+// was given.
+func (l *Logger) processID() int {
+	if l.pid != 0 {
+		return l.pid
+	}
+	return pid
+}
+
+// osExit exits the process, via Options.Exit if it was given.
+func (l *Logger) osExit(code int) {
+	if l.exit != nil {
+		l.exit(code)
+		return
+	}
+	osExit(code)
+}
+
 /*
 header formats a log header as defined by the C++ implementation.
 It returns a buffer containing the formatted header and the user's file and line number.
@@ -160,43 +748,78 @@ where the fields are defined as follows:
 	L                A single character, representing the log level (eg 'I' for INFO)
 	mm               The month (zero padded; ie May is '05')
 	dd               The day (zero padded)
-	hh:mm:ss.uuuuuu  Time in hours, minutes and fractional seconds
-	threadid         The space-padded thread ID as returned by GetTID()
+	hh:mm:ss.uuuuuu  Time in hours, minutes and fractional seconds, see Options.TimePrecision
+	threadid         The space-padded process ID, or goroutine ID if Options.StrictGlog is true
 	file             The file name
 	line             The line number
 	msg              The user-supplied message
 */
 func (l *Logger) header(s severity, depth int) (*buffer, string, int) {
-	_, file, line, ok := runtime.Caller(3 + depth + l.depthDelta)
-	if !ok {
-		file = "???"
-		line = 1
+	var pc uintptr
+	var file string
+	var line int
+	if l.caller != nil {
+		file, line = l.caller.file, l.caller.line
 	} else {
-		slash := strings.LastIndex(file, "/")
-		if slash >= 0 {
-			file = file[slash+1:]
+		var ok bool
+		pc, file, line, ok = runtime.Caller(3 + depth + l.depthDelta)
+		if !ok {
+			file = "???"
+			line = 1
 		}
 	}
-	return l.formatHeader(s, file, line), file, line
+	buf := l.headerFor(s, l.timeNow(), l.processID(), file, line, pc)
+	if s == debugLog && (l.debugBacklog != nil || l.flight != nil) {
+		buf.unwritten = !l.IncludeDebug() && l.vmoduleLevel(file) < 1
+	}
+	return buf, buf.file, line
 }
 
-// formatHeader formats a log header using the provided file name and line number.
-func (l *Logger) formatHeader(s severity, file string, line int) *buffer {
-	now := timeNow()
-	if line < 0 {
-		line = 0 // not a real line number, but acceptable to someDigits
+// headerFor returns a buffer holding the header for a log of severity s
+// made at now by process pid from the given line of the file at fullPath,
+// in the function holding pc, which is 0 if it's unknown.
+func (l *Logger) headerFor(s severity, now time.Time, pid int, fullPath string, line int, pc uintptr) *buffer {
+	file := fullPath
+	slash := strings.LastIndex(file, "/")
+	if slash >= 0 {
+		file = file[slash+1:]
 	}
-	if s > fatalLog {
-		s = infoLog // for safety.
+	var buf *buffer
+	if l.formatter != nil {
+		buf = l.getBuffer()
+	} else if l.format == JSONFormat {
+		buf = l.jsonHeader(s, now, pid, file, line, pc)
+	} else if l.stdLogHeader != nil {
+		buf = l.stdLogHeader.format(l.getBuffer(), now, fullPath, line)
+	} else {
+		buf = l.formatHeader(s, now, pid, file, line)
 	}
-	buf := l.getBuffer()
+	buf.time, buf.pid, buf.file, buf.line = now, pid, file, line
+	return buf
+}
 
+// formatHeader formats a log header using the provided time, process id,
+// file name, and line number.
+func (l *Logger) formatHeader(s severity, now time.Time, pid int, file string, line int) *buffer {
+	tid := pid
+	if l.strictGlog {
+		tid = int(goroutineID())
+	}
+	return formatGlogHeader(l.getBuffer(), s, now, l.timePrecision, l.strictGlog, tid, file, line)
+}
+
+// formatGlogHeader writes a log header to buf. The thread id, tid, is padded
+// to 5 characters if strict is true, as C++ glog does, otherwise to 7.
+func formatGlogHeader(buf *buffer, s severity, now time.Time, precision TimePrecision, strict bool, tid int, file string, line int) *buffer {
+	if line < 0 {
+		line = 0 // not a real line number, but acceptable to someDigits
+	}
 	// Avoid Fprintf, for speed. The format is so simple that we can do it quickly by hand.
 	// It's worth about 3X. Fprintf is hard.
 	_, month, day := now.Date()
 	hour, minute, second := now.Clock()
 	// Lmmdd hh:mm:ss.uuuuuu threadid file:line]
-	buf.tmp[0] = severityChar[s]
+	buf.tmp[0] = s.char()
 	buf.twoDigits(1, int(month))
 	buf.twoDigits(3, day)
 	buf.tmp[5] = ' '
@@ -205,12 +828,28 @@ func (l *Logger) formatHeader(s severity, file string, line int) *buffer {
 	buf.twoDigits(9, minute)
 	buf.tmp[11] = ':'
 	buf.twoDigits(12, second)
-	buf.tmp[14] = '.'
-	buf.nDigits(6, 15, now.Nanosecond()/1000, '0')
-	buf.tmp[21] = ' '
-	buf.nDigits(7, 22, pid, ' ') // TODO: should be TID
-	buf.tmp[29] = ' '
-	buf.Write(buf.tmp[:30])
+	i := 14
+	if d := precision.digits(); d > 0 {
+		buf.tmp[i] = '.'
+		buf.nDigits(d, i+1, now.Nanosecond()/pow10(9-d), '0')
+		i += d + 1
+	}
+	buf.tmp[i] = ' '
+	i++
+	if strict {
+		buf.Write(buf.tmp[:i])
+		// C++ glog pads the thread id to 5 characters, but never truncates it.
+		for n := numDigits(tid); n < 5; n++ {
+			buf.WriteByte(' ')
+		}
+		n := buf.someDigits(0, tid)
+		buf.tmp[n] = ' '
+		buf.Write(buf.tmp[:n+1])
+	} else {
+		buf.nDigits(7, i, tid, ' ') // TODO: should be TID
+		buf.tmp[i+7] = ' '
+		buf.Write(buf.tmp[:i+8])
+	}
 	buf.WriteString(file)
 	buf.tmp[0] = ':'
 	n := buf.someDigits(1, line)
@@ -245,6 +884,17 @@ func (buf *buffer) nDigits(n, i, d int, pad byte) {
 	}
 }
 
+// numDigits returns the number of decimal digits needed to format d.
+// It assumes d >= 0.
+func numDigits(d int) int {
+	n := 1
+	for d >= 10 {
+		d /= 10
+		n++
+	}
+	return n
+}
+
 // someDigits formats a zero-prefixed variable-width integer at buf.tmp[i].
 func (buf *buffer) someDigits(i, d int) int {
 	// Print into the top, then copy down. We know there's space for at least
@@ -271,7 +921,7 @@ func (l *Logger) printDepth(s severity, depth int, args ...interface{}) {
 	buf := l.getBuffer()
 
 	fmt.Fprint(buf, args...)
-	l.emitAsOneOrMoreLogLines(s, buf, header)
+	l.emitAsOneOrMoreLogLines(s, buf, header, nil)
 	l.putBuffer(buf)
 }
 
@@ -281,30 +931,226 @@ func (l *Logger) printf(s severity, format string, args ...interface{}) {
 
 	fmt.Fprintf(buf, format, args...)
 
-	l.emitAsOneOrMoreLogLines(s, buf, header)
+	l.emitAsOneOrMoreLogLines(s, buf, header, nil)
+	l.putBuffer(buf)
+}
+
+func (l *Logger) printFields(s severity, msg string, fields []Field) {
+	header, _, _ := l.header(s, 0)
+	buf := l.getBuffer()
+
+	buf.WriteString(msg)
+
+	l.emitAsOneOrMoreLogLines(s, buf, header, fields)
 	l.putBuffer(buf)
 }
 
-func (l *Logger) emitAsOneOrMoreLogLines(s severity, buf, header *buffer) {
+// printFieldsCtx is printFields for an entry logged with ctx.
+func (l *Logger) printFieldsCtx(ctx context.Context, s severity, msg string, fields []Field) {
+	header, _, _ := l.header(s, 0)
+	header.ctx = ctx
+	buf := l.getBuffer()
+
+	buf.WriteString(msg)
+
+	l.emitAsOneOrMoreLogLines(s, buf, header, fields)
+	l.putBuffer(buf)
+}
+
+// logDepth writes the already rendered msg along with fields, skipping
+// Debug logs if they aren't enabled. Unlike the other print functions it
+// never exits, even for fatalLog, which is written as by emitFatal but
+// leaves exitFatal to the caller.
+func (l *Logger) logDepth(s severity, depth int, msg []byte, fields []Field) {
+	if s == debugLog && !l.debugEnabled(depth+1) {
+		return
+	}
+	header, _, _ := l.header(s, depth)
+	buf := l.getBuffer()
+	buf.Write(msg)
+	if s == fatalLog {
+		l.emitFatal(buf, header, fields)
+	} else {
+		l.emitEntry(s, buf, header, fields)
+	}
+	l.putBuffer(buf)
+}
+
+// emitAsOneOrMoreLogLines writes out the message in buf along with any
+// fields, exiting if s is fatalLog.
+func (l *Logger) emitAsOneOrMoreLogLines(s severity, buf, header *buffer, fields []Field) {
+	if s != fatalLog {
+		l.emitEntry(s, buf, header, fields)
+		return
+	}
+	l.emitFatal(buf, header, fields)
+	exitFatal(l)
+}
+
+// emitFatal writes out the Fatal entry in buf along with any fields, in the
+// manner of Crash if that panics, and then the "process exiting" entry of
+// Options.Lifecycle. It doesn't exit, see exitFatal.
+func (l *Logger) emitFatal(buf, header *buffer, fields []Field) {
+	func() {
+		defer func() {
+			if recover() != nil {
+				// Make sure the message and stacks still get out.
+				l.crashFatal(buf, header)
+			}
+		}()
+		l.emitEntry(fatalLog, buf, header, fields)
+	}()
+	l.logExiting("fatal", header.file, header.line)
+}
+
+// exitFatal exits once loggers have each written a Fatal entry with
+// emitFatal, after syncing them, flushing the Loggers registered with
+// FlushOnExit, and running their OnFatal hooks. It exits via the first of
+// loggers, so its Options.Exit is respected.
+func exitFatal(loggers ...*Logger) {
+	for _, l := range loggers {
+		l.sync()
+	}
+	// Other Loggers may have buffered entries written before this one.
+	FlushAll()
+	for _, l := range loggers {
+		l.runFatalHooks()
+	}
+	if len(loggers) == 0 {
+		osExit(255)
+		return
+	}
+	loggers[0].osExit(255) // C++ uses -1, which is silly because it's anded with 255 anyway.
+}
+
+// emitEntry writes out the message in buf along with any fields, and a stack
+// trace if s is fatalLog, but doesn't exit.
+func (l *Logger) emitEntry(s severity, buf, header *buffer, fields []Field) {
+	if l.flight != nil && !header.flight {
+		if s == fatalLog {
+			l.writeFlight(l.flight.take())
+		}
+		l.flight.add(newBacklogEntry(l, s, buf, header, fields))
+	}
+	if header.unwritten {
+		if l.debugBacklog != nil {
+			l.keepDebug(buf, header, fields)
+		}
+		return
+	}
+	if l.dropped(s) {
+		return
+	}
+	if l.debugBacklog != nil && !Severity(s).less(ErrorSeverity) {
+		l.writeBacklog()
+	}
+	if len(l.fields) > 0 {
+		fields = append(l.fields[:len(l.fields):len(l.fields)], fields...)
+	}
+	if s == errorLog && l.errorDigest != nil {
+		l.errorDigest.add(buf.Bytes())
+	}
+	if l.job != nil {
+		l.job.count(s)
+	}
+	l.recordSeverity(s)
+	if l.emitHook != nil {
+		l.emitInstrumented(s, buf, header, fields)
+		return
+	}
+	l.emitFields(s, buf, header, fields)
+}
+
+// emitFields writes out the message in buf along with fields, which include
+// those of the Logger, and a stack trace if s is fatalLog and header isn't
+// for a record, but does none of the accounting of emitEntry.
+func (l *Logger) emitFields(s severity, buf, header *buffer, fields []Field) {
+	if l.name != "" {
+		if l.formatter != nil || l.format == JSONFormat {
+			fields = append([]Field{Str("component", l.name)}, fields...)
+		} else {
+			named := l.getBuffer()
+			defer l.putBuffer(named)
+			named.WriteString(l.name)
+			named.WriteString(": ")
+			named.Write(buf.Bytes())
+			buf = named
+		}
+	}
+	if l.formatter != nil {
+		l.emitFormatted(s, buf, header, fields)
+		return
+	}
+	if l.format == JSONFormat {
+		l.emitJSON(s, buf, header, fields)
+		return
+	}
+
+	suffix := l.stamp
+	if len(fields) > 0 || l.messageHash {
+		extra := l.getBuffer()
+		extra.Write(l.stamp)
+		for _, f := range fields {
+			f.appendTo(extra)
+		}
+		if l.messageHash {
+			appendKeyValue(extra, "msg_hash", messageHash(buf.Bytes()))
+		}
+		suffix = extra.Bytes()
+		defer l.putBuffer(extra)
+	}
+
+	if l.highlighter != nil {
+		highlighted := l.getBuffer()
+		defer l.putBuffer(highlighted)
+		if l.highlighter.highlight(buf.Bytes(), highlighted) {
+			dimmed := l.getBuffer()
+			defer l.putBuffer(dimmed)
+			dimmed.WriteString(ansiDim)
+			dimmed.Write(header.Bytes())
+			header = dimmed
+		}
+		buf = highlighted
+	}
+
 	// At this point buf could contain multiple embedded \n's, so we need to slice it up
 	// into multiple lines and emit each line separately.
-	l.emitAsOneOrMoreLogLinesImpl(buf, header)
+	e := l.entryInfo(s, header, fields)
+	if len(suffix) > 0 && len(bytes.Trim(buf.Bytes(), "\n")) == 0 {
+		// An entry with fields but no message is written as a line of just
+		// the fields, rather than not at all.
+		l.writeLine(e, header, nil, bytes.TrimPrefix(suffix, []byte(" ")), 0, 1)
+	} else {
+		l.emitAsOneOrMoreLogLinesImpl(e, buf, header, suffix, false)
+	}
 
-	if s == fatalLog {
+	if s == fatalLog && !header.record {
 		// If this is fatal then grab a strack trace and emit and also fatal
 		// error log entries.
-		trace := stacks(true)
-
-		buf := l.getBuffer()
-		buf.Write(trace)
-		l.emitAsOneOrMoreLogLinesImpl(buf, header)
-
-		l.w.Sync()
-		osExit(255) // C++ uses -1, which is silly because it's anded with 255 anyway.
+		l.stacks(func(trace []byte) {
+			buf := l.getBuffer()
+			defer l.putBuffer(buf)
+			buf.Write(trace)
+			l.emitAsOneOrMoreLogLinesImpl(e, buf, header, l.stamp, true)
+		})
 	}
 }
 
-func (l *Logger) emitAsOneOrMoreLogLinesImpl(buf, header *buffer) {
+// emitAsOneOrMoreLogLinesImpl writes each line in buf out prefixed with header
+// and followed by suffix, for the entry described by e. continued is true if
+// buf continues an entry that's already been written.
+func (l *Logger) emitAsOneOrMoreLogLinesImpl(e entryInfo, buf, header *buffer, suffix []byte, continued bool) {
+	// With Options.MarkContinuations every line after the first is written
+	// with cont instead of header.
+	var cont *buffer
+	if l.markContinuations {
+		cont = l.continuationHeader(e.s, header)
+		defer l.putBuffer(cont)
+		if continued {
+			header = cont
+		}
+	}
+
 	// At this point buf could contain multiple embedded \n's, so we need to slice it up
 	// into multiple lines and emit each line separately.
 	lines := bytes.Split(buf.Bytes(), []byte("\n"))
@@ -314,50 +1160,128 @@ func (l *Logger) emitAsOneOrMoreLogLinesImpl(buf, header *buffer) {
 			continue
 		}
 
-		// Writes need to happen as a single call, so concatenate all the data
-		// we want to write as a single line and the write that buffer out.
-		buf := l.getBuffer()
-		buf.Write(header.Bytes())
-		buf.Write(pline)
-		buf.Write([]byte("\n"))
+		overhead := header.Len() + len(suffix) + 1
+		if l.maxLineLength <= 0 || len(pline)+overhead <= l.maxLineLength {
+			l.writeLine(e, header, pline, suffix, 0, 1)
+		} else {
+			parts := splitLine(pline, overhead, l.maxLineLength)
+			for i, part := range parts {
+				l.writeLine(e, header, part, suffix, i, len(parts))
+				if cont != nil {
+					header = cont
+				}
+			}
+		}
+		if cont != nil {
+			header = cont
+		}
+	}
+}
 
-		l.w.Write(buf.Bytes())
+// continuationHeader returns a copy of header with the severity letter of s
+// in lower case, for Options.MarkContinuations.
+func (l *Logger) continuationHeader(s severity, header *buffer) *buffer {
+	cont := l.getBuffer()
+	cont.Write(header.Bytes())
+	b := cont.Bytes()
+	if i := bytes.IndexByte(b, s.char()); i >= 0 && b[i] >= 'A' && b[i] <= 'Z' {
+		b[i] += 'a' - 'A'
+	}
+	return cont
+}
 
-		l.putBuffer(buf)
+// writeLine writes a single line of the entry described by e, which is the
+// i'th of n parts.
+func (l *Logger) writeLine(e entryInfo, header *buffer, pline, suffix []byte, i, n int) {
+	// Writes need to happen as a single call, so concatenate all the data
+	// we want to write as a single line and the write that buffer out.
+	buf := l.getBuffer()
+	buf.Write(header.Bytes())
+	buf.Write(pline)
+	buf.Write(suffix)
+	if n > 1 {
+		fmt.Fprintf(buf, " part=%d/%d", i+1, n)
 	}
+	buf.Write([]byte("\n"))
+
+	l.write(e, buf.Bytes())
+	atomic.AddUint64(&l.linesWritten, 1)
+	l.tap(e, buf.Bytes())
+
+	l.putBuffer(buf)
 }
 
-// stacks is a wrapper for runtime.Stack that attempts to recover the data for all goroutines.
-func stacks(all bool) []byte {
+// defaultMaxStackSize is the default for Options.MaxStackSize.
+const defaultMaxStackSize = 1600000
+
+// stacksTruncated ends stack traces that were truncated.
+const stacksTruncated = "\n... stack traces truncated\n"
+
+// stacks calls f with the stack traces of all goroutines, truncated to
+// l.maxStackSize bytes. They're captured in l.stackBuf, which is grown as
+// needed, and is only valid until f returns.
+func (l *Logger) stacks(f func(trace []byte)) {
+	l.stackMu.Lock()
+	defer l.stackMu.Unlock()
 	// We don't know how big the traces are, so grow a few times if they don't fit. Start large, though.
-	n := 10000
-	if all {
+	n := len(l.stackBuf)
+	if n == 0 {
 		n = 100000
 	}
-	var trace []byte
-	for i := 0; i < 5; i++ {
-		trace = make([]byte, n)
-		nbytes := runtime.Stack(trace, all)
-		if nbytes < len(trace) {
-			return trace[:nbytes]
+	if n > l.maxStackSize {
+		n = l.maxStackSize
+	}
+	for {
+		if len(l.stackBuf) < n {
+			l.stackBuf = make([]byte, n)
+		}
+		nbytes := runtime.Stack(l.stackBuf[:n], true)
+		if nbytes < n {
+			f(l.stackBuf[:nbytes])
+			return
+		}
+		if n == l.maxStackSize {
+			break
 		}
 		n *= 2
+		if n > l.maxStackSize {
+			n = l.maxStackSize
+		}
+	}
+	trace := l.stackBuf[:n]
+	if len(trace) > len(stacksTruncated) {
+		copy(trace[len(trace)-len(stacksTruncated):], stacksTruncated)
 	}
-	return trace
+	f(trace)
 }
 
 func (l *Logger) Debug(args ...interface{}) {
-	if l.includeDebug {
+	if l.debugEnabled(0) {
 		l.print(debugLog, args...)
 	}
 }
 
 func (l *Logger) Debugf(format string, args ...interface{}) {
-	if l.includeDebug {
+	if l.debugEnabled(0) {
 		l.printf(debugLog, format, args...)
 	}
 }
 
+// DebugFields logs msg along with fields if Debug logs are being emitted.
+func (l *Logger) DebugFields(msg string, fields ...Field) {
+	if l.debugEnabled(0) {
+		l.printFields(debugLog, msg, fields)
+	}
+}
+
+// Debugw logs msg along with the alternating keys and values in
+// keysAndValues, as for With, if Debug logs are being emitted.
+func (l *Logger) Debugw(msg string, keysAndValues ...interface{}) {
+	if l.debugEnabled(0) {
+		l.printFields(debugLog, msg, l.kvFields(keysAndValues))
+	}
+}
+
 func (l *Logger) Info(args ...interface{}) {
 	l.print(infoLog, args...)
 }
@@ -366,6 +1290,17 @@ func (l *Logger) Infof(format string, args ...interface{}) {
 	l.printf(infoLog, format, args...)
 }
 
+// InfoFields logs msg along with fields.
+func (l *Logger) InfoFields(msg string, fields ...Field) {
+	l.printFields(infoLog, msg, fields)
+}
+
+// Infow logs msg along with the alternating keys and values in
+// keysAndValues, as for With.
+func (l *Logger) Infow(msg string, keysAndValues ...interface{}) {
+	l.printFields(infoLog, msg, l.kvFields(keysAndValues))
+}
+
 func (l *Logger) Warning(args ...interface{}) {
 	l.print(warningLog, args...)
 }
@@ -374,6 +1309,17 @@ func (l *Logger) Warningf(format string, args ...interface{}) {
 	l.printf(warningLog, format, args...)
 }
 
+// WarningFields logs msg along with fields.
+func (l *Logger) WarningFields(msg string, fields ...Field) {
+	l.printFields(warningLog, msg, fields)
+}
+
+// Warningw logs msg along with the alternating keys and values in
+// keysAndValues, as for With.
+func (l *Logger) Warningw(msg string, keysAndValues ...interface{}) {
+	l.printFields(warningLog, msg, l.kvFields(keysAndValues))
+}
+
 func (l *Logger) Error(args ...interface{}) {
 	l.print(errorLog, args...)
 }
@@ -382,6 +1328,17 @@ func (l *Logger) Errorf(format string, args ...interface{}) {
 	l.printf(errorLog, format, args...)
 }
 
+// ErrorFields logs msg along with fields.
+func (l *Logger) ErrorFields(msg string, fields ...Field) {
+	l.printFields(errorLog, msg, fields)
+}
+
+// Errorw logs msg along with the alternating keys and values in
+// keysAndValues, as for With.
+func (l *Logger) Errorw(msg string, keysAndValues ...interface{}) {
+	l.printFields(errorLog, msg, l.kvFields(keysAndValues))
+}
+
 func (l *Logger) Fatal(args ...interface{}) {
 	l.print(fatalLog, args...)
 }
@@ -390,12 +1347,43 @@ func (l *Logger) Fatalf(format string, args ...interface{}) {
 	l.printf(fatalLog, format, args...)
 }
 
+// FatalFields logs msg along with fields, then exits the program.
+func (l *Logger) FatalFields(msg string, fields ...Field) {
+	l.printFields(fatalLog, msg, fields)
+}
+
+// Fatalw logs msg along with the alternating keys and values in
+// keysAndValues, as for With, then exits the program.
+func (l *Logger) Fatalw(msg string, keysAndValues ...interface{}) {
+	l.printFields(fatalLog, msg, l.kvFields(keysAndValues))
+}
+
+// Log logs at severity s, which may be one added by RegisterSeverity, in
+// the manner of fmt.Print. It exits if s is FatalSeverity, and ignores an
+// unknown s.
+func (l *Logger) Log(s Severity, args ...interface{}) {
+	if !s.valid() || (s == DebugSeverity && !l.debugEnabled(0)) {
+		return
+	}
+	l.print(severity(s), args...)
+}
+
+// Logf logs at severity s, which may be one added by RegisterSeverity, in
+// the manner of fmt.Printf. It exits if s is FatalSeverity, and ignores an
+// unknown s.
+func (l *Logger) Logf(s Severity, format string, args ...interface{}) {
+	if !s.valid() || (s == DebugSeverity && !l.debugEnabled(0)) {
+		return
+	}
+	l.printf(severity(s), format, args...)
+}
+
 func (l *Logger) Raw(s string) {
-	l.w.Write([]byte(s))
+	l.write(entryInfo{s: infoLog}, []byte(s))
 	if s[len(s)-1] != '\n' {
-		l.w.Write([]byte{'\n'})
+		l.write(entryInfo{s: infoLog}, []byte{'\n'})
 	}
 }
 
-// Assert that we implement the slog.Logger interface:
-var _ slog.Logger = (*Logger)(nil)
+// Assert that we implement Interface:
+var _ Interface = (*Logger)(nil)
@@ -18,6 +18,7 @@ package logger
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
@@ -185,6 +186,56 @@ func TestHeader(t *testing.T) {
 	}
 }
 
+func TestTimePrecision(t *testing.T) {
+	ts := time.Date(2006, 1, 2, 15, 4, 5, 67890123, time.Local)
+	tests := map[TimePrecision]string{
+		SecondPrecision:      "I0102 15:04:05 ",
+		MillisecondPrecision: "I0102 15:04:05.067 ",
+		MicrosecondPrecision: "I0102 15:04:05.067890 ",
+		NanosecondPrecision:  "I0102 15:04:05.067890123 ",
+	}
+	for precision, want := range tests {
+		l := NewFromOptions(&Options{
+			SyncWriter:    &flushBuffer{},
+			TimePrecision: precision,
+			Now:           func() time.Time { return ts },
+			PID:           1234,
+		})
+		entries := l.CaptureLogs(func() { l.Info("test") })
+		if len(entries) != 1 || !entries[0].Time.Equal(ts.Truncate(time.Duration(pow10(9-precision.digits())))) {
+			t.Errorf("Wrong parsed time for precision %d: %#v", precision, entries)
+		}
+		l.Info("test")
+		got := l.w.(*flushBuffer).String()
+		if !strings.HasPrefix(got, want+"   1234 logger_test.go:") {
+			t.Errorf("Got %q want prefix %q", got, want)
+		}
+	}
+}
+
+func TestLocation(t *testing.T) {
+	ts := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	tokyo := time.FixedZone("JST", 9*60*60)
+	for loc, want := range map[*time.Location]string{
+		time.UTC: "I0102 15:04:05.000000 ",
+		tokyo:    "I0103 00:04:05.000000 ",
+	} {
+		l := NewFromOptions(&Options{
+			SyncWriter: &flushBuffer{},
+			Location:   loc,
+			Now:        func() time.Time { return ts },
+		})
+		l.Info("test")
+		if got := l.w.(*flushBuffer).String(); !strings.HasPrefix(got, want) {
+			t.Errorf("Got %q want prefix %q", got, want)
+		}
+		entries := l.CaptureLogs(func() { l.Info("test") })
+		if len(entries) != 1 || !entries[0].Time.Equal(ts) {
+			t.Errorf("Wrong parsed time in %s: %#v", loc, entries)
+		}
+	}
+}
+
 func logFromADepth() {
 	testLogger.Info("test")
 }
@@ -259,3 +310,149 @@ func BenchmarkHeader(b *testing.B) {
 		testLogger.putBuffer(buf)
 	}
 }
+
+// Test that StrictGlog reports the goroutine id padded to 5 characters,
+// where the default header reports the pid padded to 7 characters.
+func TestStrictGlogHeader(t *testing.T) {
+	l := NewFromOptions(&Options{
+		SyncWriter: &flushBuffer{},
+		StrictGlog: true,
+	})
+	defer func(previous func() time.Time) { timeNow = previous }(timeNow)
+	timeNow = func() time.Time {
+		return time.Date(2006, 1, 2, 15, 4, 5, .067890e9, time.Local)
+	}
+	l.Info("test")
+	got := l.w.(*flushBuffer).String()
+	tid := goroutineID()
+	want := fmt.Sprintf("I0102 15:04:05.067890 %5d logger_test.go:", tid)
+	if !strings.HasPrefix(got, want) {
+		t.Errorf("log format error: got:\n\t%q\nwant prefix:\t%q", got, want)
+	}
+}
+
+func TestNumDigits(t *testing.T) {
+	for d, want := range map[int]int{0: 1, 9: 1, 10: 2, 99999: 5, 1234567: 7} {
+		if got := numDigits(d); got != want {
+			t.Errorf("numDigits(%d) got %d want %d", d, got, want)
+		}
+	}
+}
+
+func TestGoroutineID(t *testing.T) {
+	id := goroutineID()
+	if id == 0 {
+		t.Fatal("Failed to get the goroutine id.")
+	}
+	other := make(chan uint64)
+	go func() { other <- goroutineID() }()
+	if id == <-other {
+		t.Error("Different goroutines should have different ids.")
+	}
+}
+
+// Test that Loggers given their own clock, exit function, and pid don't
+// depend on the package level state.
+func TestInstanceIsolation(t *testing.T) {
+	for i := 1; i <= 2; i++ {
+		i := i
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			t.Parallel()
+			exitCode := 0
+			l := NewFromOptions(&Options{
+				SyncWriter: &flushBuffer{},
+				Now:        func() time.Time { return time.Date(2006, time.Month(i), 2, 15, 4, 5, 0, time.Local) },
+				Exit:       func(code int) { exitCode = code },
+				PID:        1000 * i,
+			})
+			l.Fatal("done")
+			got := l.w.(*flushBuffer).String()
+			if want := fmt.Sprintf("F0%d02 15:04:05.000000    %d logger_test.go:", i, 1000*i); !strings.HasPrefix(got, want) {
+				t.Errorf("Got %q want prefix %q", got, want)
+			}
+			if exitCode != 255 {
+				t.Errorf("Exit not called, got code %d", exitCode)
+			}
+		})
+	}
+}
+
+// Test the number of allocations of the hot paths, so that any increase is
+// a deliberate decision.
+func TestAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("The race detector changes allocation counts.")
+	}
+	l := NewFromOptions(&Options{SyncWriter: discardWriter{}})
+	for _, tc := range []struct {
+		name string
+		max  float64
+		f    func()
+	}{
+		{"Info", 6, func() { l.Info("request served") }},
+		{"Infof", 6, func() { l.Infof("served %s in %d ms", "/index.html", 12) }},
+		{"InfoFields", 6, func() {
+			l.InfoFields("request served", Str("path", "/index.html"), Int("status", 200), Dur("latency", time.Millisecond))
+		}},
+	} {
+		if n := testing.AllocsPerRun(100, tc.f); n > tc.max {
+			t.Errorf("%s: got %v allocations want at most %v", tc.name, n, tc.max)
+		}
+	}
+}
+
+func TestMaxStackSize(t *testing.T) {
+	b := &flushBuffer{}
+	l := NewFromOptions(&Options{SyncWriter: b, MaxStackSize: 200, Format: JSONFormat, Exit: func(int) {}})
+	buf := l.stackBuf
+	l.Fatal("out of memory")
+
+	var entry struct{ Stack string }
+	if err := json.Unmarshal(b.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if len(entry.Stack) != 200 || !strings.HasSuffix(entry.Stack, stacksTruncated) {
+		t.Errorf("want 200 bytes of truncated stack traces, got %d: %q", len(entry.Stack), entry.Stack)
+	}
+	if &l.stackBuf[0] != &buf[0] {
+		t.Error("the preallocated buffer wasn't reused")
+	}
+}
+
+func TestMarkContinuations(t *testing.T) {
+	b := &flushBuffer{}
+	l := NewFromOptions(&Options{SyncWriter: b, MarkContinuations: true, MaxLineLength: 80})
+	l.Warning("first\nsecond\n" + strings.Repeat("x", 50))
+	l.Info("single")
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	want := "WwwwwI"
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d: %q", len(lines), len(want), lines)
+	}
+	for i, line := range lines {
+		if line[0] != want[i] {
+			t.Errorf("line %d: got %q, want it to start with %c", i, line, want[i])
+		}
+	}
+
+	entries := parseEntries(b.String(), 2024, time.UTC)
+	if len(entries) != 2 || !strings.HasPrefix(entries[0].Message, "first\nsecond\nxxx") || entries[1].Message != "single" {
+		t.Errorf("continuations weren't joined: %+v", entries)
+	}
+}
+
+func TestMarkContinuationsFatal(t *testing.T) {
+	b := &flushBuffer{}
+	l := NewFromOptions(&Options{SyncWriter: b, MarkContinuations: true, Exit: func(int) {}})
+	l.Fatal("bye")
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if lines[0][0] != 'F' {
+		t.Errorf("got %q, want an F header", lines[0])
+	}
+	for _, line := range lines[1:] {
+		if line[0] != 'f' {
+			t.Fatalf("stack trace line %q isn't marked as a continuation", line)
+		}
+	}
+}
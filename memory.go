@@ -0,0 +1,57 @@
+package logger
+
+import "sync/atomic"
+
+// MemoryStats reports on the memory a Logger holds on to between log calls.
+type MemoryStats struct {
+	// Used is the number of bytes held in reusable buffers, in lines
+	// queued for Inspector tails, in writes queued by the AsyncWriter of
+	// Options.AsyncQueueSize, and in entries kept for Options.DebugBacklog
+	// and Options.FlightRecorder.
+	Used int64
+
+	// Max is Options.MaxMemory, zero meaning no limit.
+	Max int64
+
+	// Dropped is the number of lines not queued for tails, writes not
+	// queued by the AsyncWriter, and entries not kept for the Debug backlog
+	// or flight recorder, because doing so would have exceeded Max.
+	Dropped uint64
+}
+
+// MemoryStats returns the Logger's current memory usage.
+func (l *Logger) MemoryStats() MemoryStats {
+	return MemoryStats{
+		Used:    atomic.LoadInt64(&l.memUsed),
+		Max:     l.maxMemory,
+		Dropped: atomic.LoadUint64(&l.memDropped),
+	}
+}
+
+// reserveMemory accounts for n more bytes being held, returning false and
+// not accounting for them if that would exceed Options.MaxMemory.
+func (s *loggerState) reserveMemory(n int) bool {
+	if s.maxMemory <= 0 {
+		atomic.AddInt64(&s.memUsed, int64(n))
+		return true
+	}
+	for {
+		used := atomic.LoadInt64(&s.memUsed)
+		if used+int64(n) > s.maxMemory {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&s.memUsed, used, used+int64(n)) {
+			return true
+		}
+	}
+}
+
+// releaseMemory accounts for n bytes no longer being held.
+func (s *loggerState) releaseMemory(n int) {
+	atomic.AddInt64(&s.memUsed, -int64(n))
+}
+
+// dropMemory counts something dropped to stay within Options.MaxMemory.
+func (s *loggerState) dropMemory() {
+	atomic.AddUint64(&s.memDropped, 1)
+}
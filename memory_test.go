@@ -0,0 +1,159 @@
+package logger
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMaxMemoryFreeList(t *testing.T) {
+	l := NewFromOptions(&Options{SyncWriter: &discardWriter{}, MaxMemory: 100})
+	var bufs []*buffer
+	for i := 0; i < 10; i++ {
+		b := l.getBuffer()
+		b.WriteString(strings.Repeat("x", 60))
+		bufs = append(bufs, b)
+	}
+	for _, b := range bufs {
+		l.putBuffer(b)
+	}
+	if got := l.MemoryStats().Used; got > 100 || got == 0 {
+		t.Errorf("Wrong memory used: %d", got)
+	}
+	if n := l.bufferCacheLen(); n != 1 {
+		t.Errorf("Expected one buffer kept, got %d", n)
+	}
+	l.getBuffer()
+	if got := l.MemoryStats().Used; got != 0 {
+		t.Errorf("Memory not released: %d", got)
+	}
+}
+
+func TestMaxMemoryTaps(t *testing.T) {
+	l := NewFromOptions(&Options{SyncWriter: &discardWriter{}, MaxMemory: 1000})
+	c := l.addTap(nil)
+	for i := 0; i < 100; i++ {
+		l.Info("a line that takes up some memory")
+	}
+	stats := l.MemoryStats()
+	if stats.Used > stats.Max {
+		t.Errorf("Memory used %d exceeds max %d", stats.Used, stats.Max)
+	}
+	if stats.Dropped == 0 || len(c) == 0 || len(c) == 100 {
+		t.Errorf("Expected some lines to be dropped: %#v, %d queued", stats, len(c))
+	}
+	l.removeTap(c)
+	var free int64
+	for b := l.freeList; b != nil; b = b.next {
+		free += int64(b.Cap())
+	}
+	if got := l.MemoryStats().Used; got != free {
+		t.Errorf("Memory for queued lines not released, got %d want %d", got, free)
+	}
+}
+
+func TestMaxMemoryAsyncWriter(t *testing.T) {
+	for policy, want := range map[QueueFullPolicy]struct {
+		written string
+		dropped uint64
+	}{
+		BlockWhenFull:      {"a\nb\nc\n", 0},
+		DropNewestWhenFull: {"a\nb\n", 1},
+		DropOldestWhenFull: {"a\nc\n", 1},
+	} {
+		g := &gatedWriter{gate: make(chan struct{})}
+		a := NewAsyncWriterWithPolicy(g, 10, policy)
+		mem := &loggerState{maxMemory: 4}
+		a.mem = mem
+		a.Write([]byte("a\n"))
+		// Wait for "a" to be taken off the queue, still holding its memory,
+		// so "b" uses up the rest.
+		for a.QueueDepth() != 0 {
+			time.Sleep(time.Millisecond)
+		}
+		a.Write([]byte("b\n"))
+		written := make(chan struct{})
+		go func() {
+			a.Write([]byte("c\n"))
+			close(written)
+		}()
+		if policy == BlockWhenFull {
+			select {
+			case <-written:
+				t.Errorf("Policy %d: write didn't wait for memory", policy)
+			case <-time.After(10 * time.Millisecond):
+			}
+		} else {
+			<-written
+		}
+		close(g.gate)
+		<-written
+		a.Flush()
+		if got := g.String(); got != want.written {
+			t.Errorf("Policy %d: got %q, want %q", policy, got, want.written)
+		}
+		if got := atomic.LoadInt64(&mem.memUsed); got != 0 {
+			t.Errorf("Policy %d: memory not released: %d", policy, got)
+		}
+		if a.Dropped() != want.dropped || mem.memDropped != want.dropped {
+			t.Errorf("Policy %d: got %d and %d dropped, want %d", policy, a.Dropped(), mem.memDropped, want.dropped)
+		}
+		a.Close()
+	}
+}
+
+func TestMaxMemoryAsyncQueueSize(t *testing.T) {
+	g := &gatedWriter{gate: make(chan struct{})}
+	l := NewFromOptions(&Options{SyncWriter: g, AsyncQueueSize: 100, AsyncQueueFull: DropNewestWhenFull, MaxMemory: 1000})
+	for i := 0; i < 100; i++ {
+		l.Info("a line that takes up some memory")
+	}
+	stats := l.MemoryStats()
+	if stats.Used > stats.Max || stats.Dropped == 0 || l.DroppedWrites() != stats.Dropped {
+		t.Errorf("Queued writes not bounded: %#v, %d dropped writes", stats, l.DroppedWrites())
+	}
+	close(g.gate)
+	l.Shutdown(context.Background())
+}
+
+func TestMaxMemoryFlightRecorder(t *testing.T) {
+	l := NewFromOptions(&Options{SyncWriter: &discardWriter{}, FlightRecorder: 100, MaxMemory: 2000})
+	for i := 0; i < 100; i++ {
+		l.Infof("entry %d", i)
+	}
+	stats := l.MemoryStats()
+	if stats.Used > stats.Max || stats.Dropped == 0 {
+		t.Errorf("Flight recorder not bounded: %#v", stats)
+	}
+	entries := l.flight.take()
+	if len(entries) == 0 || len(entries) == 100 || string(entries[len(entries)-1].msg) != "entry 99" {
+		t.Fatalf("Want the most recent entries kept, got %d", len(entries))
+	}
+	if string(entries[0].msg) == "entry 0" {
+		t.Error("Want the oldest entries discarded")
+	}
+}
+
+func TestMaxMemoryDebugBacklog(t *testing.T) {
+	l := NewFromOptions(&Options{SyncWriter: &discardWriter{}, DebugBacklog: 100, MaxMemory: 2000})
+	for i := 0; i < 100; i++ {
+		l.Debugf("entry %d", i)
+	}
+	stats := l.MemoryStats()
+	if stats.Used > stats.Max || stats.Dropped == 0 {
+		t.Errorf("Debug backlog not bounded: %#v", stats)
+	}
+	entries := l.debugBacklog.take(goroutineID())
+	if len(entries) == 0 || len(entries) == 100 || string(entries[len(entries)-1].msg) != "entry 99" {
+		t.Fatalf("Want the most recent entries kept, got %d", len(entries))
+	}
+	var free int64
+	for b := l.freeList; b != nil; b = b.next {
+		free += int64(b.Cap())
+	}
+	if got := l.MemoryStats().Used; got != free {
+		t.Errorf("Memory for entries taken not released, got %d want %d", got, free)
+	}
+}
@@ -0,0 +1,184 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The metadata service endpoints, variables so they can be replaced for testing.
+var (
+	gceMetadataURL = "http://metadata.google.internal/computeMetadata/v1"
+	ec2MetadataURL = "http://169.254.169.254/latest"
+)
+
+// InstanceMetadata describes the cloud instance the process is running on.
+//
+// Pass it in Options.InstanceMetadata to have it stamped onto every log line.
+type InstanceMetadata struct {
+	// Provider is either "gce" or "ec2".
+	Provider string
+
+	// InstanceID is the provider assigned id of the instance.
+	InstanceID string
+
+	// Zone is the zone, or availability zone, the instance is running in.
+	Zone string
+
+	// Tags are the instance tags. GCE network tags have no values and are
+	// recorded with an empty value.
+	Tags map[string]string
+}
+
+// FetchInstanceMetadata queries the GCE and then the EC2 metadata services
+// for information about the running instance. It gives up after timeout,
+// which keeps startup from hanging when not running in the cloud.
+func FetchInstanceMetadata(timeout time.Duration) (*InstanceMetadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	client := &http.Client{}
+	m, gceErr := fetchGCEMetadata(ctx, client)
+	if gceErr == nil {
+		return m, nil
+	}
+	m, ec2Err := fetchEC2Metadata(ctx, client)
+	if ec2Err == nil {
+		return m, nil
+	}
+	return nil, fmt.Errorf("no metadata service found: gce: %s, ec2: %s", gceErr, ec2Err)
+}
+
+// metadataGet does a single request against a metadata service and returns the body.
+func metadataGet(ctx context.Context, client *http.Client, method, url string, headers map[string]string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func fetchGCEMetadata(ctx context.Context, client *http.Client) (*InstanceMetadata, error) {
+	headers := map[string]string{"Metadata-Flavor": "Google"}
+	id, err := metadataGet(ctx, client, "GET", gceMetadataURL+"/instance/id", headers)
+	if err != nil {
+		return nil, err
+	}
+	zone, err := metadataGet(ctx, client, "GET", gceMetadataURL+"/instance/zone", headers)
+	if err != nil {
+		return nil, err
+	}
+	// The zone comes back as "projects/<number>/zones/<zone>".
+	if slash := strings.LastIndex(zone, "/"); slash >= 0 {
+		zone = zone[slash+1:]
+	}
+	ret := &InstanceMetadata{
+		Provider:   "gce",
+		InstanceID: id,
+		Zone:       zone,
+		Tags:       map[string]string{},
+	}
+	tags, err := metadataGet(ctx, client, "GET", gceMetadataURL+"/instance/tags?alt=json", headers)
+	if err != nil {
+		return ret, nil // Tags are optional.
+	}
+	var names []string
+	if err := json.Unmarshal([]byte(tags), &names); err != nil {
+		return nil, fmt.Errorf("decoding tags: %s", err)
+	}
+	for _, name := range names {
+		ret.Tags[name] = ""
+	}
+	return ret, nil
+}
+
+func fetchEC2Metadata(ctx context.Context, client *http.Client) (*InstanceMetadata, error) {
+	// IMDSv2 requires a session token.
+	token, err := metadataGet(ctx, client, "PUT", ec2MetadataURL+"/api/token", map[string]string{"X-aws-ec2-metadata-token-ttl-seconds": "60"})
+	if err != nil {
+		return nil, err
+	}
+	headers := map[string]string{"X-aws-ec2-metadata-token": token}
+	id, err := metadataGet(ctx, client, "GET", ec2MetadataURL+"/meta-data/instance-id", headers)
+	if err != nil {
+		return nil, err
+	}
+	zone, err := metadataGet(ctx, client, "GET", ec2MetadataURL+"/meta-data/placement/availability-zone", headers)
+	if err != nil {
+		return nil, err
+	}
+	ret := &InstanceMetadata{
+		Provider:   "ec2",
+		InstanceID: id,
+		Zone:       zone,
+		Tags:       map[string]string{},
+	}
+	// Tags are only available if the instance has them enabled in metadata, so
+	// failures here are not errors.
+	keys, err := metadataGet(ctx, client, "GET", ec2MetadataURL+"/meta-data/tags/instance", headers)
+	if err != nil || keys == "" {
+		return ret, nil
+	}
+	for _, key := range strings.Split(keys, "\n") {
+		value, err := metadataGet(ctx, client, "GET", ec2MetadataURL+"/meta-data/tags/instance/"+key, headers)
+		if err != nil {
+			continue
+		}
+		ret.Tags[key] = value
+	}
+	return ret, nil
+}
+
+// fields returns the metadata as the Fields stamped onto each log line,
+// with the tags in a "tag" Group.
+func (m *InstanceMetadata) fields() []Field {
+	if m == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(m.Tags))
+	for k := range m.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	tags := make([]Field, len(keys))
+	for i, k := range keys {
+		tags[i] = Str(k, m.Tags[k])
+	}
+	return []Field{
+		Str("provider", m.Provider),
+		Str("instance_id", m.InstanceID),
+		Str("zone", m.Zone),
+		Group("tag", tags...),
+	}
+}
+
+// stamp renders the metadata as the key=value pairs appended to each log line.
+func (m *InstanceMetadata) stamp() []byte {
+	if m == nil {
+		return nil
+	}
+	buf := &buffer{}
+	for _, f := range m.fields() {
+		f.appendTo(buf)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetchInstanceMetadataGCE(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "missing header", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/instance/id":
+			w.Write([]byte("1234567890"))
+		case "/instance/zone":
+			w.Write([]byte("projects/42/zones/us-central1-a"))
+		case "/instance/tags":
+			w.Write([]byte(`["web","prod"]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	defer func(previous string) { gceMetadataURL = previous }(gceMetadataURL)
+	gceMetadataURL = ts.URL
+
+	m, err := FetchInstanceMetadata(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Provider != "gce" || m.InstanceID != "1234567890" || m.Zone != "us-central1-a" {
+		t.Errorf("Wrong metadata: %#v", m)
+	}
+	if got, want := string(m.stamp()), ` provider=gce instance_id=1234567890 zone=us-central1-a tag.prod="" tag.web=""`; got != want {
+		t.Errorf("Wrong stamp, got %q want %q", got, want)
+	}
+}
+
+func TestFetchInstanceMetadataEC2(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/token" {
+			if r.Method != "PUT" {
+				http.Error(w, "token requires PUT", http.StatusMethodNotAllowed)
+				return
+			}
+			w.Write([]byte("secret"))
+			return
+		}
+		if r.Header.Get("X-aws-ec2-metadata-token") != "secret" {
+			http.Error(w, "bad token", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/meta-data/instance-id":
+			w.Write([]byte("i-0abc"))
+		case "/meta-data/placement/availability-zone":
+			w.Write([]byte("us-east-1b"))
+		case "/meta-data/tags/instance":
+			w.Write([]byte("Name\nteam"))
+		case "/meta-data/tags/instance/Name":
+			w.Write([]byte("frontend 1"))
+		case "/meta-data/tags/instance/team":
+			w.Write([]byte("infra"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	defer func(previous string) { gceMetadataURL = previous }(gceMetadataURL)
+	defer func(previous string) { ec2MetadataURL = previous }(ec2MetadataURL)
+	gceMetadataURL = ts.URL + "/not-gce"
+	ec2MetadataURL = ts.URL
+
+	m, err := FetchInstanceMetadata(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(m.stamp()), ` provider=ec2 instance_id=i-0abc zone=us-east-1b tag.Name="frontend 1" tag.team=infra`; got != want {
+		t.Errorf("Wrong stamp, got %q want %q", got, want)
+	}
+}
+
+func TestFetchInstanceMetadataNotFound(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+	defer func(previous string) { gceMetadataURL = previous }(gceMetadataURL)
+	defer func(previous string) { ec2MetadataURL = previous }(ec2MetadataURL)
+	gceMetadataURL = ts.URL
+	ec2MetadataURL = ts.URL
+
+	if _, err := FetchInstanceMetadata(time.Second); err == nil {
+		t.Error("Expected an error when no metadata service is present.")
+	}
+}
+
+// Test that the metadata is stamped onto every line.
+func TestInstanceMetadataStamp(t *testing.T) {
+	l := NewFromOptions(&Options{
+		SyncWriter: &flushBuffer{},
+		InstanceMetadata: &InstanceMetadata{
+			Provider:   "gce",
+			InstanceID: "123",
+			Zone:       "us-west1-b",
+		},
+	})
+	l.Info("foo\nbar")
+	lines := strings.Split(l.w.(*flushBuffer).String(), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Wrong number of lines, got: %d want: 3", len(lines))
+	}
+	for _, line := range lines[:2] {
+		if !strings.HasSuffix(line, " provider=gce instance_id=123 zone=us-west1-b") {
+			t.Errorf("Missing metadata: %q", line)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package logger
+
+import (
+	"io"
+	"os"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// entryInfo describes the entry being written, for the destinations that
+// depend on it.
+type entryInfo struct {
+	s severity
+
+	// file is the base name of the file of the call site, and line its
+	// line.
+	file string
+	line int
+
+	// time is when the entry was logged.
+	time time.Time
+
+	// name is the name of the Logger, see Named.
+	name   string
+	fields []Field
+
+	// written, if not nil, counts the bytes of the entry written to the
+	// destination, see buffer.written.
+	written *int
+}
+
+// entryInfo returns the entryInfo for an entry of severity s, made from the
+// call site in header, with fields. The fields are only included, as a
+// copy, if the destination is an entryWriter, or there are taps to filter
+// by them, so that otherwise they aren't moved to the heap.
+func (l *Logger) entryInfo(s severity, header *buffer, fields []Field) entryInfo {
+	e := entryInfo{s: s, file: header.file, line: header.line, time: header.time, name: l.name, written: header.written}
+	if _, ok := l.writer().(entryWriter); ok || atomic.LoadInt32(&l.numTaps) > 0 {
+		e.fields = append([]Field(nil), fields...)
+	}
+	return e
+}
+
+// entryWriter is implemented by destinations that need to know about the
+// entries written to them, rather than just their bytes.
+type entryWriter interface {
+	SyncWriter
+
+	// writeEntry writes p, which is all or part of the entry described by
+	// e.
+	writeEntry(e entryInfo, p []byte) (int, error)
+}
+
+// writeTo writes p, which is all or part of the entry described by e, to w.
+func writeTo(w SyncWriter, e entryInfo, p []byte) error {
+	var err error
+	if ew, ok := w.(entryWriter); ok {
+		_, err = ew.writeEntry(e, p)
+	} else {
+		_, err = w.Write(p)
+	}
+	return err
+}
+
+// LeveledWriter is a destination of a MultiSyncWriter, which only gets the
+// entries at or above MinSeverity. The zero MinSeverity is DebugSeverity,
+// so it gets every entry.
+type LeveledWriter struct {
+	SyncWriter
+	MinSeverity Severity
+}
+
+// MultiSyncWriter is a SyncWriter that writes each entry to several
+// destinations, each with its own minimum severity, e.g. to write every
+// entry to stdout but only warnings and above to a file:
+//
+//	w := logger.NewMultiSyncWriter(
+//		logger.LeveledWriter{SyncWriter: os.Stdout},
+//		logger.LeveledWriter{SyncWriter: f, MinSeverity: logger.WarningSeverity},
+//	)
+//	l := logger.NewFromOptions(&logger.Options{SyncWriter: w})
+//
+// Writes of unknown severity, such as those from Crash, and writes made
+// other than through the Logger it's the destination of, go to every
+// destination.
+type MultiSyncWriter struct {
+	writers []LeveledWriter
+}
+
+// NewMultiSyncWriter returns a MultiSyncWriter that writes to writers, in
+// order.
+func NewMultiSyncWriter(writers ...LeveledWriter) *MultiSyncWriter {
+	return &MultiSyncWriter{writers: append([]LeveledWriter(nil), writers...)}
+}
+
+// newSeverityWriters returns the MultiSyncWriter for Options.SeverityWriters.
+// A writer given for several severities is only written to once per
+// entry, as if it had only been given for the least severe of them.
+func newSeverityWriters(m map[Severity]SyncWriter) *MultiSyncWriter {
+	ret := &MultiSyncWriter{}
+	for s, w := range m {
+		found := false
+		for i, lw := range ret.writers {
+			if lw.SyncWriter == w {
+				if s.less(lw.MinSeverity) {
+					ret.writers[i].MinSeverity = s
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			ret.writers = append(ret.writers, LeveledWriter{SyncWriter: w, MinSeverity: s})
+		}
+	}
+	sort.Slice(ret.writers, func(i, j int) bool {
+		return ret.writers[i].MinSeverity.less(ret.writers[j].MinSeverity)
+	})
+	return ret
+}
+
+// writeEntry implements entryWriter, returning the first error.
+func (m *MultiSyncWriter) writeEntry(e entryInfo, p []byte) (int, error) {
+	var err error
+	for _, lw := range m.writers {
+		if Severity(e.s).less(lw.MinSeverity) {
+			continue
+		}
+		if writeErr := writeTo(lw.SyncWriter, e, p); err == nil {
+			err = writeErr
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Write implements SyncWriter, writing p to every destination.
+func (m *MultiSyncWriter) Write(p []byte) (int, error) {
+	return m.writeEntry(entryInfo{s: fatalLog}, p)
+}
+
+// Sync implements SyncWriter, returning the first error.
+func (m *MultiSyncWriter) Sync() error {
+	var err error
+	for _, lw := range m.writers {
+		if syncErr := lw.Sync(); err == nil {
+			err = syncErr
+		}
+	}
+	return err
+}
+
+// Close closes the destinations that implement io.Closer, other than
+// os.Stdout and os.Stderr, so Shutdown closes them. It returns the first
+// error.
+func (m *MultiSyncWriter) Close() error {
+	var err error
+	for _, lw := range m.writers {
+		if c, ok := lw.SyncWriter.(io.Closer); ok && lw.SyncWriter != os.Stdout && lw.SyncWriter != os.Stderr {
+			if closeErr := c.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	}
+	return err
+}
@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSeverityWriters(t *testing.T) {
+	info, errs := &flushBuffer{}, &flushBuffer{}
+	l := NewFromOptions(&Options{
+		IncludeDebug: true,
+		SeverityWriters: map[Severity]SyncWriter{
+			InfoSeverity:  info,
+			ErrorSeverity: errs,
+		},
+	})
+	l.Debug("debug")
+	l.Info("info")
+	l.Warning("warning")
+	l.Error("error")
+
+	for _, test := range []struct {
+		w    *flushBuffer
+		want []string
+	}{
+		{info, []string{"info", "warning", "error"}},
+		{errs, []string{"error"}},
+	} {
+		lines := strings.Split(strings.TrimSuffix(test.w.String(), "\n"), "\n")
+		if len(lines) != len(test.want) {
+			t.Errorf("got %q, want %q", lines, test.want)
+			continue
+		}
+		for i, line := range lines {
+			if !strings.HasSuffix(line, "] "+test.want[i]) {
+				t.Errorf("got %q, want %q", line, test.want[i])
+			}
+		}
+	}
+}
+
+func TestSeverityWritersSameWriter(t *testing.T) {
+	b := &flushBuffer{}
+	l := NewFromOptions(&Options{
+		SeverityWriters: map[Severity]SyncWriter{
+			WarningSeverity: b,
+			ErrorSeverity:   b,
+		},
+	})
+	l.Info("info")
+	l.Error("error")
+	if got := b.String(); strings.Count(got, "\n") != 1 || !strings.HasSuffix(got, "] error\n") {
+		t.Errorf("want the error written once, got %q", got)
+	}
+}
+
+func TestSeverityWritersJSON(t *testing.T) {
+	info, warnings := &flushBuffer{}, &flushBuffer{}
+	l := NewFromOptions(&Options{
+		Format: JSONFormat,
+		SeverityWriters: map[Severity]SyncWriter{
+			InfoSeverity:    info,
+			WarningSeverity: warnings,
+		},
+	})
+	l.Info("info")
+	l.Warning("warning")
+	if strings.Contains(warnings.String(), `"info"`) || !strings.Contains(warnings.String(), `"warning"`) {
+		t.Errorf("wrong warning entries: %q", warnings.String())
+	}
+	if strings.Count(info.String(), "\n") != 2 {
+		t.Errorf("wrong info entries: %q", info.String())
+	}
+}
+
+func TestMultiSyncWriter(t *testing.T) {
+	all, warnings := &flushBuffer{}, &flushBuffer{}
+	w := NewMultiSyncWriter(
+		LeveledWriter{SyncWriter: all},
+		LeveledWriter{SyncWriter: warnings, MinSeverity: WarningSeverity},
+	)
+	l := NewFromOptions(&Options{SyncWriter: w, IncludeDebug: true})
+	l.Debug("debug")
+	l.Info("info")
+	l.Warning("warning")
+	l.Error("error")
+
+	if got := strings.Count(all.String(), "\n"); got != 4 {
+		t.Errorf("got %d entries, want 4: %q", got, all.String())
+	}
+	got := warnings.String()
+	if strings.Contains(got, "] info") || !strings.Contains(got, "] warning\n") || !strings.Contains(got, "] error\n") {
+		t.Errorf("wrong entries at Warning and above: %q", got)
+	}
+}
+
+func TestMultiSyncWriterErrors(t *testing.T) {
+	good, bad := &flushBuffer{}, &failingWriter{err: errors.New("disk full")}
+	w := NewMultiSyncWriter(LeveledWriter{SyncWriter: bad}, LeveledWriter{SyncWriter: good})
+	l := NewFromOptions(&Options{SyncWriter: w})
+	l.Info("still written")
+	if !strings.HasSuffix(good.String(), "] still written\n") {
+		t.Errorf("a failing destination stopped the others: %q", good.String())
+	}
+	if err := l.Healthy(); err == nil {
+		t.Error("want the write error reported")
+	}
+}
@@ -1,8 +1,6 @@
 package logger
 
-import "github.com/jcgregorio/slog"
-
-// NopLogger implements slog.Logger and does nothing.
+// NopLogger implements Interface and does nothing.
 //
 type NopLogger struct{}
 
@@ -54,5 +52,5 @@ func (*NopLogger) Debugf(format string, args ...interface{}) {}
 // Raw sends the string s to the logs without any additional formatting.
 func (*NopLogger) Raw(s string) {}
 
-// Assert that we implement the slog.Logger interface:
-var _ slog.Logger = (*NopLogger)(nil)
+// Assert that we implement Interface:
+var _ Interface = (*NopLogger)(nil)
@@ -0,0 +1,113 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultProgressInterval is used if ProgressOptions are nil or empty.
+const defaultProgressInterval = 10 * time.Second
+
+// ProgressOptions controls how often a Progress logs. If both are zero then
+// Interval defaults to 10s.
+type ProgressOptions struct {
+	// Interval, if non-zero, is the minimum time between logs.
+	Interval time.Duration
+
+	// Percent, if non-zero, logs every time another Percent of the items
+	// have been processed.
+	Percent float64
+}
+
+// Progress logs the progress of a batch job at a bounded rate, e.g.:
+//
+//	resize: processed 1200/5000 (24.0%) items, ETA 2m30s
+//
+// Create one with Logger.Progress and call Add as items are processed.
+type Progress struct {
+	l        *Logger
+	name     string
+	total    int64
+	interval time.Duration
+	percent  float64
+
+	// mu protects the fields below.
+	mu          sync.Mutex
+	done        int64
+	start       time.Time
+	lastLog     time.Time
+	nextPercent float64
+}
+
+// Progress returns a Progress for processing total items. The options may
+// be nil.
+func (l *Logger) Progress(name string, total int64, o *ProgressOptions) *Progress {
+	if o == nil {
+		o = &ProgressOptions{}
+	}
+	p := &Progress{
+		l:           l,
+		name:        name,
+		total:       total,
+		interval:    o.Interval,
+		percent:     o.Percent,
+		start:       l.timeNow(),
+		nextPercent: o.Percent,
+	}
+	if p.interval == 0 && p.percent == 0 {
+		p.interval = defaultProgressInterval
+	}
+	p.lastLog = p.start
+	return p
+}
+
+// Add records that n more items have been processed, logging the progress
+// if enough time has passed or enough items have been processed since the
+// last log. It is safe to call from multiple goroutines.
+func (p *Progress) Add(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done += n
+	now := p.l.timeNow()
+	due := p.interval > 0 && now.Sub(p.lastLog) >= p.interval
+	if p.percent > 0 && p.percentDone() >= p.nextPercent {
+		due = true
+		for p.nextPercent <= p.percentDone() {
+			p.nextPercent += p.percent
+		}
+	}
+	if !due {
+		return
+	}
+	p.lastLog = now
+	p.log(now)
+}
+
+// Done logs the final count and total time taken.
+func (p *Progress) Done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	msg := fmt.Sprintf("%s: finished %d/%d items in %s", p.name, p.done, p.total, p.l.timeNow().Sub(p.start).Round(time.Millisecond))
+	p.l.logDepth(infoLog, 0, []byte(msg), nil)
+}
+
+// percentDone returns the percentage of items done. p.mu must be held.
+func (p *Progress) percentDone() float64 {
+	if p.total <= 0 {
+		return 0
+	}
+	return 100 * float64(p.done) / float64(p.total)
+}
+
+// log writes the current progress, reporting the caller of Add. p.mu must be held.
+func (p *Progress) log(now time.Time) {
+	eta := "unknown"
+	if p.done > 0 && p.done <= p.total {
+		elapsed := now.Sub(p.start)
+		remaining := time.Duration(float64(elapsed) * float64(p.total-p.done) / float64(p.done))
+		eta = remaining.Round(time.Second).String()
+	}
+	msg := fmt.Sprintf("%s: processed %d/%d (%.1f%%) items, ETA %s", p.name, p.done, p.total, p.percentDone(), eta)
+	p.l.logDepth(infoLog, 1, []byte(msg), nil)
+}
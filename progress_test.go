@@ -0,0 +1,50 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressInterval(t *testing.T) {
+	defer func(previous func() time.Time) { timeNow = previous }(timeNow)
+	now := time.Date(2006, 1, 2, 15, 4, 5, 0, time.Local)
+	timeNow = func() time.Time { return now }
+
+	newTestLogger()
+	p := testLogger.Progress("resize", 100, &ProgressOptions{Interval: 10 * time.Second})
+	for i := 0; i < 25; i++ {
+		now = now.Add(time.Second)
+		p.Add(1)
+	}
+	p.Done()
+	lines := strings.Split(strings.TrimSpace(contents()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Wrong number of lines, got %d want 3: %q", len(lines), lines)
+	}
+	if want := "] resize: processed 10/100 (10.0%) items, ETA 1m30s"; !strings.HasSuffix(lines[0], want) {
+		t.Errorf("Got %q want suffix %q", lines[0], want)
+	}
+	if want := "] resize: finished 25/100 items in 25s"; !strings.HasSuffix(lines[2], want) {
+		t.Errorf("Got %q want suffix %q", lines[2], want)
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, " progress_test.go:") {
+			t.Errorf("Wrong caller reported: %q", line)
+		}
+	}
+}
+
+func TestProgressPercent(t *testing.T) {
+	newTestLogger()
+	p := testLogger.Progress("copy", 200, &ProgressOptions{Percent: 25})
+	for i := 0; i < 200; i++ {
+		p.Add(1)
+	}
+	if got := strings.Count(contents(), "\n"); got != 4 {
+		t.Errorf("Expected a log at each 25%%, got %d: %q", got, contents())
+	}
+	if !strings.Contains(contents(), "processed 150/200 (75.0%) items") {
+		t.Errorf("Missing the 75%% log: %q", contents())
+	}
+}
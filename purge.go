@@ -0,0 +1,193 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Purger may be implemented by a SyncWriter that holds entries before
+// writing them, such as AsyncWriter and ForwardWriter, or that sends them
+// to others that may, as Router does, so they can be removed by
+// Logger.Purge.
+type Purger interface {
+	// Purge removes the entries not yet written that contain subject, and
+	// returns how many.
+	Purge(subject string) int
+}
+
+// Purge removes the entries that contain subject, such as the id of a
+// person who asked for their data to be erased, from the memory of the
+// Logger: the entries kept by Options.FlightRecorder and
+// Options.DebugBacklog, and those queued by the destination, if it
+// implements Purger. It returns the number of entries removed. An entry
+// contains subject if its message, its fields, or those of its Logger, as
+// written by TextFormat, do. For a PIIStr field pass its hash, see PIIHash.
+//
+// Entries that have already been written, including to a tail of an
+// Inspector, aren't affected, see PurgeFile for those in log files.
+func (l *Logger) Purge(subject string) int {
+	if subject == "" {
+		return 0
+	}
+	n := 0
+	if l.flight != nil {
+		n += l.flight.purge(subject)
+	}
+	if l.debugBacklog != nil {
+		n += l.debugBacklog.purge(subject)
+	}
+	if p, ok := l.writer().(Purger); ok {
+		n += p.Purge(subject)
+	}
+	return n
+}
+
+// contains returns true if the message or fields of e contain subject.
+func (e backlogEntry) contains(subject string) bool {
+	if bytes.Contains(e.msg, []byte(subject)) {
+		return true
+	}
+	buf := &buffer{}
+	for _, f := range e.l.fields {
+		f.appendTo(buf)
+	}
+	for _, f := range e.fields {
+		f.appendTo(buf)
+	}
+	return bytes.Contains(buf.Bytes(), []byte(subject))
+}
+
+// purge removes the entries that contain subject, returning how many.
+func (f *flightRecorder) purge(subject string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var kept []backlogEntry
+	for _, e := range f.ordered() {
+		if e.contains(subject) {
+			f.mem.releaseMemory(e.size())
+		} else {
+			kept = append(kept, e)
+		}
+	}
+	n := len(f.entries) - len(kept)
+	f.entries, f.next = kept, 0
+	return n
+}
+
+// purge removes the entries of every goroutine that contain subject,
+// returning how many.
+func (b *debugBacklog) purge(subject string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := 0
+	for id, entries := range b.entries {
+		kept := entries[:0]
+		for _, e := range entries {
+			if e.contains(subject) {
+				b.mem.releaseMemory(e.size())
+				n++
+			} else {
+				kept = append(kept, e)
+			}
+		}
+		if len(kept) == 0 {
+			delete(b.entries, id)
+		} else {
+			b.entries[id] = kept
+		}
+	}
+	return n
+}
+
+// PurgeFile removes the lines that contain subject from the log file at
+// path, which is gunzipped and gzipped again if its name ends in ".gz", as
+// rotated files are with FileOptions.Compress. It returns the number of
+// lines removed. The file is replaced by a rewritten copy, so it must not
+// be written to at the same time, as the active file of a FileWriter is,
+// which makes it suited to rotated files. The lines of an entry that
+// spans more than one, such as the stack traces of a Fatal entry, are only
+// removed if they contain subject.
+func PurgeFile(path, subject string) (int, error) {
+	if subject == "" {
+		return 0, nil
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+	st, err := in.Stat()
+	if err != nil {
+		return 0, err
+	}
+	var r io.Reader = in
+	compressed := strings.HasSuffix(path, ".gz")
+	if compressed {
+		zr, err := gzip.NewReader(in)
+		if err != nil {
+			return 0, err
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	out, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".purge*")
+	if err != nil {
+		return 0, err
+	}
+	// Only has an effect if the rename fails.
+	defer os.Remove(out.Name())
+	defer out.Close()
+	var w io.Writer = out
+	var zw *gzip.Writer
+	if compressed {
+		zw = gzip.NewWriter(out)
+		w = zw
+	}
+	bw := bufio.NewWriter(w)
+
+	n := 0
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if bytes.Contains(line, []byte(subject)) {
+				n++
+			} else if _, err := bw.Write(line); err != nil {
+				return 0, err
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	if err := bw.Flush(); err != nil {
+		return 0, err
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return 0, err
+		}
+	}
+	if err := out.Chmod(st.Mode().Perm()); err != nil {
+		return 0, err
+	}
+	if err := out.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(out.Name(), path); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPurge(t *testing.T) {
+	g := &gatedWriter{gate: make(chan struct{})}
+	a := NewAsyncWriter(g, 10)
+	l := NewFromOptions(&Options{SyncWriter: a, FlightRecorder: 10, DebugBacklog: 10})
+	// Wait for it to be held in the write to g, so it isn't queued.
+	l.Info("first")
+	for a.QueueDepth() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	l.Info("about alice")
+	l.InfoFields("login", Str("user", "alice"))
+	l.Debug("debug alice")
+	l.With(Str("user", "alice")).Info("with")
+	l.Info("bob")
+
+	// 4 from the flight recorder, 1 from the backlog, and 3 queued.
+	if n := l.Purge("alice"); n != 8 {
+		t.Errorf("Purged %d entries, want 8", n)
+	}
+	if n := l.Purge(""); n != 0 {
+		t.Errorf("Purged %d entries for no subject", n)
+	}
+	close(g.gate)
+	l.Flush()
+	if got := g.String(); strings.Contains(got, "alice") || !strings.Contains(got, "] first\n") || !strings.Contains(got, "] bob\n") {
+		t.Errorf("Wrong entries written: %q", got)
+	}
+	if entries := l.flight.snapshot(); len(entries) != 2 || string(entries[0].msg) != "first" || string(entries[1].msg) != "bob" {
+		t.Errorf("Wrong entries left in the flight recorder: %v", entries)
+	}
+	if len(l.debugBacklog.entries) != 0 {
+		t.Errorf("Backlog not purged: %v", l.debugBacklog.entries)
+	}
+	want := int64(0)
+	for _, e := range l.flight.snapshot() {
+		want += int64(e.size())
+	}
+	for b := l.freeList; b != nil; b = b.next {
+		want += int64(b.Cap())
+	}
+	if got := l.MemoryStats().Used; got != want {
+		t.Errorf("Memory for purged entries not released, got %d want %d", got, want)
+	}
+}
+
+func TestForwardWriterPurge(t *testing.T) {
+	// Nothing is listening, so every write stays pending.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	w := NewForwardWriter(addr)
+	w.Write([]byte("a alice\n"))
+	w.Write([]byte("b\n"))
+	if n := w.Purge("alice"); n != 1 {
+		t.Errorf("Purged %d writes, want 1", n)
+	}
+	if len(w.pending) != 2 || w.pending[0].payload != nil || string(w.pending[1].payload) != "b\n" {
+		t.Errorf("Wrong pending writes: %v", w.pending)
+	}
+}
+
+func TestPurgeFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log.1")
+	if err := os.WriteFile(path, []byte("a alice\nb bob\nc alice"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := PurgeFile(path, "alice"); n != 2 || err != nil {
+		t.Fatalf("PurgeFile returned %d, %v", n, err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "b bob\n" {
+		t.Errorf("Wrong contents: %q", b)
+	}
+	if st, err := os.Stat(path); err != nil || st.Mode().Perm() != 0600 {
+		t.Errorf("Mode not kept: %v %v", st.Mode(), err)
+	}
+	if n, err := PurgeFile(path, "carol"); n != 0 || err != nil {
+		t.Errorf("PurgeFile returned %d, %v without matches", n, err)
+	}
+	if names := dirNames(t, dir); len(names) != 1 {
+		t.Errorf("Temporary files left: %v", names)
+	}
+
+	gz := filepath.Join(dir, "app.log.2.gz")
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte("a alice\nb bob\n"))
+	zw.Close()
+	if err := os.WriteFile(gz, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := PurgeFile(gz, "alice"); n != 1 || err != nil {
+		t.Fatalf("PurgeFile returned %d, %v", n, err)
+	}
+	f, err := os.Open(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := io.ReadAll(zr); string(b) != "b bob\n" {
+		t.Errorf("Wrong contents: %q", b)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build race
+
+package logger
+
+// raceEnabled is true if the race detector is on, which changes allocation
+// counts.
+const raceEnabled = true
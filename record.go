@@ -0,0 +1,45 @@
+package logger
+
+// LogRecord writes e, a fully formed record from elsewhere, such as one
+// received by a log forwarding agent, in the same way as the Logger's own
+// entries, so this package can serve as its output stage. The record's
+// Time, PID, File, and Line are written instead of those of the caller, and
+// its Fields follow any added by With. If Time is zero the current time is
+// used, and if PID is zero the Logger's.
+//
+// Severity must be one of the severity names, ignoring case. Debug records
+// are dropped unless IncludeDebug is true, and Fatal records are written
+// without stack traces and never cause the Logger to exit.
+func (l *Logger) LogRecord(e Entry) error {
+	sev, err := ParseSeverity(e.Severity)
+	if err != nil {
+		return err
+	}
+	s := severity(sev)
+	if s == debugLog && !l.IncludeDebug() {
+		return nil
+	}
+	now := e.Time
+	if now.IsZero() {
+		now = l.timeNow()
+	} else if loc := l.timeLocation(); loc != nil {
+		now = now.In(loc)
+	}
+	pid := e.PID
+	if pid == 0 {
+		pid = l.processID()
+	}
+	file := e.File
+	if file == "" {
+		file = "???"
+	}
+
+	header := l.headerFor(s, now, pid, file, e.Line, 0)
+	header.record = true
+	buf := l.getBuffer()
+	buf.WriteString(e.Message)
+	l.emitEntry(s, buf, header, e.Fields)
+	l.putBuffer(buf)
+	l.putBuffer(header)
+	return nil
+}
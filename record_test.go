@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogRecord(t *testing.T) {
+	when := time.Date(2006, 1, 2, 15, 4, 5, 67890000, time.Local)
+	exited := false
+	b := &flushBuffer{}
+	l := NewFromOptions(&Options{SyncWriter: b, Exit: func(int) { exited = true }})
+	if err := l.With("agent", "a1").LogRecord(Entry{Severity: "warning", Time: when, PID: 42, File: "remote/app.py", Line: 17, Message: "disk full", Fields: []Field{Str("host", "h1")}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.LogRecord(Entry{Severity: "FATAL", Time: when, PID: 42, File: "app.py", Line: 3, Message: "died"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.LogRecord(Entry{Severity: "DEBUG", Message: "hidden"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "W0102 15:04:05.067890      42 app.py:17] disk full agent=a1 host=h1\n" +
+		"F0102 15:04:05.067890      42 app.py:3] died\n"
+	if got := b.String(); got != want {
+		t.Errorf("Got %q want %q", got, want)
+	}
+	if exited {
+		t.Error("A Fatal record must not exit")
+	}
+
+	if err := l.LogRecord(Entry{Severity: "LOUD"}); err == nil {
+		t.Error("Expected an error for an unknown severity")
+	}
+
+	b = &flushBuffer{}
+	if err := NewFromOptions(&Options{SyncWriter: b, Format: JSONFormat, Location: time.UTC}).LogRecord(Entry{Severity: "info", Time: when, PID: 7, File: "app.py", Line: 1, Message: "ok"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), `"severity":"INFO","timestamp":"`+when.UTC().Format("2006-01-02T15:04:05.000000Z07:00")+`","pid":7,"caller":{"file":"app.py","line":1},"message":"ok"}`; !strings.Contains(got, want) {
+		t.Errorf("Got %q want %q", got, want)
+	}
+
+	var entries []Entry
+	f := NewFromOptions(&Options{SyncWriter: &flushBuffer{}, Formatter: formatterFunc(func(e Entry, _ *bytes.Buffer) { entries = append(entries, e) })})
+	if err := f.LogRecord(Entry{Severity: "ERROR", Time: when, PID: 7, File: "app.py", Line: 1, Message: "failed"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].PID != 7 || entries[0].File != "app.py" || !entries[0].Time.Equal(when) {
+		t.Errorf("Wrong entries: %#v", entries)
+	}
+}
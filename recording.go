@@ -0,0 +1,175 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// RecordingLogger is a Logger for tests that keeps every entry written,
+// with its fields, so the expected logs can be checked with Check rather
+// than by searching the output, e.g.:
+//
+//	l := logger.NewRecordingLogger()
+//	serve(l.Logger, req)
+//	if err := l.Check(logger.InOrder(
+//		logger.HasEntry(logger.InfoSeverity, "request", logger.Str("path", "/x")),
+//		logger.HasEntry(logger.ErrorSeverity, "failed"),
+//	)); err != nil {
+//		t.Error(err)
+//	}
+//
+// Debug entries are included, and Fatal entries don't exit.
+type RecordingLogger struct {
+	*Logger
+	r *recorder
+}
+
+// NewRecordingLogger returns a new RecordingLogger.
+func NewRecordingLogger() *RecordingLogger {
+	r := &recorder{}
+	return &RecordingLogger{
+		Logger: NewFromOptions(&Options{
+			SyncWriter:   r,
+			Formatter:    r,
+			IncludeDebug: true,
+			Exit:         func(int) {},
+		}),
+		r: r,
+	}
+}
+
+// Entries returns the entries written so far, in order. Fatal entries are
+// followed by an entry holding the stack traces.
+func (l *RecordingLogger) Entries() []Entry {
+	l.r.mu.Lock()
+	defer l.r.mu.Unlock()
+	return append([]Entry(nil), l.r.entries...)
+}
+
+// Check returns an error describing the expectations that the entries
+// written so far don't meet, or nil if they meet them all.
+func (l *RecordingLogger) Check(expectations ...Expectation) error {
+	entries := l.Entries()
+	var failed []string
+	for _, e := range expectations {
+		if err := e.check(entries); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	var got strings.Builder
+	for _, e := range entries {
+		fmt.Fprintf(&got, "\n\t%s %q", e.Severity, e.Message)
+		for _, f := range e.Fields {
+			fmt.Fprintf(&got, " %s=%s", f.Key, fieldValue(f))
+		}
+	}
+	return fmt.Errorf("%s, in the %d entries:%s", strings.Join(failed, "; "), len(entries), got.String())
+}
+
+// recorder is the destination and Formatter of a RecordingLogger, which
+// keeps the entries and writes nothing.
+type recorder struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// Format implements Formatter.
+func (r *recorder) Format(entry Entry, buf *bytes.Buffer) {
+	entry.Fields = append([]Field(nil), entry.Fields...)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+// Write implements SyncWriter.
+func (r *recorder) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// Sync implements SyncWriter.
+func (r *recorder) Sync() error {
+	return nil
+}
+
+// Expectation is a condition on the entries of a RecordingLogger, see
+// RecordingLogger.Check.
+type Expectation interface {
+	// check returns an error if entries don't meet the expectation.
+	check(entries []Entry) error
+}
+
+// EntryMatcher matches entries, see HasEntry.
+type EntryMatcher struct {
+	severity Severity
+	msg      string
+	fields   []Field
+}
+
+// HasEntry returns an EntryMatcher for the entries of severity s with a
+// message containing msg, and with top level fields, other than Groups, of
+// the keys of fields, with values that render the same. As an Expectation
+// it's met if any entry matches.
+func HasEntry(s Severity, msg string, fields ...Field) EntryMatcher {
+	return EntryMatcher{severity: s, msg: msg, fields: fields}
+}
+
+// Matches returns true if e matches.
+func (m EntryMatcher) Matches(e Entry) bool {
+	if !strings.EqualFold(e.Severity, m.severity.String()) || !strings.Contains(e.Message, m.msg) {
+		return false
+	}
+	for _, want := range m.fields {
+		if !hasField(entryInfo{fields: e.Fields}, want.Key, fieldValue(want)) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns a description of the entries m matches.
+func (m EntryMatcher) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %q", m.severity, m.msg)
+	for _, f := range m.fields {
+		fmt.Fprintf(&b, " %s=%s", f.Key, fieldValue(f))
+	}
+	return b.String()
+}
+
+func (m EntryMatcher) check(entries []Entry) error {
+	for _, e := range entries {
+		if m.Matches(e) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no entry matches %s", m)
+}
+
+// inOrder is the Expectation returned by InOrder.
+type inOrder []EntryMatcher
+
+// InOrder returns an Expectation that's met if there are entries that
+// match each of matchers, in the same order, with any other entries in
+// between.
+func InOrder(matchers ...EntryMatcher) Expectation {
+	return inOrder(matchers)
+}
+
+func (o inOrder) check(entries []Entry) error {
+	next := 0
+	for _, m := range o {
+		for next < len(entries) && !m.Matches(entries[next]) {
+			next++
+		}
+		if next == len(entries) {
+			return fmt.Errorf("no entry matches %s in order", m)
+		}
+		next++
+	}
+	return nil
+}
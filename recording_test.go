@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRecordingLogger(t *testing.T) {
+	l := NewRecordingLogger()
+	l.DebugFields("starting", Int("workers", 4))
+	l.Named("http").InfoFields("request", Str("path", "/x"), Int("status", 200))
+	l.ErrorFields("failed", Err(errors.New("boom")))
+
+	if err := l.Check(
+		HasEntry(DebugSeverity, "start", Int("workers", 4)),
+		HasEntry(InfoSeverity, "request", Str("component", "http"), Str("path", "/x")),
+		InOrder(
+			HasEntry(DebugSeverity, ""),
+			HasEntry(InfoSeverity, "request", Int("status", 200)),
+			HasEntry(ErrorSeverity, "failed", Str("error", "boom")),
+		),
+	); err != nil {
+		t.Error(err)
+	}
+
+	for _, e := range []Expectation{
+		HasEntry(WarningSeverity, "request"),
+		HasEntry(InfoSeverity, "request", Int("status", 500)),
+		HasEntry(InfoSeverity, "response"),
+		InOrder(HasEntry(ErrorSeverity, "failed"), HasEntry(InfoSeverity, "request")),
+	} {
+		if err := l.Check(e); err == nil {
+			t.Errorf("%v met, want an error", e)
+		}
+	}
+
+	err := l.Check(HasEntry(InfoSeverity, "response", Str("path", "/y")))
+	if err == nil || !strings.Contains(err.Error(), `no entry matches INFO "response" path=/y`) || !strings.Contains(err.Error(), `INFO "request" component=http path=/x status=200`) {
+		t.Errorf("Wrong error: %v", err)
+	}
+}
+
+func TestRecordingLoggerFatal(t *testing.T) {
+	l := NewRecordingLogger()
+	l.Fatal("bye")
+	entries := l.Entries()
+	if len(entries) != 2 || entries[0].Message != "bye" || !strings.Contains(entries[1].Message, "goroutine") {
+		t.Errorf("Wrong entries: %+v", entries)
+	}
+}
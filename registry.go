@@ -0,0 +1,120 @@
+package logger
+
+import (
+	"strings"
+	"sync"
+)
+
+// Registry holds hierarchical named Loggers, such as "server.http.handler",
+// whose minimum severities can be overridden per name at runtime. A Logger
+// without an override of its own inherits that of its nearest ancestor, so
+// e.g.:
+//
+//	r := logger.NewRegistry(l)
+//	h := r.Logger("server.http.handler")
+//	r.SetLevel("server", logger.WarningSeverity)
+//	r.SetLevel("server.http", logger.DebugSeverity)
+//
+// makes h write Debug entries, while "server.db" only writes Warnings and
+// above. Loggers without an override in their hierarchy log as the root
+// Logger does. Fatal entries are never dropped.
+type Registry struct {
+	root *Logger
+
+	// mu protects loggers and levels.
+	mu      sync.RWMutex
+	loggers map[string]*Logger
+	levels  map[string]Severity
+}
+
+// NewRegistry returns a Registry whose Loggers are derived from root.
+func NewRegistry(root *Logger) *Registry {
+	return &Registry{
+		root:    root,
+		loggers: map[string]*Logger{},
+		levels:  map[string]Severity{},
+	}
+}
+
+// Logger returns the Logger for name, which is dot separated, creating it
+// if needed. It's the same as calling Named on the root Logger, except the
+// overrides set with SetLevel apply, including to the Loggers derived from
+// it by Named.
+func (r *Registry) Logger(name string) *Logger {
+	r.mu.RLock()
+	l, ok := r.loggers[name]
+	r.mu.RUnlock()
+	if ok {
+		return l
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if l, ok := r.loggers[name]; ok {
+		return l
+	}
+	l = r.root.Named(name)
+	l.registry = r
+	r.loggers[name] = l
+	return l
+}
+
+// SetLevel sets the minimum severity written by the Logger for name and its
+// descendants that don't have an override of their own. The empty name sets
+// it for every Logger in the Registry.
+func (r *Registry) SetLevel(name string, s Severity) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.levels[name] = s
+}
+
+// ClearLevel removes any override set with SetLevel for name, so it
+// inherits that of its ancestors again.
+func (r *Registry) ClearLevel(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.levels, name)
+}
+
+// Level returns the minimum severity written by the Logger for name, and
+// false if neither it nor any of its ancestors has an override.
+func (r *Registry) Level(name string) (Severity, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for {
+		if s, ok := r.levels[name]; ok {
+			return s, true
+		}
+		if name == "" {
+			return 0, false
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			name = ""
+		} else {
+			name = name[:i]
+		}
+	}
+}
+
+// dropped returns true if an entry of severity s logged by l should be
+// dropped because the Governor has raised the minimum severity, because of
+// an override in l's Registry, or otherwise because of the Logger's
+// MinSeverity.
+func (l *Logger) dropped(s severity) bool {
+	if s == fatalLog {
+		return false
+	}
+	if l.governed(s) {
+		return true
+	}
+	if l.sampledOut && Severity(s).less(ErrorSeverity) {
+		return true
+	}
+	if l.registry != nil {
+		if min, ok := l.registry.Level(l.name); ok {
+			return Severity(s).less(min)
+		}
+	}
+	return Severity(s).less(l.MinSeverity())
+}
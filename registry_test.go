@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	newTestLogger()
+	r := NewRegistry(testLogger)
+	handler := r.Logger("server.http.handler")
+	db := r.Logger("server.db")
+	if r.Logger("server.db") != db {
+		t.Error("Expected the same Logger for the same name")
+	}
+
+	r.SetLevel("server", WarningSeverity)
+	r.SetLevel("server.http", DebugSeverity)
+	handler.Debug("handler debug")
+	handler.Named("auth").Debug("auth debug")
+	db.Info("db info")
+	db.Warning("db warning")
+	testLogger.Debug("root debug")
+
+	r.ClearLevel("server.http")
+	handler.Info("handler info")
+
+	got := contents()
+	for _, want := range []string{"server.http.handler: handler debug", "server.http.handler.auth: auth debug", "server.db: db warning"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Missing %q in %q", want, got)
+		}
+	}
+	for _, missing := range []string{"db info", "root debug", "handler info"} {
+		if strings.Contains(got, missing) {
+			t.Errorf("Unexpected %q in %q", missing, got)
+		}
+	}
+
+	if s, ok := r.Level("server.http.handler"); !ok || s != WarningSeverity {
+		t.Errorf("Wrong inherited level: %v %v", s, ok)
+	}
+	if _, ok := r.Level("other"); ok {
+		t.Error("Expected no level for other")
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	for _, name := range []string{"debug", "INFO", "Warning", "error", "FATAL"} {
+		s, err := ParseSeverity(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.EqualFold(s.String(), name) {
+			t.Errorf("Got %v want %s", s, name)
+		}
+	}
+	if _, err := ParseSeverity("loud"); err == nil {
+		t.Error("Expected an error")
+	}
+	if got := Severity(9).String(); got != "Severity(9)" {
+		t.Errorf("Got %q", got)
+	}
+}
+
+func TestMinSeverity(t *testing.T) {
+	newTestLogger()
+	testLogger.SetMinSeverity(WarningSeverity)
+	testLogger.Info("dropped")
+	testLogger.Error("kept")
+	r := NewRegistry(testLogger)
+	r.SetLevel("db", InfoSeverity)
+	r.Logger("db").Info("kept by the override")
+	if got := contents(); strings.Contains(got, "dropped") || !strings.Contains(got, "] kept\n") || !strings.Contains(got, "] db: kept by the override\n") {
+		t.Errorf("Wrong output: %q", got)
+	}
+}
+
+func TestLevelEnvVar(t *testing.T) {
+	t.Setenv(LevelEnvVar, "debug")
+	l := NewFromOptions(&Options{SyncWriter: &flushBuffer{}, MinSeverity: ErrorSeverity})
+	if !l.IncludeDebug() || l.MinSeverity() != DebugSeverity {
+		t.Errorf("Wrong settings for debug: %v %v", l.IncludeDebug(), l.MinSeverity())
+	}
+
+	t.Setenv(LevelEnvVar, "WARNING")
+	b := &flushBuffer{}
+	l = NewFromOptions(&Options{SyncWriter: b, IncludeDebug: true})
+	l.Info("dropped")
+	l.Warning("kept")
+	if l.IncludeDebug() || strings.Contains(b.String(), "dropped") || !strings.Contains(b.String(), "] kept\n") {
+		t.Errorf("Wrong output for warning: %q", b.String())
+	}
+
+	t.Setenv(LevelEnvVar, "loud")
+	diagnostics := &flushBuffer{}
+	l = NewFromOptions(&Options{SyncWriter: &flushBuffer{}, Diagnostics: diagnostics})
+	if l.MinSeverity() != DebugSeverity || !strings.Contains(diagnostics.String(), `LOGGER_LEVEL: unknown severity "loud"`) {
+		t.Errorf("Wrong handling of an invalid value: %v %q", l.MinSeverity(), diagnostics.String())
+	}
+}
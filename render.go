@@ -0,0 +1,140 @@
+package logger
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxCollectionElements is the number of elements of a slice, array, or
+	// map that are rendered before the rest are elided.
+	maxCollectionElements = 32
+
+	// maxCollectionDepth is how deeply nested collections are rendered.
+	maxCollectionDepth = 8
+)
+
+// renderAny renders v for use as a field value.
+//
+// Slices and arrays are rendered as "[a,b,c]" and maps as "{k1:v1,k2:v2}"
+// with the keys sorted by their rendered form, so the output is stable from
+// run to run. Only the first maxCollectionElements elements are rendered,
+// followed by "...+N" noting how many were left out. Nil maps, slices,
+// pointers, and interfaces are rendered as "<nil>". Everything else is
+// rendered in the manner of fmt.Print.
+func renderAny(v interface{}) string {
+	if v == nil {
+		return "<nil>"
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		var b strings.Builder
+		renderValue(&b, rv, 0)
+		return b.String()
+	}
+	return fmt.Sprint(v)
+}
+
+func renderValue(b *strings.Builder, rv reflect.Value, depth int) {
+	switch rv.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if rv.IsNil() {
+			b.WriteString("<nil>")
+			return
+		}
+		if rv.Kind() == reflect.Interface {
+			renderValue(b, rv.Elem(), depth)
+			return
+		}
+	case reflect.Slice, reflect.Map:
+		if rv.IsNil() {
+			b.WriteString("<nil>")
+			return
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			// Render []byte as a string, in the manner of fmt.
+			renderElement(b, string(rv.Bytes()))
+			return
+		}
+		if depth >= maxCollectionDepth {
+			b.WriteString("[...]")
+			return
+		}
+		b.WriteByte('[')
+		n := rv.Len()
+		for i := 0; i < n && i < maxCollectionElements; i++ {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			renderValue(b, rv.Index(i), depth+1)
+		}
+		elided(b, n)
+		b.WriteByte(']')
+	case reflect.Map:
+		if depth >= maxCollectionDepth {
+			b.WriteString("{...}")
+			return
+		}
+		type kv struct {
+			key   string
+			value reflect.Value
+		}
+		pairs := make([]kv, 0, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			var key strings.Builder
+			renderValue(&key, iter.Key(), depth+1)
+			pairs = append(pairs, kv{key: key.String(), value: iter.Value()})
+		}
+		sort.Slice(pairs, func(i, j int) bool { return pairs[i].key < pairs[j].key })
+		b.WriteByte('{')
+		for i, p := range pairs {
+			if i == maxCollectionElements {
+				break
+			}
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(p.key)
+			b.WriteByte(':')
+			renderValue(b, p.value, depth+1)
+		}
+		elided(b, len(pairs))
+		b.WriteByte('}')
+	case reflect.String:
+		renderElement(b, rv.String())
+	default:
+		if rv.CanInterface() {
+			renderElement(b, fmt.Sprint(rv.Interface()))
+		} else {
+			renderElement(b, fmt.Sprint(rv))
+		}
+	}
+}
+
+// elided notes how many of the n elements in a collection weren't rendered.
+func elided(b *strings.Builder, n int) {
+	if n <= maxCollectionElements {
+		return
+	}
+	b.WriteString(",...+")
+	b.WriteString(strconv.Itoa(n - maxCollectionElements))
+}
+
+// renderElement writes s as an element of a collection, quoting it if it
+// is empty or contains any of the characters used to delimit collections.
+func renderElement(b *strings.Builder, s string) {
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"=,:[]{}") {
+		b.WriteString(strconv.Quote(s))
+		return
+	}
+	b.WriteString(s)
+}
@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type renderStruct struct {
+	A int
+	B string
+}
+
+func TestRenderAny(t *testing.T) {
+	var nilSlice []int
+	var nilMap map[string]int
+	var nilPtr *renderStruct
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{nil, "<nil>"},
+		{nilSlice, "<nil>"},
+		{nilMap, "<nil>"},
+		{nilPtr, "<nil>"},
+		{[]int{}, "[]"},
+		{map[string]int{}, "{}"},
+		{[]int{3, 1, 2}, "[3,1,2]"},
+		{[2]string{"a", "b c"}, `[a,"b c"]`},
+		{[]string{""}, `[""]`},
+		{[]byte("bytes"), "bytes"},
+		{map[string]int{"b": 2, "a": 1, "c": 3}, "{a:1,b:2,c:3}"},
+		{map[int]string{10: "x", 2: "y"}, "{10:x,2:y}"},
+		{map[string][]int{"x": {1}, "y": nil}, "{x:[1],y:<nil>}"},
+		{[]interface{}{1, "a", nil, []int{2}}, "[1,a,<nil>,[2]]"},
+		{[]renderStruct{{1, "x"}}, `["{1 x}"]`},
+		{42, "42"},
+		{"plain", "plain"},
+	}
+	for _, tc := range tests {
+		if got := renderAny(tc.value); got != tc.want {
+			t.Errorf("renderAny(%#v) got %q want %q", tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestRenderAnyCaps(t *testing.T) {
+	long := make([]int, maxCollectionElements+5)
+	got := renderAny(long)
+	if !strings.HasSuffix(got, ",...+5]") {
+		t.Errorf("Long slice not elided: %q", got)
+	}
+	if n := strings.Count(got, "0"); n != maxCollectionElements {
+		t.Errorf("Wrong number of elements rendered: %d", n)
+	}
+
+	m := map[string]int{}
+	for i := 0; i < maxCollectionElements+1; i++ {
+		m[fmt.Sprintf("k%03d", i)] = i
+	}
+	if got := renderAny(m); !strings.HasSuffix(got, ",...+1}") {
+		t.Errorf("Long map not elided: %q", got)
+	}
+
+	var deep interface{} = 1
+	for i := 0; i < maxCollectionDepth+2; i++ {
+		deep = []interface{}{deep}
+	}
+	if got := renderAny(deep); !strings.Contains(got, "[...]") {
+		t.Errorf("Deep nesting not capped: %q", got)
+	}
+}
+
+// Test that rendering a map is stable across many runs, since map iteration
+// order is randomized.
+func TestRenderAnyStable(t *testing.T) {
+	m := map[string]int{}
+	for i := 0; i < 20; i++ {
+		m[fmt.Sprint(i)] = i
+	}
+	want := renderAny(m)
+	for i := 0; i < 100; i++ {
+		if got := renderAny(m); got != want {
+			t.Fatalf("Unstable rendering: %q != %q", got, want)
+		}
+	}
+}
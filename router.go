@@ -0,0 +1,254 @@
+package logger
+
+import (
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Route is a rule of a Router, which sends the entries it matches to To.
+// An entry matches if it meets all of the conditions, and the zero value
+// of each condition matches every entry.
+type Route struct {
+	// MinSeverity matches entries at or above it.
+	MinSeverity Severity
+
+	// File, if not empty, is a path.Match pattern for the base name of the
+	// file of the call site, e.g. "billing_*.go".
+	File string
+
+	// Name, if not empty, matches the entries of the Logger with that name
+	// and of those Named from it, e.g. "billing" matches "billing" and
+	// "billing.invoices", but not "billingx".
+	Name string
+
+	// Fields, if not empty, matches entries with top level fields of all
+	// those keys with values that render as the given strings, e.g.
+	// {"component": "billing"}. The name of a Logger, see Named, counts as
+	// its "component" field.
+	Fields map[string]string
+
+	// To is where the matched entries are written.
+	To SyncWriter
+
+	// Continue, if true, keeps trying the routes that follow this one
+	// after it matches, so an entry can be written to more than one
+	// destination.
+	Continue bool
+}
+
+// matches returns true if the entry described by e matches the route.
+func (r *Route) matches(e entryInfo) bool {
+	if Severity(e.s).less(r.MinSeverity) {
+		return false
+	}
+	if r.File != "" {
+		if ok, _ := path.Match(r.File, e.file); !ok {
+			return false
+		}
+	}
+	if r.Name != "" && e.name != r.Name && !strings.HasPrefix(e.name, r.Name+".") {
+		return false
+	}
+	for key, want := range r.Fields {
+		if !hasField(e, key, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasField returns true if the entry described by e has a top level field
+// of key with a value that renders as want.
+func hasField(e entryInfo, key, want string) bool {
+	if key == "component" && e.name == want {
+		return true
+	}
+	for _, f := range e.fields {
+		if f.Key == key && f.t != groupField && fieldValue(f) == want {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldValue returns the value of f, which isn't a Group, rendered as in
+// TextFormat, but unquoted.
+func fieldValue(f Field) string {
+	switch f.t {
+	case stringField:
+		return f.str
+	case int64Field:
+		return strconv.FormatInt(f.num, 10)
+	case boolField:
+		return strconv.FormatBool(f.num == 1)
+	case durationField:
+		return time.Duration(f.num).String()
+	case errorField:
+		if f.iface == nil {
+			return "<nil>"
+		}
+		return f.iface.(error).Error()
+	}
+	buf := &buffer{}
+	f.appendTo(buf)
+	return strings.TrimPrefix(buf.String(), " "+f.Key+"=")
+}
+
+// Router is a SyncWriter that sends each entry to the destinations of the
+// routes it matches, trying them in order, and to a fallback destination
+// if it matches none, e.g. to write the billing entries to their own file:
+//
+//	r := logger.NewRouter(os.Stdout, logger.Route{
+//		Fields: map[string]string{"component": "billing"},
+//		To:     billingFile,
+//	})
+//	l := logger.NewFromOptions(&logger.Options{SyncWriter: r})
+//	l.Named("billing").Info("charged") // Written to billingFile.
+//
+// Routes can be changed while logging with AddRoute and SetRoutes. Writes
+// that aren't of an entry, such as those from Crash, go to every
+// destination.
+type Router struct {
+	fallback SyncWriter
+
+	// routes holds the current []Route, which is never modified once
+	// stored.
+	routes atomic.Value
+
+	// mu serializes changes to routes.
+	mu sync.Mutex
+}
+
+// NewRouter returns a Router with routes, which writes the entries that
+// don't match any of them to fallback. If fallback is nil those entries are
+// dropped.
+func NewRouter(fallback SyncWriter, routes ...Route) *Router {
+	r := &Router{fallback: fallback}
+	r.SetRoutes(routes...)
+	return r
+}
+
+// SetRoutes replaces the routes of the Router.
+func (r *Router) SetRoutes(routes ...Route) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes.Store(append([]Route(nil), routes...))
+}
+
+// AddRoute adds route after the existing routes.
+func (r *Router) AddRoute(route Route) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	routes := r.loadRoutes()
+	r.routes.Store(append(routes[:len(routes):len(routes)], route))
+}
+
+func (r *Router) loadRoutes() []Route {
+	routes, _ := r.routes.Load().([]Route)
+	return routes
+}
+
+// writeEntry implements entryWriter, returning the first error.
+func (r *Router) writeEntry(e entryInfo, p []byte) (int, error) {
+	var err error
+	matched := false
+	routes := r.loadRoutes()
+	for i := range routes {
+		route := &routes[i]
+		if !route.matches(e) {
+			continue
+		}
+		matched = true
+		if writeErr := writeTo(route.To, e, p); err == nil {
+			err = writeErr
+		}
+		if !route.Continue {
+			break
+		}
+	}
+	if !matched && r.fallback != nil {
+		err = writeTo(r.fallback, e, p)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// destinations returns the destinations of the Router, each only once.
+func (r *Router) destinations() []SyncWriter {
+	var ret []SyncWriter
+	add := func(w SyncWriter) {
+		if w == nil {
+			return
+		}
+		for _, existing := range ret {
+			if existing == w {
+				return
+			}
+		}
+		ret = append(ret, w)
+	}
+	add(r.fallback)
+	for _, route := range r.loadRoutes() {
+		add(route.To)
+	}
+	return ret
+}
+
+// Write implements SyncWriter, writing p to every destination.
+func (r *Router) Write(p []byte) (int, error) {
+	var err error
+	for _, w := range r.destinations() {
+		if _, writeErr := w.Write(p); err == nil {
+			err = writeErr
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync implements SyncWriter, returning the first error.
+func (r *Router) Sync() error {
+	var err error
+	for _, w := range r.destinations() {
+		if syncErr := w.Sync(); err == nil {
+			err = syncErr
+		}
+	}
+	return err
+}
+
+// Purge implements Purger, purging the destinations that implement it.
+func (r *Router) Purge(subject string) int {
+	n := 0
+	for _, w := range r.destinations() {
+		if p, ok := w.(Purger); ok {
+			n += p.Purge(subject)
+		}
+	}
+	return n
+}
+
+// Close closes the destinations that implement io.Closer, other than
+// os.Stdout and os.Stderr, so Shutdown closes them. It returns the first
+// error.
+func (r *Router) Close() error {
+	var err error
+	for _, w := range r.destinations() {
+		if c, ok := w.(io.Closer); ok && w != os.Stdout && w != os.Stderr {
+			if closeErr := c.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	}
+	return err
+}
@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	fallback, billing, errs, files := &flushBuffer{}, &flushBuffer{}, &flushBuffer{}, &flushBuffer{}
+	r := NewRouter(fallback,
+		Route{MinSeverity: ErrorSeverity, To: errs, Continue: true},
+		Route{Fields: map[string]string{"component": "billing"}, To: billing},
+		Route{Fields: map[string]string{"status": "500"}, To: billing},
+		Route{File: "router_*.go", Name: "files", To: files},
+	)
+	l := NewFromOptions(&Options{SyncWriter: r})
+
+	l.Info("plain")
+	l.Named("billing").Info("charged")
+	l.Named("billing").Named("invoices").Info("not billing")
+	l.InfoFields("by field", Str("component", "billing"))
+	l.InfoFields("by status", Int("status", 500))
+	l.Named("billing").Error("declined")
+	l.Named("files").Info("by file")
+
+	for _, test := range []struct {
+		name string
+		w    *flushBuffer
+		want []string
+	}{
+		{"fallback", fallback, []string{"plain", "billing.invoices: not billing"}},
+		{"billing", billing, []string{"billing: charged", "by field component=billing", "by status status=500", "billing: declined"}},
+		{"errors", errs, []string{"billing: declined"}},
+		{"files", files, []string{"files: by file"}},
+	} {
+		lines := strings.Split(strings.TrimSuffix(test.w.String(), "\n"), "\n")
+		if len(lines) != len(test.want) {
+			t.Errorf("%s: got %q, want %q", test.name, lines, test.want)
+			continue
+		}
+		for i, line := range lines {
+			if !strings.HasSuffix(line, "] "+test.want[i]) {
+				t.Errorf("%s: got %q, want %q", test.name, line, test.want[i])
+			}
+		}
+	}
+}
+
+func TestRouterAddRoute(t *testing.T) {
+	fallback, warnings := &flushBuffer{}, &flushBuffer{}
+	r := NewRouter(fallback)
+	l := NewFromOptions(&Options{SyncWriter: r, Format: JSONFormat})
+	l.Warning("before")
+	r.AddRoute(Route{MinSeverity: WarningSeverity, To: warnings})
+	l.Warning("after")
+	l.Info("info")
+
+	if got := warnings.String(); strings.Contains(got, "before") || !strings.Contains(got, `"message":"after"`) {
+		t.Errorf("wrong routed entries: %q", got)
+	}
+	if got := fallback.String(); !strings.Contains(got, "before") || !strings.Contains(got, "info") || strings.Contains(got, "after") {
+		t.Errorf("wrong fallback entries: %q", got)
+	}
+}
+
+func TestRouterNoFallback(t *testing.T) {
+	r := NewRouter(nil)
+	l := NewFromOptions(&Options{SyncWriter: r})
+	l.Info("dropped")
+	if err := l.Healthy(); err != nil {
+		t.Errorf("dropping an entry is not a failure, got %s", err)
+	}
+}
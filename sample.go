@@ -0,0 +1,160 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// everyN logs args at the 1st, (n+1)th, (2n+1)th, etc. occurrence of the
+// call site, or only at the first occurrence if n is zero. The call site is
+// the caller of the exported method that called everyN.
+func (l *Logger) everyN(s severity, n uint64, args ...interface{}) {
+	pc, _, _, ok := runtime.Caller(2 + l.depthDelta)
+	if !ok {
+		pc = 0
+	}
+	counter, _ := l.occurrences.LoadOrStore(pc, new(uint64))
+	count := atomic.AddUint64(counter.(*uint64), 1)
+	if n == 0 {
+		if count != 1 {
+			return
+		}
+	} else if (count-1)%n != 0 {
+		return
+	}
+
+	header, _, _ := l.header(s, 0)
+	buf := l.getBuffer()
+	if l.occurrenceCounter {
+		buf.WriteString("[x")
+		n := buf.someDigits(0, int(count))
+		buf.Write(buf.tmp[:n])
+		buf.WriteString("] ")
+	}
+	fmt.Fprint(buf, args...)
+	l.emitAsOneOrMoreLogLines(s, buf, header, nil)
+	l.putBuffer(buf)
+}
+
+// InfoEveryN logs the 1st, (n+1)th, (2n+1)th, etc. call from each call
+// site, in the manner of glog's LOG_EVERY_N. Arguments are handled in the
+// manner of fmt.Print.
+func (l *Logger) InfoEveryN(n int, args ...interface{}) {
+	l.everyN(infoLog, everyNCount(n), args...)
+}
+
+// WarningEveryN logs the 1st, (n+1)th, (2n+1)th, etc. call from each call
+// site. Arguments are handled in the manner of fmt.Print.
+func (l *Logger) WarningEveryN(n int, args ...interface{}) {
+	l.everyN(warningLog, everyNCount(n), args...)
+}
+
+// ErrorEveryN logs the 1st, (n+1)th, (2n+1)th, etc. call from each call
+// site. Arguments are handled in the manner of fmt.Print.
+func (l *Logger) ErrorEveryN(n int, args ...interface{}) {
+	l.everyN(errorLog, everyNCount(n), args...)
+}
+
+// InfoOnce logs only the first call from each call site. Arguments are
+// handled in the manner of fmt.Print.
+func (l *Logger) InfoOnce(args ...interface{}) {
+	l.everyN(infoLog, 0, args...)
+}
+
+// WarningOnce logs only the first call from each call site. Arguments are
+// handled in the manner of fmt.Print.
+func (l *Logger) WarningOnce(args ...interface{}) {
+	l.everyN(warningLog, 0, args...)
+}
+
+// ErrorOnce logs only the first call from each call site. Arguments are
+// handled in the manner of fmt.Print.
+func (l *Logger) ErrorOnce(args ...interface{}) {
+	l.everyN(errorLog, 0, args...)
+}
+
+// everyNCount converts n to the count used by everyN, treating n < 1 as
+// logging every call.
+func everyNCount(n int) uint64 {
+	if n < 1 {
+		return 1
+	}
+	return uint64(n)
+}
+
+// sampleKey is the key of the sampling decision stored in a context by
+// Sample.
+type sampleKey struct{}
+
+var (
+	// sampleRand decides whether requests are sampled in, maintained under
+	// sampleRandMu.
+	sampleRand   = rand.New(rand.NewSource(time.Now().UnixNano()))
+	sampleRandMu sync.Mutex
+)
+
+// sampleFloat returns a random number in [0, 1), stubbed out for testing.
+var sampleFloat = func() float64 {
+	sampleRandMu.Lock()
+	defer sampleRandMu.Unlock()
+	return sampleRand.Float64()
+}
+
+// Sample returns a copy of ctx holding the decision to keep the logs of a
+// request, with probability rate, or drop them, so that every Logger
+// logging for the request decides the same way, rather than keeping some of
+// its entries and dropping others. It's intended to be called once per
+// request, e.g. by the outermost handler:
+//
+//	ctx = logger.Sample(ctx, 0.01)
+//	logger.InfoCtx(ctx, "request started")
+//
+// If ctx already holds a decision it's kept, and if ctx carries a valid
+// OpenTelemetry span the decision is whether the span is sampled, so the
+// logs are kept for the same requests as the traces. The decision is
+// applied to the Logger in ctx, see NewContext, and to those Loggers
+// passed ctx by WithSampling. Loggers for requests that are sampled out
+// drop Debug, Info, and Warning entries, but still write Errors and Fatals.
+func Sample(ctx context.Context, rate float64) context.Context {
+	if _, ok := ctx.Value(sampleKey{}).(bool); ok {
+		return ctx
+	}
+	var keep bool
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		keep = sc.IsSampled()
+	} else {
+		keep = sampleFloat() < rate
+	}
+	ctx = context.WithValue(ctx, sampleKey{}, keep)
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok && l != nil {
+		ctx = NewContext(ctx, l)
+	}
+	return ctx
+}
+
+// Sampled returns whether the logs of the request of ctx are kept, and ok
+// of false if ctx doesn't hold a sampling decision, see Sample.
+func Sampled(ctx context.Context) (keep, ok bool) {
+	keep, ok = ctx.Value(sampleKey{}).(bool)
+	return keep, ok
+}
+
+// WithSampling returns a Logger that applies the sampling decision held by
+// ctx, see Sample, or l itself if there isn't one, or it's already applied.
+// Loggers derived from the returned one by With and Named keep it.
+func (l *Logger) WithSampling(ctx context.Context) *Logger {
+	keep, ok := Sampled(ctx)
+	if !ok || l.sampledOut == !keep {
+		return l
+	}
+	ret := *l
+	ret.sampledOut = !keep
+	return &ret
+}
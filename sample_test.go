@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestInfoEveryN(t *testing.T) {
+	newTestLogger()
+	for i := 0; i < 7; i++ {
+		testLogger.InfoEveryN(3, "sampled")
+	}
+	lines := strings.Split(strings.TrimSpace(contents()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected occurrences 1, 4, and 7 to be logged, got %q", lines)
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "I") || !strings.Contains(line, " sample_test.go:") || !strings.HasSuffix(line, "] sampled") {
+			t.Errorf("Wrong line: %q", line)
+		}
+	}
+}
+
+func TestOnce(t *testing.T) {
+	newTestLogger()
+	for i := 0; i < 3; i++ {
+		testLogger.WarningOnce("once")
+	}
+	testLogger.WarningOnce("different call site")
+	lines := strings.Split(strings.TrimSpace(contents()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "] once") || !strings.HasSuffix(lines[1], "] different call site") {
+		t.Errorf("Expected one line per call site, got %q", lines)
+	}
+}
+
+func TestOccurrenceCounter(t *testing.T) {
+	l := NewFromOptions(&Options{SyncWriter: &flushBuffer{}, OccurrenceCounter: true})
+	for i := 0; i < 5; i++ {
+		l.ErrorEveryN(2, "sampled")
+	}
+	got := l.w.(*flushBuffer).String()
+	for _, want := range []string{"] [x1] sampled\n", "] [x3] sampled\n", "] [x5] sampled\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Missing %q in %q", want, got)
+		}
+	}
+}
+
+func TestSample(t *testing.T) {
+	defer func(f func() float64) { sampleFloat = f }(sampleFloat)
+	sampleFloat = func() float64 { return 0.5 }
+
+	newTestLogger()
+	ctx := Sample(NewContext(context.Background(), testLogger), 0.1)
+	if keep, ok := Sampled(ctx); keep || !ok {
+		t.Fatalf("Sampled() = %v, %v, want false, true", keep, ok)
+	}
+	// The decision is kept however rate changes, and applied to Loggers
+	// derived for the request.
+	ctx = Sample(ctx, 1)
+	InfoCtx(ctx, "dropped")
+	FromContext(ctx).With("step", 2).Warning("dropped")
+	InfoCtx(NewContext(ctx, testLogger.Named("db")), "dropped")
+	testLogger.WithSampling(ctx).Info("dropped")
+	ErrorCtx(ctx, "kept")
+	testLogger.Info("unsampled")
+
+	lines := strings.Split(strings.TrimSpace(contents()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "] kept") || !strings.HasSuffix(lines[1], "] unsampled") {
+		t.Errorf("Wrong lines: %q", lines)
+	}
+
+	newTestLogger()
+	InfoCtx(Sample(NewContext(context.Background(), testLogger), 0.9), "kept")
+	if !strings.HasSuffix(contents(), "] kept\n") {
+		t.Errorf("Wrong output: %q", contents())
+	}
+}
+
+func TestSampleFollowsTrace(t *testing.T) {
+	defer func(f func() float64) { sampleFloat = f }(sampleFloat)
+	sampleFloat = func() float64 { return 0 }
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x01},
+		SpanID:  trace.SpanID{0x01},
+	})
+	ctx := Sample(trace.ContextWithSpanContext(context.Background(), sc), 1)
+	if keep, _ := Sampled(ctx); keep {
+		t.Error("Kept the logs of a trace that isn't sampled")
+	}
+	ctx = Sample(trace.ContextWithSpanContext(context.Background(), sc.WithTraceFlags(trace.FlagsSampled)), 0)
+	if keep, _ := Sampled(ctx); !keep {
+		t.Error("Dropped the logs of a trace that is sampled")
+	}
+}
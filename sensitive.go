@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync/atomic"
+)
+
+// SecretStr constructs a Field with a string value that must never be
+// logged, such as a password or a token, which is written as "xxxxx". The
+// value is masked when the Field is constructed, so it doesn't reach any
+// encoder, destination, tail, or the entries kept by Options.DebugBacklog
+// and Options.FlightRecorder.
+func SecretStr(key, val string) Field {
+	return Str(key, redacted)
+}
+
+// PIIStr constructs a Field with a string value that identifies a person,
+// such as an email address, which is written as a keyed hash of the value,
+// e.g. "pii:3f2a9c01b4d5e6f7", so that entries about the same person can be
+// correlated without logging who they are. As with SecretStr the value is
+// hashed when the Field is constructed. The key is random for each process,
+// see SetPIIKey to correlate entries across processes.
+func PIIStr(key, val string) Field {
+	return Str(key, PIIHash(val))
+}
+
+// PIIHash returns the hash PIIStr writes for val, e.g. to find the entries
+// about a person, see Logger.Purge.
+func PIIHash(val string) string {
+	k, _ := piiKey.Load().([]byte)
+	mac := hmac.New(sha256.New, k)
+	mac.Write([]byte(val))
+	return "pii:" + hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// piiKey holds the []byte key PIIStr hashes values with.
+var piiKey atomic.Value
+
+func init() {
+	k := make([]byte, 32)
+	rand.Read(k)
+	piiKey.Store(k)
+}
+
+// SetPIIKey sets the key PIIStr hashes values with, which must be kept as
+// secret as the values, since the values can be guessed by anyone with the
+// key. Processes with the same key write the same hash for the same value.
+func SetPIIKey(key []byte) {
+	piiKey.Store(append([]byte(nil), key...))
+}
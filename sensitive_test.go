@@ -0,0 +1,40 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSecretStr(t *testing.T) {
+	for _, format := range []Format{TextFormat, JSONFormat} {
+		b := &flushBuffer{}
+		l := NewFromOptions(&Options{SyncWriter: b, Format: format})
+		l.InfoFields("login", SecretStr("password", "hunter2"))
+		if got := b.String(); strings.Contains(got, "hunter2") || !strings.Contains(got, "xxxxx") {
+			t.Errorf("Secret not masked in %v: %q", format, got)
+		}
+	}
+}
+
+func TestPIIStr(t *testing.T) {
+	defer piiKey.Store(piiKey.Load())
+	SetPIIKey([]byte("key"))
+	a, again, other := PIIStr("email", "a@example.com"), PIIStr("email", "a@example.com"), PIIStr("email", "b@example.com")
+	if a.str != again.str || a.str == other.str {
+		t.Errorf("Wrong hashes: %q %q %q", a.str, again.str, other.str)
+	}
+	if !strings.HasPrefix(a.str, "pii:") || len(a.str) != len("pii:")+16 || strings.Contains(a.str, "example") {
+		t.Errorf("Wrong hash: %q", a.str)
+	}
+	SetPIIKey([]byte("other key"))
+	if PIIStr("email", "a@example.com").str == a.str {
+		t.Error("Hash doesn't depend on the key")
+	}
+
+	b := &flushBuffer{}
+	l := NewFromOptions(&Options{SyncWriter: b, Formatter: &ConsoleFormatter{Width: -1}})
+	l.InfoFields("signed up", PIIStr("email", "a@example.com"))
+	if got := b.String(); strings.Contains(got, "a@example.com") || !strings.Contains(got, " email=pii:") {
+		t.Errorf("PII not hashed: %q", got)
+	}
+}
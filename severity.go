@@ -0,0 +1,253 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// LevelEnvVar is the environment variable read by New and NewFromOptions
+// to set the verbosity without code changes, e.g. LOGGER_LEVEL=debug. Its
+// value is a severity name, which overrides Options.MinSeverity and
+// Options.IncludeDebug: Debug entries are included if it's "debug", and
+// entries below it are dropped.
+const LevelEnvVar = "LOGGER_LEVEL"
+
+// Severity is the severity of a log entry, for the settings that take one.
+type Severity int32
+
+// These constants are the severities in order of increasing severity.
+const (
+	DebugSeverity   = Severity(debugLog)
+	InfoSeverity    = Severity(infoLog)
+	WarningSeverity = Severity(warningLog)
+	ErrorSeverity   = Severity(errorLog)
+	FatalSeverity   = Severity(fatalLog)
+)
+
+// customSeverity is a severity added by RegisterSeverity.
+type customSeverity struct {
+	name string
+	char byte
+
+	// base is the built in severity the custom one is counted as by Job,
+	// which is the most severe one it's above or equal to.
+	base severity
+}
+
+// severityTable holds the custom severities, immutable once stored.
+type severityTable struct {
+	// custom are the custom severities, from fatalLog+1 on.
+	custom []customSeverity
+
+	// ranks holds the position of each severity, built in and custom, in
+	// order of increasing severity.
+	ranks []int
+}
+
+var (
+	// severities holds a *severityTable, or nil if no custom severities
+	// have been registered.
+	severities atomic.Value
+
+	// severitiesMu serializes RegisterSeverity.
+	severitiesMu sync.Mutex
+)
+
+// loadSeverities returns the current severityTable, or nil.
+func loadSeverities() *severityTable {
+	t, _ := severities.Load().(*severityTable)
+	return t
+}
+
+// RegisterSeverity adds a severity named name, written with char in the
+// glog header, ordered immediately above after, for applications with
+// levels like NOTICE or AUDIT:
+//
+//	var Notice = logger.RegisterSeverity("NOTICE", 'N', logger.InfoSeverity)
+//	...
+//	l.Log(Notice, "config reloaded")
+//
+// A custom severity is filtered by MinSeverity and Registry.SetLevel
+// according to its order, and never exits. RegisterSeverity is intended to
+// be called from an init function, and panics if name or char are already
+// in use, char isn't an upper case letter, or after isn't a severity.
+func RegisterSeverity(name string, char byte, after Severity) Severity {
+	severitiesMu.Lock()
+	defer severitiesMu.Unlock()
+	if char < 'A' || char > 'Z' {
+		panic(fmt.Sprintf("logger: RegisterSeverity char %q isn't an upper case letter", char))
+	}
+	if _, err := ParseSeverity(name); err == nil {
+		panic("logger: RegisterSeverity called twice for " + name)
+	}
+	if _, ok := severityFromChar(char); ok {
+		panic(fmt.Sprintf("logger: RegisterSeverity char %q is already in use", char))
+	}
+	if !after.valid() {
+		panic(fmt.Sprintf("logger: RegisterSeverity after %v isn't a severity", after))
+	}
+
+	old := loadSeverities()
+	t := &severityTable{}
+	var order []severity
+	if old != nil {
+		t.custom = append(t.custom, old.custom...)
+		order = make([]severity, len(old.ranks))
+		for s, rank := range old.ranks {
+			order[rank] = severity(s)
+		}
+	} else {
+		order = []severity{debugLog, infoLog, warningLog, errorLog, fatalLog}
+	}
+	s := severity(int(fatalLog) + 1 + len(t.custom))
+	var inserted []severity
+	for _, o := range order {
+		inserted = append(inserted, o)
+		if o == severity(after) {
+			inserted = append(inserted, s)
+		}
+	}
+	base := severity(after)
+	if old != nil && base > fatalLog {
+		base = old.custom[base-fatalLog-1].base
+	}
+	t.custom = append(t.custom, customSeverity{name: strings.ToUpper(name), char: char, base: base})
+	t.ranks = make([]int, len(inserted))
+	for rank, o := range inserted {
+		t.ranks[o] = rank
+	}
+	severities.Store(t)
+	return Severity(s)
+}
+
+// valid returns true if s is a built in or registered severity.
+func (s Severity) valid() bool {
+	if s >= DebugSeverity && s <= FatalSeverity {
+		return true
+	}
+	t := loadSeverities()
+	return t != nil && s > FatalSeverity && int(s) < len(t.ranks)
+}
+
+// String returns the name of the severity, e.g. "INFO".
+func (s Severity) String() string {
+	if !s.valid() {
+		return fmt.Sprintf("Severity(%d)", int32(s))
+	}
+	return severity(s).name()
+}
+
+// ParseSeverity returns the Severity named name, ignoring case, e.g. "info"
+// or "WARNING", including those added by RegisterSeverity.
+func ParseSeverity(name string) (Severity, error) {
+	for i, n := range severityName {
+		if strings.EqualFold(n, name) {
+			return Severity(i), nil
+		}
+	}
+	if t := loadSeverities(); t != nil {
+		for i, c := range t.custom {
+			if strings.EqualFold(c.name, name) {
+				return Severity(int(fatalLog) + 1 + i), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unknown severity %q", name)
+}
+
+// custom returns the customSeverity for s, which must be above fatalLog, or
+// false if it isn't registered.
+func (s severity) custom() (customSeverity, bool) {
+	t := loadSeverities()
+	if t == nil || s <= fatalLog || int(s-fatalLog-1) >= len(t.custom) {
+		return customSeverity{}, false
+	}
+	return t.custom[s-fatalLog-1], true
+}
+
+// name returns the name of s, or "INFO" for an unknown severity.
+func (s severity) name() string {
+	if s >= debugLog && s <= fatalLog {
+		return severityName[s]
+	}
+	if c, ok := s.custom(); ok {
+		return c.name
+	}
+	return severityName[infoLog]
+}
+
+// char returns the header character of s, or 'I' for an unknown severity.
+func (s severity) char() byte {
+	if s >= debugLog && s <= fatalLog {
+		return severityChar[s]
+	}
+	if c, ok := s.custom(); ok {
+		return c.char
+	}
+	return severityChar[infoLog]
+}
+
+// base returns the built in severity s is counted as.
+func (s severity) base() severity {
+	if s <= fatalLog {
+		return s
+	}
+	if c, ok := s.custom(); ok {
+		return c.base
+	}
+	return infoLog
+}
+
+// severityFromChar returns the severity written with char in the header.
+func severityFromChar(char byte) (severity, bool) {
+	if i := strings.IndexByte(severityChar, char); i >= 0 {
+		return severity(i), true
+	}
+	if t := loadSeverities(); t != nil {
+		for i, c := range t.custom {
+			if c.char == char {
+				return severity(int(fatalLog) + 1 + i), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// less returns true if a is less severe than b.
+func (a Severity) less(b Severity) bool {
+	t := loadSeverities()
+	if t == nil || !a.valid() || !b.valid() {
+		return a < b
+	}
+	return t.ranks[a] < t.ranks[b]
+}
+
+// SetMinSeverity drops the entries below s from then on, except for Fatal
+// ones. Overrides set with Registry.SetLevel take precedence.
+func (l *Logger) SetMinSeverity(s Severity) {
+	atomic.StoreInt32(&l.minSeverity, int32(s))
+}
+
+// MinSeverity returns the Severity below which entries are dropped.
+func (l *Logger) MinSeverity() Severity {
+	return Severity(atomic.LoadInt32(&l.minSeverity))
+}
+
+// applyLevelEnv applies the value of LevelEnvVar, reporting an invalid one
+// to Options.Diagnostics.
+func (l *Logger) applyLevelEnv() {
+	v := os.Getenv(LevelEnvVar)
+	if v == "" {
+		return
+	}
+	s, err := ParseSeverity(v)
+	if err != nil {
+		l.diagnosef("%s: %s", LevelEnvVar, err)
+		return
+	}
+	l.SetIncludeDebug(s == DebugSeverity)
+	l.SetMinSeverity(s)
+}
@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+var (
+	testNotice = RegisterSeverity("NOTICE", 'N', InfoSeverity)
+	testAudit  = RegisterSeverity("audit", 'A', testNotice)
+)
+
+func TestRegisterSeverity(t *testing.T) {
+	newTestLogger()
+	testLogger.Log(testNotice, "config reloaded")
+	testLogger.Logf(testAudit, "user %s logged in", "bob")
+	testLogger.Log(DebugSeverity, "hidden")
+	testLogger.Log(Severity(99), "unknown")
+
+	lines := strings.Split(strings.TrimSpace(contents()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Wrong number of lines: %q", lines)
+	}
+	if !strings.HasPrefix(lines[0], "N") || !strings.Contains(lines[0], " severity_test.go:") || !strings.HasSuffix(lines[0], "] config reloaded") {
+		t.Errorf("Wrong line: %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "A") || !strings.HasSuffix(lines[1], "] user bob logged in") {
+		t.Errorf("Wrong line: %q", lines[1])
+	}
+
+	// NOTICE and AUDIT sit between INFO and WARNING.
+	if !InfoSeverity.less(testNotice) || !testNotice.less(testAudit) || !testAudit.less(WarningSeverity) {
+		t.Error("Wrong order")
+	}
+	newTestLogger()
+	testLogger.SetMinSeverity(testAudit)
+	testLogger.Log(testNotice, "dropped")
+	testLogger.Info("dropped")
+	testLogger.Log(testAudit, "kept")
+	testLogger.Warning("kept")
+	if got := contents(); strings.Contains(got, "dropped") || strings.Count(got, "kept") != 2 {
+		t.Errorf("Wrong output: %q", got)
+	}
+
+	if s, err := ParseSeverity("Audit"); err != nil || s != testAudit || s.String() != "AUDIT" {
+		t.Errorf("Wrong parse: %v %v", s, err)
+	}
+	entries := testLogger.CaptureLogs(func() { testLogger.Log(testAudit, "captured") })
+	if len(entries) != 1 || entries[0].Severity != "AUDIT" {
+		t.Errorf("Wrong captured entries: %#v", entries)
+	}
+
+	b := &flushBuffer{}
+	NewFromOptions(&Options{SyncWriter: b, Format: JSONFormat}).Log(testNotice, "json")
+	if !strings.Contains(b.String(), `"severity":"NOTICE"`) {
+		t.Errorf("Wrong JSON: %q", b.String())
+	}
+
+	for name, f := range map[string]func(){
+		"name":  func() { RegisterSeverity("info", 'X', InfoSeverity) },
+		"char":  func() { RegisterSeverity("OTHER", 'W', InfoSeverity) },
+		"lower": func() { RegisterSeverity("OTHER", 'x', InfoSeverity) },
+		"after": func() { RegisterSeverity("OTHER", 'X', Severity(99)) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected a panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestJobCountsCustomSeverities(t *testing.T) {
+	newTestLogger()
+	job := testLogger.StartJob("import")
+	job.Logger().Log(testAudit, "counted as info")
+	job.Logger().Warning("warned")
+	job.End()
+	if got := contents(); !strings.Contains(got, "warnings=1 errors=0") {
+		t.Errorf("Wrong summary: %q", got)
+	}
+}
@@ -0,0 +1,160 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// write writes p, which is all or part of the entry described by e, to the
+// destination, or to the fallback once the Logger has been shut down,
+// returning the error from the destination.
+func (l *Logger) write(e entryInfo, p []byte) error {
+	if e.written != nil {
+		*e.written += len(p)
+	}
+	if atomic.LoadInt32(&l.shutdown) == 1 {
+		return l.writeFallback(e, p)
+	}
+	if l.governor != nil {
+		// Deferred first so it runs after wMu is released.
+		defer l.observeWrite(l.timeNow())
+	}
+	l.wMu.RLock()
+	defer l.wMu.RUnlock()
+	// Shutdown may have closed the destination since the check above, but
+	// can't until wMu is released once it's held.
+	if atomic.LoadInt32(&l.shutdown) == 1 {
+		return l.writeFallback(e, p)
+	}
+	var err error
+	if ew, ok := l.w.(entryWriter); ok {
+		_, err = ew.writeEntry(e, p)
+	} else {
+		_, err = l.w.Write(p)
+	}
+	l.recordWriteResult(err)
+	return err
+}
+
+// writeFallback writes p, which is all or part of the entry described by
+// e, to Options.Fallback, or stderr, once the Logger has been shut down.
+func (l *Logger) writeFallback(e entryInfo, p []byte) error {
+	if l.fallback == nil {
+		os.Stderr.Write(p)
+		return nil
+	}
+	writeTo(l.fallback, e, p)
+	return nil
+}
+
+// writer returns the current destination.
+func (l *Logger) writer() SyncWriter {
+	l.wMu.RLock()
+	defer l.wMu.RUnlock()
+	return l.w
+}
+
+// sync syncs the destination, or Options.Fallback, if set, once the
+// Logger has been shut down.
+func (l *Logger) sync() error {
+	if atomic.LoadInt32(&l.shutdown) == 1 {
+		return l.syncFallback()
+	}
+	l.wMu.RLock()
+	defer l.wMu.RUnlock()
+	if atomic.LoadInt32(&l.shutdown) == 1 {
+		return l.syncFallback()
+	}
+	return l.w.Sync()
+}
+
+// syncFallback syncs Options.Fallback, if set.
+func (l *Logger) syncFallback() error {
+	if l.fallback == nil {
+		return nil
+	}
+	return l.fallback.Sync()
+}
+
+// Flush syncs the destination, which for a Router or MultiSyncWriter syncs
+// each of theirs, and for an AsyncWriter first waits for the queued writes.
+// Once the Logger has been shut down it syncs Options.Fallback, if set.
+// See Options.FlushInterval to flush periodically.
+func (l *Logger) Flush() error {
+	return l.sync()
+}
+
+// flushEvery calls Flush every interval until the Logger is shut down.
+func (l *Logger) flushEvery(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			l.Flush()
+		case <-l.flushStop:
+			return
+		}
+	}
+}
+
+// Shutdown stops writing logs to the destination, sending any further logs
+// to Options.Fallback, or stderr, instead, then waits for in-flight writes
+// to finish, syncs the destination, and closes it if it implements
+// io.Closer. os.Stdout and os.Stderr are never closed.
+//
+// Shutdown returns ctx.Err() if ctx is done before the destination has been
+// synced and closed, otherwise it returns the first error from Sync or
+// Close. Calling Shutdown more than once is a no-op.
+func (l *Logger) Shutdown(ctx context.Context) error {
+	return l.shutdownDepth(ctx, 1)
+}
+
+// Close is Shutdown without a deadline: any writes queued by the
+// destination, such as those of an AsyncWriter, are made, and it's synced
+// and closed, so the last entries aren't lost. Entries logged afterwards
+// go to Options.Fallback, or stderr.
+func (l *Logger) Close() error {
+	return l.shutdownDepth(context.Background(), 1)
+}
+
+// shutdownDepth implements Shutdown, which was called from the call site
+// depth frames above the caller of shutdownDepth.
+func (l *Logger) shutdownDepth(ctx context.Context, depth int) error {
+	if atomic.LoadInt32(&l.shutdown) == 1 {
+		return nil
+	}
+	if l.lifecycle != nil {
+		_, file, line, _ := runtime.Caller(1 + depth)
+		l.logExiting("shutdown", file, line)
+	}
+	if !atomic.CompareAndSwapInt32(&l.shutdown, 0, 1) {
+		return nil
+	}
+	if l.flushStop != nil {
+		close(l.flushStop)
+	}
+	done := make(chan error, 1)
+	go func() {
+		// Wait for any writes that started before shutdown to finish.
+		l.wMu.Lock()
+		defer l.wMu.Unlock()
+		err := l.w.Sync()
+		if c, ok := l.w.(io.Closer); ok && l.w != os.Stdout && l.w != os.Stderr {
+			if closeErr := c.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
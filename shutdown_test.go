@@ -0,0 +1,190 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// closeBuffer is a flushBuffer that records being synced and closed.
+type closeBuffer struct {
+	flushBuffer
+	synced bool
+	closed bool
+	block  chan struct{}
+}
+
+func (c *closeBuffer) Sync() error {
+	if c.block != nil {
+		<-c.block
+	}
+	c.synced = true
+	return nil
+}
+
+func (c *closeBuffer) Close() error {
+	c.closed = true
+	return errors.New("close failed")
+}
+
+func TestShutdown(t *testing.T) {
+	w := &closeBuffer{}
+	l := NewFromOptions(&Options{SyncWriter: w})
+	l.Info("before")
+	if err := l.Shutdown(context.Background()); err == nil || err.Error() != "close failed" {
+		t.Errorf("Expected the Close error, got %v", err)
+	}
+	if !w.synced || !w.closed {
+		t.Errorf("Destination not synced and closed: %v %v", w.synced, w.closed)
+	}
+	l.Info("after")
+	if !strings.Contains(w.String(), "before") || strings.Contains(w.String(), "after") {
+		t.Errorf("Logs after Shutdown should not be written to the destination: %q", w.String())
+	}
+	if err := l.Shutdown(context.Background()); err != nil {
+		t.Errorf("A second Shutdown should be a no-op, got %s", err)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	w := &closeBuffer{block: make(chan struct{})}
+	defer close(w.block)
+	l := NewFromOptions(&Options{SyncWriter: w})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected a deadline error, got %v", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	g := &gatedWriter{gate: make(chan struct{})}
+	fallback := &flushBuffer{}
+	l := NewFromOptions(&Options{SyncWriter: g, AsyncQueueSize: 10, Fallback: fallback})
+	l.Info("queued")
+	close(g.gate)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(g.String(), "] queued\n") {
+		t.Errorf("The queued entry wasn't written by Close: %q", g.String())
+	}
+	l.Info("after")
+	if !strings.HasSuffix(fallback.String(), "] after\n") || strings.Contains(g.String(), "after") {
+		t.Errorf("Entries after Close should go to the fallback: %q %q", fallback.String(), g.String())
+	}
+	if err := l.Close(); err != nil {
+		t.Errorf("A second Close should be a no-op, got %s", err)
+	}
+}
+
+// closeCheckWriter counts the writes and syncs made after it's closed.
+type closeCheckWriter struct {
+	closed int32
+	late   int32
+}
+
+func (c *closeCheckWriter) Write(p []byte) (int, error) {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		atomic.AddInt32(&c.late, 1)
+	}
+	return len(p), nil
+}
+
+func (c *closeCheckWriter) Sync() error {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		atomic.AddInt32(&c.late, 1)
+	}
+	return nil
+}
+
+func (c *closeCheckWriter) Close() error {
+	atomic.StoreInt32(&c.closed, 1)
+	return nil
+}
+
+func TestShutdownWaitsForWrites(t *testing.T) {
+	w := &closeCheckWriter{}
+	var armed int32
+	var once sync.Once
+	checked := make(chan struct{})
+	closed := make(chan struct{})
+	l := NewFromOptions(&Options{
+		SyncWriter: w,
+		Fallback:   &syncBuffer{},
+		// The Governor reads the time after the write has checked whether
+		// the Logger is shut down, but before it waits for Shutdown.
+		Governor: &Governor{MaxLatency: time.Hour},
+		Now: func() time.Time {
+			if atomic.LoadInt32(&armed) == 1 {
+				once.Do(func() {
+					close(checked)
+					<-closed
+				})
+			}
+			return time.Now()
+		},
+	})
+	atomic.StoreInt32(&armed, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.Raw("racing\n")
+	}()
+	<-checked
+	if err := l.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	close(closed)
+	<-done
+	if late := atomic.LoadInt32(&w.late); late > 0 {
+		t.Errorf("%d writes after the destination was closed", late)
+	}
+}
+
+// countingSyncer is a flushBuffer that counts its syncs.
+type countingSyncer struct {
+	flushBuffer
+	syncs int32
+}
+
+func (c *countingSyncer) Sync() error {
+	atomic.AddInt32(&c.syncs, 1)
+	return nil
+}
+
+func TestFlush(t *testing.T) {
+	g := &gatedWriter{gate: make(chan struct{})}
+	l := NewFromOptions(&Options{SyncWriter: g, AsyncQueueSize: 10})
+	defer l.Close()
+	l.Info("queued")
+	close(g.gate)
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(g.String(), "] queued\n") {
+		t.Errorf("Flush didn't wait for the queued entry: %q", g.String())
+	}
+}
+
+func TestFlushInterval(t *testing.T) {
+	c := &countingSyncer{}
+	l := NewFromOptions(&Options{SyncWriter: c, FlushInterval: time.Millisecond})
+	for i := 0; atomic.LoadInt32(&c.syncs) < 2; i++ {
+		if i == 1000 {
+			t.Fatal("Not flushed periodically")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	l.Close()
+	// Close syncs once more, after which the ticker has stopped.
+	n := atomic.LoadInt32(&c.syncs)
+	time.Sleep(10 * time.Millisecond)
+	if got := atomic.LoadInt32(&c.syncs); got != n {
+		t.Errorf("Flushed %d more times after Close", got-n)
+	}
+}
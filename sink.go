@@ -0,0 +1,233 @@
+package logger
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SinkFactory returns the SyncWriter described by u.
+type SinkFactory func(u *url.URL) (SyncWriter, error)
+
+var (
+	// sinks are the SinkFactorys registered by URL scheme, see RegisterSink.
+	sinks = map[string]SinkFactory{
+		"file":    fileSink,
+		"stderr":  func(*url.URL) (SyncWriter, error) { return os.Stderr, nil },
+		"stdout":  func(*url.URL) (SyncWriter, error) { return os.Stdout, nil },
+		"tcp":     tcpSink,
+		"forward": forwardSink,
+		"syslog":  syslogSink,
+		"systemd": systemdSink,
+		"gelf":    gelfSink,
+	}
+
+	// sinksMu protects sinks.
+	sinksMu sync.RWMutex
+)
+
+// RegisterSink makes the destinations created by factory available to
+// NewSink for URLs with the given scheme, e.g. "loki". It's intended to be
+// called from an init function, and panics if factory is nil or scheme is
+// already registered.
+func RegisterSink(scheme string, factory SinkFactory) {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	if factory == nil {
+		panic("logger: RegisterSink factory is nil")
+	}
+	if _, ok := sinks[scheme]; ok {
+		panic("logger: RegisterSink called twice for " + scheme)
+	}
+	sinks[scheme] = factory
+}
+
+// NewSink returns the destination described by rawURL, so a single string,
+// such as the value of a LOG_OUTPUT environment variable, can select any
+// destination:
+//
+//	w, err := logger.NewSink(os.Getenv("LOG_OUTPUT"))
+//	...
+//	l := logger.NewFromOptions(&logger.Options{SyncWriter: w})
+//
+// The built in schemes are:
+//
+//	file:///var/log/app.log  appends to the file, see NewFileWriter.
+//	stderr: or stdout:       writes to os.Stderr or os.Stdout.
+//	tcp://host:514           writes the raw lines over TCP, reconnecting as needed.
+//	forward://host:port      sends to a ForwardServer, see NewForwardWriter.
+//	syslog: or syslog://host:514
+//	                         sends to the local syslog daemon, or to host
+//	                         over UDP, see NewSyslogWriter. The query can set
+//	                         network=tcp, format=rfc3164, facility=16, and
+//	                         tag=app.
+//	systemd: or systemd:stderr
+//	                         writes to os.Stdout, or os.Stderr, with the
+//	                         priority prefixes read by systemd, see
+//	                         NewSystemdWriter.
+//	gelf://host:12201        sends to a Graylog GELF input over UDP, see
+//	                         NewGELFWriter. The query can set network=tcp,
+//	                         and compress=gzip or compress=zlib.
+//
+// Others can be added with RegisterSink.
+func NewSink(rawURL string) (SyncWriter, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	sinksMu.RLock()
+	factory, ok := sinks[u.Scheme]
+	sinksMu.RUnlock()
+	if !ok {
+		sinksMu.RLock()
+		schemes := make([]string, 0, len(sinks))
+		for s := range sinks {
+			schemes = append(schemes, s)
+		}
+		sinksMu.RUnlock()
+		sort.Strings(schemes)
+		return nil, fmt.Errorf("unknown sink scheme %q in %q, registered schemes are %q", u.Scheme, rawURL, schemes)
+	}
+	return factory(u)
+}
+
+func fileSink(u *url.URL) (SyncWriter, error) {
+	if u.Path == "" {
+		return nil, fmt.Errorf("no path in %q", u.String())
+	}
+	return NewFileWriter(u.Path)
+}
+
+func gelfSink(u *url.URL) (SyncWriter, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("no host in %q", u.String())
+	}
+	q := u.Query()
+	o := &GELFOptions{Network: q.Get("network"), Addr: u.Host}
+	switch strings.ToLower(q.Get("compress")) {
+	case "":
+	case "gzip":
+		o.Compression = GELFGzip
+	case "zlib":
+		o.Compression = GELFZlib
+	default:
+		return nil, fmt.Errorf("unknown GELF compression %q in %q", q.Get("compress"), u.String())
+	}
+	return NewGELFWriter(o)
+}
+
+func systemdSink(u *url.URL) (SyncWriter, error) {
+	switch u.Opaque {
+	case "", "stdout":
+		return NewSystemdWriter(os.Stdout), nil
+	case "stderr":
+		return NewSystemdWriter(os.Stderr), nil
+	}
+	return nil, fmt.Errorf("unknown stream %q in %q, want stdout or stderr", u.Opaque, u.String())
+}
+
+func tcpSink(u *url.URL) (SyncWriter, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("no host in %q", u.String())
+	}
+	return &tcpWriter{addr: u.Host}, nil
+}
+
+func forwardSink(u *url.URL) (SyncWriter, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("no host in %q", u.String())
+	}
+	return NewForwardWriter(u.Host), nil
+}
+
+func syslogSink(u *url.URL) (SyncWriter, error) {
+	q := u.Query()
+	o := &SyslogOptions{Addr: u.Host, Tag: q.Get("tag")}
+	if o.Addr != "" {
+		o.Network = "udp"
+	}
+	if network := q.Get("network"); network != "" {
+		o.Network = network
+	}
+	switch strings.ToLower(q.Get("format")) {
+	case "", "rfc5424":
+	case "rfc3164":
+		o.Format = RFC3164
+	default:
+		return nil, fmt.Errorf("unknown syslog format %q in %q", q.Get("format"), u.String())
+	}
+	if facility := q.Get("facility"); facility != "" {
+		n, err := strconv.Atoi(facility)
+		if err != nil || n < 0 || n > 23 {
+			return nil, fmt.Errorf("bad syslog facility %q in %q", facility, u.String())
+		}
+		o.Facility = n
+	}
+	return NewSyslogWriter(o)
+}
+
+// tcpWriteTimeout is how long a tcpWriter waits for a write to be taken by
+// the destination, after which the connection is treated as failed. It's a
+// variable for testing.
+var tcpWriteTimeout = 5 * time.Second
+
+// tcpWriter writes to a TCP connection, connecting on the first write and
+// reconnecting after any error, backing off as a ForwardWriter does. Writes
+// made while the destination can't be reached, or that it doesn't take
+// within tcpWriteTimeout, are lost.
+type tcpWriter struct {
+	addr string
+
+	// mu protects conn and backoff.
+	mu      sync.Mutex
+	conn    net.Conn
+	backoff reconnectBackoff
+}
+
+// Write implements SyncWriter.
+func (t *tcpWriter) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conn == nil {
+		if err := t.backoff.wait(); err != nil {
+			return 0, err
+		}
+		conn, err := net.DialTimeout("tcp", t.addr, forwardDialTimeout)
+		if err != nil {
+			t.backoff.failed(err)
+			return 0, err
+		}
+		t.backoff.connected()
+		t.conn = conn
+	}
+	t.conn.SetWriteDeadline(time.Now().Add(tcpWriteTimeout))
+	n, err := t.conn.Write(p)
+	if err != nil {
+		t.conn.Close()
+		t.conn = nil
+	}
+	return n, err
+}
+
+// Sync implements SyncWriter.
+func (t *tcpWriter) Sync() error {
+	return nil
+}
+
+// Close closes the connection, if any. A later write connects again.
+func (t *tcpWriter) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conn == nil {
+		return nil
+	}
+	err := t.conn.Close()
+	t.conn = nil
+	return err
+}
@@ -0,0 +1,185 @@
+package logger
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewSinkFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w, err := NewSink("file://" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewFromOptions(&Options{SyncWriter: w})
+	l.Info("to a file")
+	w.(*FileWriter).Close()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(b), "] to a file\n") {
+		t.Errorf("Wrong contents: %q", b)
+	}
+}
+
+func TestNewSinkTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := make(chan string)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		lines <- line
+	}()
+
+	w, err := NewSink("tcp://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	NewFromOptions(&Options{SyncWriter: w}).Info("over tcp")
+	if got := <-lines; !strings.HasSuffix(got, "] over tcp\n") {
+		t.Errorf("Wrong line: %q", got)
+	}
+}
+
+// Test that a tcp sink gives up on a destination that stops reading, and
+// closes its connection when closed.
+func TestNewSinkTCPTimeoutAndClose(t *testing.T) {
+	defer func(timeout time.Duration) { tcpWriteTimeout = timeout }(tcpWriteTimeout)
+	tcpWriteTimeout = 10 * time.Millisecond
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	conns := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+		}
+	}()
+
+	w, err := NewSink("tcp://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nothing is read, so the socket buffers fill up and a write times out.
+	if _, err := w.Write([]byte("connect\n")); err != nil {
+		t.Fatal(err)
+	}
+	stalled := <-conns
+	defer stalled.Close()
+	big := make([]byte, 1<<20)
+	var writeErr error
+	for i := 0; i < 64 && writeErr == nil; i++ {
+		_, writeErr = w.Write(big)
+	}
+	if ne, ok := writeErr.(net.Error); !ok || !ne.Timeout() {
+		t.Fatalf("Got %v, want a write to time out", writeErr)
+	}
+
+	if _, err := w.Write([]byte("reconnected\n")); err != nil {
+		t.Fatal(err)
+	}
+	conn := <-conns
+	defer conn.Close()
+	if err := w.(io.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(conn)
+	if string(got) != "reconnected\n" {
+		t.Errorf("Got %q before the connection was closed", got)
+	}
+}
+
+// Test that after failing to connect a tcp sink doesn't try again until its
+// backoff expires.
+func TestNewSinkTCPBackoff(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := ln.Addr().String()
+	ln.Close()
+	w, err := NewSink("tcp://" + down)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("lost\n")); err == nil {
+		t.Fatal("Write succeeded with nothing listening")
+	}
+
+	ln, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	tw := w.(*tcpWriter)
+	tw.addr = ln.Addr().String()
+	if _, err := w.Write([]byte("lost\n")); err == nil {
+		t.Fatal("Write connected before the backoff expired")
+	}
+	tw.backoff.next = time.Time{}
+	if _, err := w.Write([]byte("sent\n")); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if line, _ := bufio.NewReader(conn).ReadString('\n'); line != "sent\n" {
+		t.Errorf("Wrong line: %q", line)
+	}
+}
+
+func TestRegisterSink(t *testing.T) {
+	b := &flushBuffer{}
+	var got *url.URL
+	RegisterSink("test-mem", func(u *url.URL) (SyncWriter, error) {
+		got = u
+		return b, nil
+	})
+	defer func() {
+		sinksMu.Lock()
+		delete(sinks, "test-mem")
+		sinksMu.Unlock()
+	}()
+
+	w, err := NewSink("test-mem://bucket/path?x=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w != b || got.Host != "bucket" || got.Query().Get("x") != "1" {
+		t.Errorf("Wrong sink: %v %v", w, got)
+	}
+
+	for _, bad := range []string{"missing://x", "file://", "tcp:///path", "%zz"} {
+		if _, err := NewSink(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+	if w, err := NewSink("stderr:"); err != nil || w != os.Stderr {
+		t.Errorf("Wrong stderr sink: %v %v", w, err)
+	}
+}
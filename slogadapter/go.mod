@@ -0,0 +1,15 @@
+module github.com/jcgregorio/logger/slogadapter
+
+go 1.18
+
+require (
+	github.com/jcgregorio/logger v0.0.0-00010101000000-000000000000
+	github.com/jcgregorio/slog v0.0.0-20190423190439-e6f2d537f900
+)
+
+require (
+	go.opentelemetry.io/otel v1.14.0 // indirect
+	go.opentelemetry.io/otel/trace v1.14.0 // indirect
+)
+
+replace github.com/jcgregorio/logger => ../
@@ -0,0 +1,31 @@
+// Package slogadapter ties the loggers of github.com/jcgregorio/logger to
+// the slog.Logger interface of github.com/jcgregorio/slog, which the logger
+// package no longer depends on. It's a module of its own so only the users
+// of slog depend on it:
+//
+//	var log slog.Logger = slogadapter.New(logger.New())
+//
+// Since logger.Interface and slog.Logger have the same methods, the loggers
+// can also be used as a slog.Logger directly. This package fails to build
+// if the two ever differ.
+package slogadapter
+
+import (
+	"github.com/jcgregorio/logger"
+	"github.com/jcgregorio/slog"
+)
+
+// New returns l as a slog.Logger.
+func New(l logger.Interface) slog.Logger {
+	return l
+}
+
+// Assert that logger.Interface and slog.Logger are the same, and that the
+// loggers implement slog.Logger:
+var (
+	_ slog.Logger      = logger.Interface(nil)
+	_ logger.Interface = slog.Logger(nil)
+	_ slog.Logger      = (*logger.Logger)(nil)
+	_ slog.Logger      = (*logger.NopLogger)(nil)
+	_ slog.Logger      = (*logger.Broadcast)(nil)
+)
@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync/atomic"
+	"time"
+)
+
+// Span times an operation, logging an entry when it begins and a paired
+// entry when it ends, both carrying the same span_id field:
+//
+//	span := l.Span("load-config")
+//	if err := load(); err != nil {
+//		span.Fail(err)
+//		return err
+//	}
+//	span.End()
+//
+// This provides lightweight tracing for services without full tracing
+// infrastructure.
+type Span struct {
+	l        *Logger
+	name     string
+	id       string
+	parentID string
+	start    time.Time
+
+	// ended is 1 once End or Fail has been called, accessed atomically.
+	ended int32
+}
+
+// newSpanID returns a random id for a span.
+func newSpanID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// Span logs the beginning of the named operation and returns the Span to
+// end it with.
+func (l *Logger) Span(name string) *Span {
+	s := &Span{
+		l:     l,
+		name:  name,
+		id:    newSpanID(),
+		start: l.timeNow(),
+	}
+	s.log(infoLog, "begin "+name)
+	return s
+}
+
+// Span begins a child operation, whose entries carry this span's id in
+// their parent_id field.
+func (s *Span) Span(name string) *Span {
+	child := &Span{
+		l:        s.l,
+		name:     name,
+		id:       newSpanID(),
+		parentID: s.id,
+		start:    s.l.timeNow(),
+	}
+	child.log(infoLog, "begin "+name)
+	return child
+}
+
+// ID returns the span's id, as logged in its span_id field.
+func (s *Span) ID() string {
+	return s.id
+}
+
+// End logs that the operation completed successfully, along with its
+// duration. Only the first call to End or Fail logs anything.
+func (s *Span) End() {
+	if !atomic.CompareAndSwapInt32(&s.ended, 0, 1) {
+		return
+	}
+	s.log(infoLog, "end "+s.name, Dur("duration", s.l.timeNow().Sub(s.start)), Bool("ok", true))
+}
+
+// Fail logs, as an Error, that the operation failed with err, along with its
+// duration. Only the first call to End or Fail logs anything.
+func (s *Span) Fail(err error) {
+	if !atomic.CompareAndSwapInt32(&s.ended, 0, 1) {
+		return
+	}
+	s.log(errorLog, "end "+s.name, Dur("duration", s.l.timeNow().Sub(s.start)), Bool("ok", false), Err(err))
+}
+
+// log writes an entry for the span, reporting the caller of the exported
+// Span method.
+func (s *Span) log(sev severity, msg string, extra ...Field) {
+	fields := make([]Field, 0, 3+len(extra))
+	fields = append(fields, Str("span", s.name), Str("span_id", s.id))
+	if s.parentID != "" {
+		fields = append(fields, Str("parent_id", s.parentID))
+	}
+	fields = append(fields, extra...)
+	s.l.logDepth(sev, 1, []byte(msg), fields)
+}
@@ -0,0 +1,44 @@
+package logger
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSpan(t *testing.T) {
+	defer func(previous func() time.Time) { timeNow = previous }(timeNow)
+	now := time.Date(2006, 1, 2, 15, 4, 5, 0, time.Local)
+	timeNow = func() time.Time { return now }
+
+	newTestLogger()
+	span := testLogger.Span("load-config")
+	child := span.Span("read-file")
+	now = now.Add(250 * time.Millisecond)
+	child.Fail(errors.New("not found"))
+	span.End()
+	span.End()
+
+	lines := strings.Split(strings.TrimSpace(contents()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Wrong number of lines, got %d want 4: %q", len(lines), lines)
+	}
+	want := []string{
+		"] begin load-config span=load-config span_id=" + span.ID(),
+		"] begin read-file span=read-file span_id=" + child.ID() + " parent_id=" + span.ID(),
+		"] end read-file span=read-file span_id=" + child.ID() + " parent_id=" + span.ID() + ` duration=250ms ok=false error="not found"`,
+		"] end load-config span=load-config span_id=" + span.ID() + " duration=250ms ok=true",
+	}
+	for i, line := range lines {
+		if !strings.HasSuffix(line, want[i]) {
+			t.Errorf("Line %d got %q want suffix %q", i, line, want[i])
+		}
+		if !strings.Contains(line, " span_test.go:") {
+			t.Errorf("Wrong caller reported: %q", line)
+		}
+	}
+	if !strings.HasPrefix(lines[2], "E") {
+		t.Errorf("Failed span should be logged as an Error: %q", lines[2])
+	}
+}
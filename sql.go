@@ -0,0 +1,318 @@
+package logger
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SQLArgPolicy selects how the arguments of SQL queries are logged.
+type SQLArgPolicy int
+
+const (
+	// SQLArgsOmit logs only the number of arguments, as args=N.
+	SQLArgsOmit SQLArgPolicy = iota
+
+	// SQLArgsTypes logs the types of the arguments, e.g. args=[string,int64].
+	SQLArgsTypes
+
+	// SQLArgsLog logs the values of the arguments, e.g. args=[bob,42]. Only
+	// use this if the arguments never hold sensitive data.
+	SQLArgsLog
+)
+
+// SQLOptions control what WrapDriver logs. The zero value logs every query
+// without the values of its arguments.
+type SQLOptions struct {
+	// Args selects how query arguments are logged.
+	Args SQLArgPolicy
+
+	// SampleEvery, if greater than one, logs only the 1st, (n+1)th, (2n+1)th,
+	// etc. successful execution of each distinct query. Failed queries are
+	// always logged.
+	SampleEvery int
+}
+
+// WrapDriver returns a database/sql driver that logs the queries run
+// through d to l, along with their arguments, as selected by o.Args, their
+// durations, and any errors, e.g.:
+//
+//	sql.Register("logged-postgres", logger.WrapDriver(l, &pq.Driver{}, nil))
+//	db, err := sql.Open("logged-postgres", dsn)
+//
+// Successful queries are logged as Info, and failed ones as Errors. The
+// options may be nil.
+func WrapDriver(l *Logger, d driver.Driver, o *SQLOptions) driver.Driver {
+	if o == nil {
+		o = &SQLOptions{}
+	}
+	return &sqlDriver{d: d, log: &sqlLogger{l: l, o: *o}}
+}
+
+// sqlLogger logs queries for the wrapped driver.
+type sqlLogger struct {
+	l *Logger
+	o SQLOptions
+
+	// counts is the number of successful executions of each query, as a
+	// *uint64, for SampleEvery.
+	counts sync.Map
+}
+
+// log logs the execution of query with args that started at start and
+// finished with err.
+func (s *sqlLogger) log(op, query string, args []driver.NamedValue, start time.Time, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		// Nothing was run, database/sql will try again another way.
+		return
+	}
+	if err == nil && s.o.SampleEvery > 1 {
+		counter, _ := s.counts.LoadOrStore(query, new(uint64))
+		if (atomic.AddUint64(counter.(*uint64), 1)-1)%uint64(s.o.SampleEvery) != 0 {
+			return
+		}
+	}
+	fields := []Field{
+		Str("op", op),
+		Str("query", query),
+		s.args(args),
+		Dur("duration", s.l.timeNow().Sub(start)),
+	}
+	if err != nil {
+		s.l.logDepth(errorLog, 0, []byte("sql failed"), append(fields, Err(err)))
+		return
+	}
+	s.l.logDepth(infoLog, 0, []byte("sql"), fields)
+}
+
+// args returns the args Field for args, as selected by SQLOptions.Args.
+func (s *sqlLogger) args(args []driver.NamedValue) Field {
+	if s.o.Args == SQLArgsOmit {
+		return Int("args", len(args))
+	}
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		if s.o.Args == SQLArgsTypes {
+			parts[i] = fmt.Sprintf("%T", arg.Value)
+		} else {
+			parts[i] = renderAny(arg.Value)
+		}
+	}
+	return Str("args", "["+strings.Join(parts, ",")+"]")
+}
+
+type sqlDriver struct {
+	d   driver.Driver
+	log *sqlLogger
+}
+
+// Open implements driver.Driver.
+func (d *sqlDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.d.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{Conn: c, log: d.log}, nil
+}
+
+// sqlConn wraps a driver.Conn, implementing the optional interfaces by
+// falling back to the same behavior as database/sql when the wrapped Conn
+// doesn't implement them.
+type sqlConn struct {
+	driver.Conn
+	log *sqlLogger
+}
+
+// Prepare implements driver.Conn.
+func (c *sqlConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext implements driver.ConnPrepareContext.
+func (c *sqlConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	start := c.log.l.timeNow()
+	var stmt driver.Stmt
+	var err error
+	if cpc, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = cpc.PrepareContext(ctx, query)
+	} else if err = ctx.Err(); err == nil {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		c.log.log("prepare", query, nil, start, err)
+		return nil, err
+	}
+	return &sqlStmt{Stmt: stmt, query: query, log: c.log}, nil
+}
+
+// ExecContext implements driver.ExecerContext.
+func (c *sqlConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := c.log.l.timeNow()
+	var res driver.Result
+	var err error
+	if ec, ok := c.Conn.(driver.ExecerContext); ok {
+		res, err = ec.ExecContext(ctx, query, args)
+	} else if e, ok := c.Conn.(driver.Execer); ok {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			res, err = e.Exec(query, values)
+		}
+	} else {
+		return nil, driver.ErrSkip
+	}
+	c.log.log("exec", query, args, start, err)
+	return res, err
+}
+
+// QueryContext implements driver.QueryerContext.
+func (c *sqlConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := c.log.l.timeNow()
+	var rows driver.Rows
+	var err error
+	if qc, ok := c.Conn.(driver.QueryerContext); ok {
+		rows, err = qc.QueryContext(ctx, query, args)
+	} else if q, ok := c.Conn.(driver.Queryer); ok {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			rows, err = q.Query(query, values)
+		}
+	} else {
+		return nil, driver.ErrSkip
+	}
+	c.log.log("query", query, args, start, err)
+	return rows, err
+}
+
+// BeginTx implements driver.ConnBeginTx.
+func (c *sqlConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if cbt, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return cbt.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != 0 {
+		return nil, errors.New("sql: driver does not support non-default isolation level")
+	}
+	if opts.ReadOnly {
+		return nil, errors.New("sql: driver does not support read-only transactions")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.Conn.Begin()
+}
+
+// Ping implements driver.Pinger.
+func (c *sqlConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// ResetSession implements driver.SessionResetter.
+func (c *sqlConn) ResetSession(ctx context.Context) error {
+	if sr, ok := c.Conn.(driver.SessionResetter); ok {
+		return sr.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid implements driver.Validator.
+func (c *sqlConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+// CheckNamedValue implements driver.NamedValueChecker.
+func (c *sqlConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// sqlStmt wraps a driver.Stmt.
+type sqlStmt struct {
+	driver.Stmt
+	query string
+	log   *sqlLogger
+}
+
+// Exec implements driver.Stmt.
+func (s *sqlStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), valuesToNamedValues(args))
+}
+
+// Query implements driver.Stmt.
+func (s *sqlStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), valuesToNamedValues(args))
+}
+
+// ExecContext implements driver.StmtExecContext.
+func (s *sqlStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := s.log.l.timeNow()
+	var res driver.Result
+	var err error
+	if sec, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = sec.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			res, err = s.Stmt.Exec(values)
+		}
+	}
+	s.log.log("exec", s.query, args, start, err)
+	return res, err
+}
+
+// QueryContext implements driver.StmtQueryContext.
+func (s *sqlStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := s.log.l.timeNow()
+	var rows driver.Rows
+	var err error
+	if sqc, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = sqc.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			rows, err = s.Stmt.Query(values)
+		}
+	}
+	s.log.log("query", s.query, args, start, err)
+	return rows, err
+}
+
+// CheckNamedValue implements driver.NamedValueChecker.
+func (s *sqlStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// namedValuesToValues converts args for drivers that don't support named
+// arguments.
+func namedValuesToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("sql: driver does not support the use of Named Parameters")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
+
+func valuesToNamedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return named
+}
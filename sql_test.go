@@ -0,0 +1,142 @@
+package logger
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// fakeDriver is a minimal driver that only implements the required
+// interfaces, so WrapDriver has to fall back for everything else.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) {
+	if strings.Contains(query, "syntax error") {
+		return nil, errors.New("bad query")
+	}
+	return fakeStmt{query: query}, nil
+}
+
+func (fakeConn) Close() error              { return nil }
+func (fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct {
+	query string
+}
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if strings.Contains(s.query, "fail") {
+		return nil, errors.New("constraint violated")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (fakeStmt) Query(args []driver.Value) (driver.Rows, error) { return fakeRows{}, nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string              { return []string{"n"} }
+func (fakeRows) Close() error                   { return nil }
+func (fakeRows) Next(dest []driver.Value) error { return io.EOF }
+
+// driverConnector opens a sql.DB on a driver without registering it, so
+// the tests can be run more than once.
+type driverConnector struct {
+	d driver.Driver
+}
+
+func (c driverConnector) Connect(context.Context) (driver.Conn, error) { return c.d.Open("") }
+
+func (c driverConnector) Driver() driver.Driver { return c.d }
+
+func openWrapped(t *testing.T, o *SQLOptions) *sql.DB {
+	newTestLogger()
+	return sql.OpenDB(driverConnector{WrapDriver(testLogger, fakeDriver{}, o)})
+}
+
+func TestWrapDriver(t *testing.T) {
+	db := openWrapped(t, nil)
+	defer db.Close()
+	if _, err := db.Exec("INSERT INTO users VALUES (?, ?)", "bob", 42); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.Query("SELECT n FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if _, err := db.Exec("INSERT INTO fail VALUES (?)", "x"); err == nil {
+		t.Error("Expected an error")
+	}
+	if _, err := db.Exec("syntax error"); err == nil {
+		t.Error("Expected an error")
+	}
+
+	lines := strings.Split(strings.TrimSpace(contents()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Wrong number of lines: %q", lines)
+	}
+	for i, want := range []string{
+		`] sql op=exec query="INSERT INTO users VALUES (?, ?)" args=2 duration=`,
+		`] sql op=query query="SELECT n FROM users" args=0 duration=`,
+		`] sql failed op=exec query="INSERT INTO fail VALUES (?)" args=1 duration=`,
+		`] sql failed op=prepare query="syntax error" args=0 duration=`,
+	} {
+		if !strings.Contains(lines[i], want) {
+			t.Errorf("Line %d: %q doesn't contain %q", i, lines[i], want)
+		}
+	}
+	if !strings.HasPrefix(lines[2], "E") || !strings.HasSuffix(lines[2], ` error="constraint violated"`) {
+		t.Errorf("Failure not logged as an Error: %q", lines[2])
+	}
+	if strings.Contains(contents(), "bob") {
+		t.Errorf("Argument values logged by default: %q", contents())
+	}
+}
+
+func TestWrapDriverArgPolicies(t *testing.T) {
+	for policy, want := range map[SQLArgPolicy]string{
+		SQLArgsTypes: " args=[string,int64] ",
+		SQLArgsLog:   " args=[bob,42] ",
+	} {
+		db := openWrapped(t, &SQLOptions{Args: policy})
+		if _, err := db.Exec("INSERT INTO users VALUES (?, ?)", "bob", 42); err != nil {
+			t.Fatal(err)
+		}
+		db.Close()
+		if !contains(want, t) {
+			t.Errorf("Policy %d: %q doesn't contain %q", policy, contents(), want)
+		}
+	}
+}
+
+func TestWrapDriverSampling(t *testing.T) {
+	db := openWrapped(t, &SQLOptions{SampleEvery: 3})
+	defer db.Close()
+	for i := 0; i < 7; i++ {
+		db.Exec("UPDATE counters SET n = n + 1")
+		db.Exec("INSERT INTO fail VALUES (1)")
+	}
+	if n := strings.Count(contents(), "] sql op=exec"); n != 3 {
+		t.Errorf("Expected 3 sampled successes, got %d", n)
+	}
+	if n := strings.Count(contents(), "] sql failed"); n != 7 {
+		t.Errorf("Expected every failure to be logged, got %d", n)
+	}
+}
@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+// StdLogHeader describes a header in the format written by a log.Logger
+// from the standard library, with the same meaning as the arguments to
+// log.New. For example, to match the output of the standard logger:
+//
+//	logger.NewFromOptions(&logger.Options{
+//		StdLogHeader: &logger.StdLogHeader{Flags: log.LstdFlags},
+//	})
+//
+// All of the log package's flags are supported: log.Ldate, log.Ltime,
+// log.Lmicroseconds, log.Llongfile, log.Lshortfile, log.LUTC, and
+// log.Lmsgprefix. Note that the severity isn't recorded in this format, and
+// messages containing newlines are split into multiple lines each with
+// their own header, where the log package would write them verbatim.
+type StdLogHeader struct {
+	// Prefix is written at the start of each line, or just before the
+	// message if Flags contains log.Lmsgprefix.
+	Prefix string
+
+	// Flags are the log package flags controlling the header contents.
+	Flags int
+}
+
+// format writes the header into buf in the same manner as the log package.
+func (h *StdLogHeader) format(buf *buffer, t time.Time, file string, line int) *buffer {
+	if h.Flags&log.Lmsgprefix == 0 {
+		buf.WriteString(h.Prefix)
+	}
+	if h.Flags&(log.Ldate|log.Ltime|log.Lmicroseconds) != 0 {
+		if h.Flags&log.LUTC != 0 {
+			t = t.UTC()
+		}
+		if h.Flags&log.Ldate != 0 {
+			year, month, day := t.Date()
+			buf.nDigits(4, 0, year, '0')
+			buf.tmp[4] = '/'
+			buf.twoDigits(5, int(month))
+			buf.tmp[7] = '/'
+			buf.twoDigits(8, day)
+			buf.tmp[10] = ' '
+			buf.Write(buf.tmp[:11])
+		}
+		if h.Flags&(log.Ltime|log.Lmicroseconds) != 0 {
+			hour, min, sec := t.Clock()
+			buf.twoDigits(0, hour)
+			buf.tmp[2] = ':'
+			buf.twoDigits(3, min)
+			buf.tmp[5] = ':'
+			buf.twoDigits(6, sec)
+			n := 8
+			if h.Flags&log.Lmicroseconds != 0 {
+				buf.tmp[8] = '.'
+				buf.nDigits(6, 9, t.Nanosecond()/1e3, '0')
+				n = 15
+			}
+			buf.tmp[n] = ' '
+			buf.Write(buf.tmp[:n+1])
+		}
+	}
+	if h.Flags&(log.Lshortfile|log.Llongfile) != 0 {
+		if h.Flags&log.Lshortfile != 0 {
+			if slash := strings.LastIndex(file, "/"); slash >= 0 {
+				file = file[slash+1:]
+			}
+		}
+		buf.WriteString(file)
+		buf.tmp[0] = ':'
+		n := buf.someDigits(1, line)
+		buf.tmp[n+1] = ':'
+		buf.tmp[n+2] = ' '
+		buf.Write(buf.tmp[:n+3])
+	}
+	if h.Flags&log.Lmsgprefix != 0 {
+		buf.WriteString(h.Prefix)
+	}
+	return buf
+}
@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestStdLogHeaderMatchesLogPackage(t *testing.T) {
+	defer func(previous func() time.Time) { timeNow = previous }(timeNow)
+	now := time.Date(2009, 1, 23, 1, 23, 23, 123123000, time.Local)
+	timeNow = func() time.Time { return now }
+
+	// Flags that don't depend on the time, or the caller, can be compared
+	// directly against the log package.
+	for _, flags := range []int{0, log.Lmsgprefix} {
+		var std bytes.Buffer
+		log.New(&std, "app: ", flags).Print("hello")
+		l := NewFromOptions(&Options{
+			SyncWriter:   &flushBuffer{},
+			StdLogHeader: &StdLogHeader{Prefix: "app: ", Flags: flags},
+		})
+		l.Info("hello")
+		if got := l.w.(*flushBuffer).String(); got != std.String() {
+			t.Errorf("Flags %d: got %q want %q", flags, got, std.String())
+		}
+	}
+
+	tests := []struct {
+		flags int
+		want  string
+	}{
+		{log.LstdFlags, "app: 2009/01/23 01:23:23 hello\n"},
+		{log.Ldate | log.Lmicroseconds, "app: 2009/01/23 01:23:23.123123 hello\n"},
+		{log.Ltime | log.Lmsgprefix, "01:23:23 app: hello\n"},
+	}
+	for _, tc := range tests {
+		l := NewFromOptions(&Options{
+			SyncWriter:   &flushBuffer{},
+			StdLogHeader: &StdLogHeader{Prefix: "app: ", Flags: tc.flags},
+		})
+		l.Info("hello")
+		if got := l.w.(*flushBuffer).String(); got != tc.want {
+			t.Errorf("Flags %d: got %q want %q", tc.flags, got, tc.want)
+		}
+	}
+}
+
+func TestStdLogHeaderFiles(t *testing.T) {
+	l := NewFromOptions(&Options{
+		SyncWriter:   &flushBuffer{},
+		StdLogHeader: &StdLogHeader{Flags: log.Lshortfile},
+	})
+	l.Info("hello")
+	var line int
+	if _, err := fmt.Sscanf(l.w.(*flushBuffer).String(), "stdlog_test.go:%d: hello\n", &line); err != nil {
+		t.Errorf("Wrong short file format %q: %s", l.w.(*flushBuffer).String(), err)
+	}
+
+	l = NewFromOptions(&Options{
+		SyncWriter:   &flushBuffer{},
+		StdLogHeader: &StdLogHeader{Flags: log.Llongfile},
+	})
+	l.Info("hello")
+	if got := l.w.(*flushBuffer).String(); !regexp.MustCompile(`^/.*/stdlog_test.go:\d+: hello\n$`).MatchString(got) {
+		t.Errorf("Wrong long file format: %q", got)
+	}
+}
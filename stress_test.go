@@ -0,0 +1,152 @@
+//go:build stress
+
+// The stress tests hammer a single Logger from many goroutines while its
+// settings and destination change underneath it. They take a while, so are
+// only built with the stress tag:
+//
+//	go test -tags stress -race -run Stress
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	stressGoroutines = 200
+	stressIterations = 1000
+)
+
+// stressWriter records every line written to it, failing every failEvery'th
+// write, in which case the line is lost.
+type stressWriter struct {
+	failEvery uint64
+
+	writes uint64
+	failed uint64
+
+	mu    sync.Mutex
+	lines []string
+}
+
+func (s *stressWriter) Write(p []byte) (int, error) {
+	if n := atomic.AddUint64(&s.writes, 1); s.failEvery > 0 && n%s.failEvery == 0 {
+		atomic.AddUint64(&s.failed, 1)
+		return 0, errors.New("sink failure")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lines = append(s.lines, string(p))
+	return len(p), nil
+}
+
+func (s *stressWriter) Sync() error {
+	return nil
+}
+
+// stressLineRegex matches the complete lines logged by TestStress.
+var stressLineRegex = regexp.MustCompile(`^[DI]\d{4} \d\d:\d\d:\d\d\.\d{6} +\d+ stress_test\.go:\d+\] stress: (?:debug|info) g=(\d+) i=(\d+) padding=x+\n$`)
+
+func TestStress(t *testing.T) {
+	w := &stressWriter{failEvery: 97}
+	root := NewFromOptions(&Options{SyncWriter: w})
+	r := NewRegistry(root)
+	l := r.Logger("stress")
+	padding := strings.Repeat("x", 100)
+
+	done := make(chan struct{})
+	var background sync.WaitGroup
+
+	// Flip the settings that decide what's written.
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			root.SetIncludeDebug(i%2 == 0)
+			if i%3 == 0 {
+				r.SetLevel("stress", DebugSeverity)
+			} else {
+				r.ClearLevel("stress")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	// Swap the destination back and forth, as rotating it would.
+	var captured []Entry
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			captured = append(captured, l.CaptureLogs(func() { time.Sleep(2 * time.Millisecond) })...)
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for g := 0; g < stressGoroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < stressIterations; i++ {
+				if i%10 == 0 {
+					l.DebugFields("debug", Int("g", g), Int("i", i), Str("padding", padding))
+				} else {
+					l.InfoFields("info", Int("g", g), Int("i", i), Str("padding", padding))
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(done)
+	background.Wait()
+
+	seen := map[string]bool{}
+	infos := 0
+	record := func(line string) {
+		m := stressLineRegex.FindStringSubmatch(line)
+		if m == nil {
+			t.Fatalf("Malformed or interleaved line: %q", line)
+		}
+		key := m[1] + "/" + m[2]
+		if seen[key] {
+			t.Fatalf("Duplicate line: %q", line)
+		}
+		seen[key] = true
+		if i, _ := strconv.Atoi(m[2]); i%10 != 0 {
+			infos++
+		}
+	}
+	for _, line := range w.lines {
+		record(line)
+	}
+	for _, e := range captured {
+		record(fmt.Sprintf("%c%02d%02d %02d:%02d:%02d.%06d %7d %s:%d] %s\n", e.Severity[0], e.Time.Month(), e.Time.Day(), e.Time.Hour(), e.Time.Minute(), e.Time.Second(), e.Time.Nanosecond()/1000, e.PID, e.File, e.Line, e.Message))
+	}
+
+	// Info is always written, so the only Info lines missing should be those
+	// lost to sink failures.
+	want := stressGoroutines * stressIterations * 9 / 10
+	if lost := want - infos; lost < 0 || uint64(lost) > atomic.LoadUint64(&w.failed) {
+		t.Errorf("Lost %d Info lines but only %d writes failed", lost, w.failed)
+	}
+	if len(captured) == 0 {
+		t.Error("Expected some lines to be written while the destination was swapped")
+	}
+}
@@ -0,0 +1,312 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyslogFormat selects the syslog message format written by a
+// SyslogWriter.
+type SyslogFormat int
+
+const (
+	// RFC5424 writes messages as defined by RFC 5424, with the fields of
+	// each entry as structured data, e.g.:
+	//
+	//	<14>1 2006-01-02T15:04:05.000000Z host app 42 - [fields@32473 path="/"] I0102 ...
+	RFC5424 SyslogFormat = iota
+
+	// RFC3164 writes messages in the older BSD format of RFC 3164, which is
+	// the one understood by most local syslog daemons, e.g.:
+	//
+	//	<14>Jan  2 15:04:05 host app[42]: I0102 ...
+	RFC3164
+)
+
+// syslogPriorities are the syslog severities of the built in severities.
+var syslogPriorities = [...]int{
+	debugLog:   7, // debug
+	infoLog:    6, // informational
+	warningLog: 4, // warning
+	errorLog:   3, // error
+	fatalLog:   2, // critical
+}
+
+// localSyslogPaths are the unix sockets tried for the local syslog daemon.
+var localSyslogPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogOptions configures a SyslogWriter.
+type SyslogOptions struct {
+	// Network is "unix", "unixgram", "udp", or "tcp", or any of the
+	// variants accepted by net.Dial. If both Network and Addr are empty the
+	// local syslog daemon is used, over the first of /dev/log,
+	// /var/run/syslog, and /var/run/log that exists.
+	Network string
+
+	// Addr is the address of the syslog server, e.g. "logs.example.com:514",
+	// or the path of a unix socket.
+	Addr string
+
+	// Format is the message format, RFC5424 by default.
+	Format SyslogFormat
+
+	// Facility is the syslog facility code, e.g. 16 for local0. Zero, which
+	// is the kernel's, selects 1, for user level messages.
+	Facility int
+
+	// Tag is the APP-NAME written with each message, the base name of the
+	// program by default.
+	Tag string
+
+	// Hostname is the HOSTNAME written with each message, os.Hostname by
+	// default.
+	Hostname string
+
+	// StructuredDataID is the SD-ID of the structured data holding the
+	// fields of each entry in RFC5424 messages, "fields@32473" by default,
+	// 32473 being the enterprise number reserved for documentation.
+	StructuredDataID string
+}
+
+// SyslogWriter is a SyncWriter that sends each entry as a syslog message,
+// at the priority of its severity: Debug is debug, Info is informational,
+// Warning is warning, Error is error, and Fatal is critical. The message is
+// the entry as formatted by the Logger, without the trailing newline.
+// Writes that aren't of an entry, such as those from Crash, are sent at the
+// severity of the header they start with, or informational.
+//
+// It connects when created, and reconnects once after a write fails.
+// Over TCP messages are framed by their lengths, as defined by RFC 6587, in
+// RFC5424 format, and by newlines in RFC3164 format.
+type SyslogWriter struct {
+	o       SyslogOptions
+	network string
+	addr    string
+	pid     string
+
+	// mu protects conn.
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogWriter returns a SyslogWriter configured by o, which may be nil
+// for the defaults, connecting to check the address.
+func NewSyslogWriter(o *SyslogOptions) (*SyslogWriter, error) {
+	w := &SyslogWriter{pid: strconv.Itoa(os.Getpid())}
+	if o != nil {
+		w.o = *o
+	}
+	if w.o.Facility == 0 {
+		w.o.Facility = 1
+	}
+	if w.o.Tag == "" {
+		w.o.Tag = filepath.Base(os.Args[0])
+	}
+	if w.o.Hostname == "" {
+		w.o.Hostname, _ = os.Hostname()
+	}
+	if w.o.StructuredDataID == "" {
+		w.o.StructuredDataID = "fields@32473"
+	}
+	w.network, w.addr = w.o.Network, w.o.Addr
+	if w.network == "" && w.addr == "" {
+		for _, path := range localSyslogPaths {
+			if _, err := os.Stat(path); err == nil {
+				w.network, w.addr = "unixgram", path
+				break
+			}
+		}
+		if w.addr == "" {
+			return nil, errors.New("no local syslog daemon found")
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.connectLocked(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *SyslogWriter) connectLocked() error {
+	conn, err := net.DialTimeout(w.network, w.addr, forwardDialTimeout)
+	if err != nil && w.network == "unixgram" && w.o.Network == "" {
+		// Some local daemons listen on a stream socket.
+		w.network = "unix"
+		conn, err = net.DialTimeout(w.network, w.addr, forwardDialTimeout)
+	}
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+// writeEntry implements entryWriter.
+func (w *SyslogWriter) writeEntry(e entryInfo, p []byte) (int, error) {
+	return w.send(e, p)
+}
+
+// Write implements SyncWriter.
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	e := entryInfo{s: infoLog}
+	if len(p) > 0 {
+		if fromChar, ok := severityFromChar(p[0]); ok {
+			e.s = fromChar
+		}
+	}
+	return w.send(e, p)
+}
+
+// send sends p, of the entry described by e, as a syslog message.
+func (w *SyslogWriter) send(e entryInfo, p []byte) (int, error) {
+	msg := w.message(e, strings.TrimRight(string(p), "\n"))
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if w.conn == nil {
+			if err = w.connectLocked(); err != nil {
+				continue
+			}
+		}
+		if _, err = w.conn.Write(msg); err == nil {
+			return len(p), nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	return 0, err
+}
+
+// message returns the syslog message for msg, of the entry described by e,
+// framed for the network. It's timestamped with the time of the entry, or
+// the current time for writes that aren't of an entry.
+func (w *SyslogWriter) message(e entryInfo, msg string) []byte {
+	pri := w.o.Facility*8 + syslogPriorities[e.s.base()]
+	now := e.time
+	if now.IsZero() {
+		now = timeNow()
+	}
+	var b strings.Builder
+	stream := w.network == "tcp" || w.network == "tcp4" || w.network == "tcp6"
+	if w.o.Format == RFC3164 {
+		fmt.Fprintf(&b, "<%d>%s ", pri, now.Format(time.Stamp))
+		// Local daemons add the hostname themselves.
+		if !strings.HasPrefix(w.network, "unix") {
+			b.WriteString(w.o.Hostname)
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%s[%s]: %s", w.o.Tag, w.pid, msg)
+		if stream || w.network == "unix" {
+			b.WriteByte('\n')
+		}
+		return []byte(b.String())
+	}
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %s - ", pri, now.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogHeaderField(w.o.Hostname, 255), syslogHeaderField(w.o.Tag, 48), w.pid)
+	w.appendStructuredData(&b, e.fields)
+	b.WriteByte(' ')
+	b.WriteString(msg)
+	if stream {
+		return []byte(strconv.Itoa(b.Len()) + " " + b.String())
+	}
+	if w.network == "unix" {
+		b.WriteByte('\n')
+	}
+	return []byte(b.String())
+}
+
+// appendStructuredData writes fields as the STRUCTURED-DATA of an RFC5424
+// message, flattening groups into keys joined by ".", or "-" if there are
+// none.
+func (w *SyslogWriter) appendStructuredData(b *strings.Builder, fields []Field) {
+	if len(fields) == 0 {
+		b.WriteByte('-')
+		return
+	}
+	b.WriteByte('[')
+	b.WriteString(w.o.StructuredDataID)
+	var add func(prefix string, fields []Field)
+	add = func(prefix string, fields []Field) {
+		for _, f := range fields {
+			if f.t == groupField {
+				children, _ := f.iface.([]Field)
+				add(prefix+f.Key+".", children)
+				continue
+			}
+			b.WriteByte(' ')
+			b.WriteString(syslogParamName(prefix + f.Key))
+			b.WriteString(`="`)
+			for _, r := range fieldValue(f) {
+				if r == '"' || r == '\\' || r == ']' {
+					b.WriteByte('\\')
+				}
+				b.WriteRune(r)
+			}
+			b.WriteByte('"')
+		}
+	}
+	add("", fields)
+	b.WriteByte(']')
+}
+
+// syslogParamName returns key as a valid RFC 5424 PARAM-NAME, of at most 32
+// printable ASCII characters other than '=', ' ', ']', and '"'.
+func syslogParamName(key string) string {
+	b := []byte(key)
+	if len(b) > 32 {
+		b = b[:32]
+	}
+	for i, c := range b {
+		if c <= ' ' || c > '~' || c == '=' || c == ']' || c == '"' {
+			b[i] = '_'
+		}
+	}
+	if len(b) == 0 {
+		return "_"
+	}
+	return string(b)
+}
+
+// syslogHeaderField returns s as a valid RFC 5424 header field of at most
+// n printable ASCII characters, or "-" if it's empty.
+func syslogHeaderField(s string, n int) string {
+	if s == "" {
+		return "-"
+	}
+	b := []byte(s)
+	if len(b) > n {
+		b = b[:n]
+	}
+	for i, c := range b {
+		if c <= ' ' || c > '~' {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+// Sync implements SyncWriter.
+func (w *SyslogWriter) Sync() error {
+	return nil
+}
+
+// Close closes the connection.
+func (w *SyslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
@@ -0,0 +1,169 @@
+package logger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readPacket returns the next packet received by c.
+func readPacket(t *testing.T, c net.PacketConn) string {
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 4096)
+	n, _, err := c.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf[:n])
+}
+
+func TestSyslogWriterRFC5424(t *testing.T) {
+	defer func(now func() time.Time) { timeNow = now }(timeNow)
+	timeNow = func() time.Time { return time.Date(2006, 1, 2, 15, 4, 5, 123456000, time.UTC) }
+	c, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	w, err := NewSyslogWriter(&SyslogOptions{Network: "udp", Addr: c.LocalAddr().String(), Tag: "app", Hostname: "host"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewFromOptions(&Options{SyncWriter: w})
+	defer l.Close()
+
+	l.WarningFields("slow", Str("path", `/a"]\`), Group("req", Int("n", 1)))
+	pid := os.Getpid()
+	want := fmt.Sprintf(`<12>1 2006-01-02T15:04:05.123456Z host app %d - [fields@32473 path="/a\"\]\\" req.n="1"] W`, pid)
+	got := readPacket(t, c)
+	if !strings.HasPrefix(got, want) || !strings.HasSuffix(got, `] slow path="/a\"]\\" req.n=1`) {
+		t.Errorf("Got %q, want the prefix %q", got, want)
+	}
+
+	l.Info("plain")
+	if got := readPacket(t, c); !strings.HasPrefix(got, fmt.Sprintf("<14>1 2006-01-02T15:04:05.123456Z host app %d - - I", pid)) {
+		t.Errorf("Wrong message without fields: %q", got)
+	}
+
+	// Not an entry, so the severity comes from the header.
+	w.Write([]byte("E0102 crashed\n"))
+	if got := readPacket(t, c); !strings.HasPrefix(got, "<11>1 ") || !strings.HasSuffix(got, " - E0102 crashed") {
+		t.Errorf("Wrong message for a write: %q", got)
+	}
+
+	// Timestamped with the time of the entry, not the time it's sent.
+	entryTime := time.Date(2007, 3, 4, 5, 6, 7, 890000000, time.UTC)
+	NewFromOptions(&Options{SyncWriter: w, Now: func() time.Time { return entryTime }}).Info("earlier")
+	if got := readPacket(t, c); !strings.HasPrefix(got, "<14>1 2007-03-04T05:06:07.890000Z ") {
+		t.Errorf("Wrong timestamp for an entry: %q", got)
+	}
+}
+
+func TestSyslogWriterRFC3164(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no unix datagram sockets on windows")
+	}
+	defer func(now func() time.Time) { timeNow = now }(timeNow)
+	timeNow = func() time.Time { return time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC) }
+	path := filepath.Join(t.TempDir(), "log")
+	c, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	defer func(paths []string) { localSyslogPaths = paths }(localSyslogPaths)
+	localSyslogPaths = []string{filepath.Join(t.TempDir(), "missing"), path}
+	w, err := NewSyslogWriter(&SyslogOptions{Format: RFC3164, Facility: 16, Tag: "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	l := NewFromOptions(&Options{SyncWriter: w})
+	l.Error("failed")
+	want := fmt.Sprintf("<131>Jan  2 15:04:05 app[%d]: E0102 ", os.Getpid())
+	if got := readPacket(t, c); !strings.HasPrefix(got, want) || !strings.HasSuffix(got, "] failed") {
+		t.Errorf("Got %q, want the prefix %q", got, want)
+	}
+
+	localSyslogPaths = nil
+	if _, err := NewSyslogWriter(nil); err == nil {
+		t.Error("No error without a local syslog daemon")
+	}
+}
+
+func TestSyslogWriterTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			var n int
+			if _, err := fmt.Fscanf(r, "%d ", &n); err != nil {
+				return
+			}
+			buf := make([]byte, n)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return
+			}
+			lines <- string(buf)
+		}
+	}()
+
+	w, err := NewSink("syslog://" + ln.Addr().String() + "?network=tcp&tag=t&facility=16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.(*SyslogWriter).Close()
+	l := NewFromOptions(&Options{SyncWriter: w})
+	l.Info("one")
+	l.Debug("hidden")
+	l.Warning("two")
+	for _, want := range []string{"] one", "] two"} {
+		select {
+		case got := <-lines:
+			if !strings.HasPrefix(got, "<1") || !strings.Contains(got, " t ") || !strings.HasSuffix(got, want) {
+				t.Errorf("Got %q, want the suffix %q", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+}
+
+func TestSyslogSinkErrors(t *testing.T) {
+	for _, u := range []string{"syslog://127.0.0.1:1?format=json", "syslog://127.0.0.1:1?facility=24"} {
+		if _, err := NewSink(u); err == nil {
+			t.Errorf("No error for %q", u)
+		}
+	}
+}
+
+func TestSyslogParamName(t *testing.T) {
+	for key, want := range map[string]string{
+		"path":                               "path",
+		`a b"c]d=e`:                          "a_b_c_d_e",
+		"":                                   "_",
+		"0123456789012345678901234567890123": "01234567890123456789012345678901",
+	} {
+		if got := syslogParamName(key); got != want {
+			t.Errorf("syslogParamName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"strconv"
+	"sync"
+)
+
+// SystemdWriter is a SyncWriter that prefixes each line written to it with
+// the priority of its entry, e.g. "<3>" for an Error, as systemd reads
+// them from the stdout and stderr of the services it runs, so journalctl
+// shows and filters their entries by severity without a syslog or journal
+// socket. The priorities are those of SyslogWriter. Every line of an
+// entry that spans more than one, such as the stack traces of a Fatal
+// entry, gets the prefix. Writes that aren't of an entry are prefixed with
+// the priority of the header they start with, or informational, or that of
+// the previous write if they continue its line, e.g.:
+//
+//	l := logger.NewFromOptions(&logger.Options{
+//		SyncWriter: logger.NewSystemdWriter(os.Stdout),
+//	})
+type SystemdWriter struct {
+	w SyncWriter
+
+	// mu protects midLine, which is true if the last write didn't end with
+	// a newline, so the next doesn't start a line, and last, the severity
+	// of the last write.
+	mu      sync.Mutex
+	midLine bool
+	last    severity
+}
+
+// NewSystemdWriter returns a SystemdWriter writing to w.
+func NewSystemdWriter(w SyncWriter) *SystemdWriter {
+	return &SystemdWriter{w: w}
+}
+
+// writeEntry implements entryWriter.
+func (w *SystemdWriter) writeEntry(e entryInfo, p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writeLocked(e.s, p)
+}
+
+// Write implements SyncWriter.
+func (w *SystemdWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s := infoLog
+	if w.midLine {
+		s = w.last
+	} else if len(p) > 0 {
+		if fromChar, ok := severityFromChar(p[0]); ok {
+			s = fromChar
+		}
+	}
+	return w.writeLocked(s, p)
+}
+
+// writeLocked writes p, of severity s, with each line prefixed. w.mu must
+// be held.
+func (w *SystemdWriter) writeLocked(s severity, p []byte) (int, error) {
+	prefix := "<" + strconv.Itoa(syslogPriorities[s.base()]) + ">"
+	w.last = s
+	out := make([]byte, 0, len(p)+len(prefix))
+	for _, c := range p {
+		if !w.midLine {
+			out = append(out, prefix...)
+			w.midLine = true
+		}
+		out = append(out, c)
+		if c == '\n' {
+			w.midLine = false
+		}
+	}
+	if _, err := w.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync implements SyncWriter.
+func (w *SystemdWriter) Sync() error {
+	return w.w.Sync()
+}
@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSystemdWriter(t *testing.T) {
+	buf := &flushBuffer{}
+	l := NewFromOptions(&Options{SyncWriter: NewSystemdWriter(buf), Formatter: CLIFormatter{}, Exit: func(int) {}})
+	l.Info("started")
+	l.Error("failed")
+	l.Warning("two\nlines")
+	l.Fatal("goodbye")
+
+	lines := strings.Split(buf.String(), "\n")
+	for i, want := range []string{"<6>started", "<3>error: failed", "<4>warning: two", "<4>lines", "<2>fatal: goodbye"} {
+		if lines[i] != want {
+			t.Errorf("Line %d is %q, want %q", i, lines[i], want)
+		}
+	}
+	// The stack traces of the Fatal entry.
+	for _, line := range lines[5 : len(lines)-1] {
+		if !strings.HasPrefix(line, "<2>") {
+			t.Errorf("Wrong prefix: %q", line)
+		}
+	}
+}
+
+func TestSystemdWriterWrite(t *testing.T) {
+	buf := &flushBuffer{}
+	w := NewSystemdWriter(buf)
+	w.Write([]byte("E0102 15:04:05.000000 1 main.go:10] part"))
+	w.Write([]byte("ial\nnot a header\n"))
+	if got, want := buf.String(), "<3>E0102 15:04:05.000000 1 main.go:10] partial\n<3>not a header\n"; got != want {
+		t.Errorf("Got %q want %q", got, want)
+	}
+	buf.Reset()
+	w.Write([]byte("no header\n"))
+	if got := buf.String(); got != "<6>no header\n" {
+		t.Errorf("Wrong output: %q", got)
+	}
+}
+
+func TestNewSinkSystemd(t *testing.T) {
+	if _, err := NewSink("systemd:stderr"); err != nil {
+		t.Error(err)
+	}
+	if _, err := NewSink("systemd:console"); err == nil {
+		t.Error("want an error for an unknown stream")
+	}
+	if _, ok := interface{}(NewSystemdWriter(&flushBuffer{})).(entryWriter); !ok {
+		t.Error("SystemdWriter isn't an entryWriter")
+	}
+}
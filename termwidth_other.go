@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package logger
+
+// stderrWidth returns 0, as the width of the terminal isn't known.
+func stderrWidth() int {
+	return 0
+}
@@ -0,0 +1,18 @@
+//go:build linux || darwin
+
+package logger
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// stderrWidth returns the number of columns of the terminal on stderr, or 0
+// if it isn't one.
+func stderrWidth() int {
+	var ws struct{ row, col, xpixel, ypixel uint16 }
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(syscall.Stderr), syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(&ws))); errno != 0 {
+		return 0
+	}
+	return int(ws.col)
+}
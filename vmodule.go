@@ -0,0 +1,195 @@
+package logger
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// vmodule holds the patterns set with SetVModule.
+type vmodule struct {
+	patterns []vmodulePattern
+
+	// levels caches the level of each call site, as an int keyed by PC.
+	levels sync.Map
+}
+
+// vmodulePattern is one pattern=level entry of a vmodule spec.
+type vmodulePattern struct {
+	pattern string
+
+	// slashes is the number of slashes in pattern, which is matched against
+	// that many trailing directories of the path along with the file name.
+	slashes int
+	level   int
+}
+
+// parseVModule parses spec, a comma separated list of pattern=level
+// entries.
+func parseVModule(spec string) (*vmodule, error) {
+	vm := &vmodule{}
+	for _, entry := range strings.Split(spec, ",") {
+		if entry == "" {
+			continue
+		}
+		i := strings.LastIndexByte(entry, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid vmodule entry %q, want pattern=level", entry)
+		}
+		pattern := strings.TrimSuffix(entry[:i], ".go")
+		level, err := strconv.Atoi(entry[i+1:])
+		if err != nil || level < 0 {
+			return nil, fmt.Errorf("invalid level in vmodule entry %q", entry)
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern in vmodule entry %q: %s", entry, err)
+		}
+		vm.patterns = append(vm.patterns, vmodulePattern{pattern: pattern, slashes: strings.Count(pattern, "/"), level: level})
+	}
+	return vm, nil
+}
+
+// level returns the level of the first pattern that matches file, the full
+// path of a source file, or 0 if none do.
+func (vm *vmodule) level(file string) int {
+	file = strings.TrimSuffix(file, ".go")
+	for _, p := range vm.patterns {
+		name := file
+		for i, slashes := len(file)-1, 0; i >= 0; i-- {
+			if file[i] == '/' {
+				if slashes == p.slashes {
+					name = file[i+1:]
+					break
+				}
+				slashes++
+			}
+		}
+		if ok, _ := filepath.Match(p.pattern, name); ok {
+			return p.level
+		}
+	}
+	return 0
+}
+
+// SetVModule sets per-file verbosity levels in the manner of glog's
+// -vmodule flag, so Debug logs can be enabled for specific files or
+// packages only. spec is a comma separated list of pattern=level entries,
+// e.g. "server*=2,db.go=1,storage/*=1", where each pattern is matched, in
+// the syntax of filepath.Match, against the name of the calling file
+// without its ".go" suffix, preceded by as many of its directories as the
+// pattern has slashes. The first matching pattern wins.
+//
+// Debug logs from files with a level of 1 or more are written even if
+// IncludeDebug is false, as are the logs from V(level) for files with at
+// least that level. An empty spec removes all the patterns.
+func (l *Logger) SetVModule(spec string) error {
+	vm, err := parseVModule(spec)
+	if err != nil {
+		return err
+	}
+	if len(vm.patterns) == 0 {
+		vm = nil
+	}
+	l.vmodule.Store(vm)
+	return nil
+}
+
+// vlevel returns the vmodule level of the file of the call site depth
+// frames above the caller of vlevel.
+func (l *Logger) vlevel(depth int) int {
+	vm, _ := l.vmodule.Load().(*vmodule)
+	if vm == nil {
+		return 0
+	}
+	if l.caller != nil {
+		return vm.level(l.caller.file)
+	}
+	var pcs [1]uintptr
+	if runtime.Callers(3+depth+l.depthDelta, pcs[:]) == 0 {
+		return 0
+	}
+	if level, ok := vm.levels.Load(pcs[0]); ok {
+		return level.(int)
+	}
+	frame, _ := runtime.CallersFrames(pcs[:]).Next()
+	level := vm.level(frame.File)
+	vm.levels.Store(pcs[0], level)
+	return level
+}
+
+// debugEnabled returns true if a Debug log from the call site depth frames
+// above the caller of debugEnabled should be written, or kept for
+// Options.DebugBacklog or Options.FlightRecorder.
+func (l *Logger) debugEnabled(depth int) bool {
+	return l.IncludeDebug() || l.debugBacklog != nil || l.flight != nil || l.vlevel(depth+1) >= 1
+}
+
+// vmoduleLevel returns the vmodule level of file, the full path of the
+// file of a call site.
+func (l *Logger) vmoduleLevel(file string) int {
+	vm, _ := l.vmodule.Load().(*vmodule)
+	if vm == nil {
+		return 0
+	}
+	return vm.level(file)
+}
+
+// Verbose is returned by V, and only writes logs if the verbosity is at
+// least the level passed to V.
+type Verbose struct {
+	l       *Logger
+	enabled bool
+}
+
+// V returns a Verbose whose Info logs are only written if the verbosity set
+// with SetVerbosity, or the level set for the calling file with SetVModule,
+// is at least level, in the manner of glog:
+//
+//	l.V(2).Infof("cache miss for %q", key)
+//
+//	if v := l.V(3); v.Enabled() {
+//		v.Info(expensiveDump())
+//	}
+func (l *Logger) V(level int) Verbose {
+	return Verbose{l: l, enabled: l.Verbosity() >= level || l.vlevel(0) >= level}
+}
+
+// SetVerbosity sets the verbosity used by V.
+func (l *Logger) SetVerbosity(level int) {
+	atomic.StoreInt32(&l.verbosity, int32(level))
+}
+
+// Verbosity returns the verbosity used by V.
+func (l *Logger) Verbosity() int {
+	return int(atomic.LoadInt32(&l.verbosity))
+}
+
+// Enabled returns true if the Verbose writes logs.
+func (v Verbose) Enabled() bool {
+	return v.enabled
+}
+
+// Info is equivalent to Logger.Info if v is enabled.
+func (v Verbose) Info(args ...interface{}) {
+	if v.enabled {
+		v.l.print(infoLog, args...)
+	}
+}
+
+// Infof is equivalent to Logger.Infof if v is enabled.
+func (v Verbose) Infof(format string, args ...interface{}) {
+	if v.enabled {
+		v.l.printf(infoLog, format, args...)
+	}
+}
+
+// InfoFields is equivalent to Logger.InfoFields if v is enabled.
+func (v Verbose) InfoFields(msg string, fields ...Field) {
+	if v.enabled {
+		v.l.printFields(infoLog, msg, fields)
+	}
+}
@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVModule(t *testing.T) {
+	newTestLogger()
+	if err := testLogger.SetVModule("other=3,vmodule_test.go=1"); err != nil {
+		t.Fatal(err)
+	}
+	testLogger.Debug("enabled by vmodule")
+	testLogger.DebugFields("fields", Int("a", 1))
+	if got := contents(); !strings.Contains(got, " vmodule_test.go:") || !strings.Contains(got, "] enabled by vmodule\n") || !strings.Contains(got, "] fields a=1\n") {
+		t.Errorf("Wrong output: %q", got)
+	}
+
+	newTestLogger()
+	if err := testLogger.SetVModule("vmodule_test=0,*=1"); err != nil {
+		t.Fatal(err)
+	}
+	testLogger.Debug("disabled by the first match")
+	if err := testLogger.SetVModule("other/*=2,*/vmodule_test=1"); err != nil {
+		t.Fatal(err)
+	}
+	testLogger.Debugf("enabled by %s", "the directory")
+	if got := contents(); strings.Contains(got, "first match") || !strings.Contains(got, "] enabled by the directory\n") {
+		t.Errorf("Wrong output: %q", got)
+	}
+
+	newTestLogger()
+	testLogger.SetVModule("")
+	testLogger.Debug("disabled")
+	if got := contents(); got != "" {
+		t.Errorf("Expected no output: %q", got)
+	}
+
+	for _, bad := range []string{"nolevel", "=1", "x=-1", "x=y", "[=1"} {
+		if err := testLogger.SetVModule(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestV(t *testing.T) {
+	newTestLogger()
+	testLogger.V(1).Info("hidden")
+	testLogger.SetVerbosity(2)
+	testLogger.V(2).Infof("shown %d", 2)
+	testLogger.V(3).Info("hidden")
+	if err := testLogger.SetVModule("vmodule_test=4"); err != nil {
+		t.Fatal(err)
+	}
+	v := testLogger.V(4)
+	if !v.Enabled() {
+		t.Error("Expected V(4) to be enabled by vmodule")
+	}
+	v.InfoFields("shown by vmodule", Int("level", 4))
+
+	got := contents()
+	if strings.Contains(got, "hidden") || !strings.Contains(got, " vmodule_test.go:") {
+		t.Errorf("Wrong output: %q", got)
+	}
+	for _, want := range []string{"] shown 2\n", "] shown by vmodule level=4\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Missing %q in %q", want, got)
+		}
+	}
+	if !strings.HasPrefix(got, "I") {
+		t.Errorf("Expected Info logs: %q", got)
+	}
+}
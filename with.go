@@ -0,0 +1,170 @@
+package logger
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// badKey is the key used for values in keysAndValues without a string key,
+// matching log/slog.
+const badKey = "!BADKEY"
+
+// With returns a Logger that adds the fields in keysAndValues to every
+// entry, after any fields added by l. The returned Logger shares l's
+// destination and settings, so e.g. SetIncludeDebug on either affects both.
+//
+// keysAndValues alternates string keys and values, e.g.:
+//
+//	reqLog := l.With("request_id", id, "user", user)
+//
+// and may also contain Fields, which are added as is. A value without a
+// string key is added with the key "!BADKEY".
+func (l *Logger) With(keysAndValues ...interface{}) *Logger {
+	fields := make([]Field, 0, len(l.fields)+len(keysAndValues)/2)
+	fields = append(fields, l.fields...)
+	fields = append(fields, l.kvFields(keysAndValues)...)
+	ret := *l
+	ret.fields = fields
+	return &ret
+}
+
+// Named returns a Logger that prefixes every message with "name: ", or for
+// JSONFormat and Options.Formatter adds a component=name field before any
+// other fields, so the entries of different subsystems can be told apart.
+// Calling Named on a named Logger joins the names with a dot, e.g.
+// l.Named("server").Named("http") logs "server.http: ". Like With, the
+// returned Logger shares l's destination and settings.
+func (l *Logger) Named(name string) *Logger {
+	ret := *l
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+	ret.name = name
+	return &ret
+}
+
+// caller is a source location set with WithCaller.
+type caller struct {
+	file string
+	line int
+}
+
+// WithCaller returns a Logger that reports file and line as the source of
+// every entry, instead of the code calling it, for code that proxies logs
+// from another system, such as a remote agent or a JS frontend, and wants
+// to report the original source location. Like With, the returned Logger
+// shares l's destination and settings.
+func (l *Logger) WithCaller(file string, line int) *Logger {
+	ret := *l
+	ret.caller = &caller{file: file, line: line}
+	return &ret
+}
+
+// WithDepth returns a Logger that looks up delta more stack levels than l
+// when reporting the calling function, for helpers that wrap a Logger's
+// methods and want their callers reported instead of themselves. It adds
+// to Options.DepthDelta. Like With, the returned Logger shares l's
+// destination and settings.
+func (l *Logger) WithDepth(delta int) *Logger {
+	ret := *l
+	ret.depthDelta += delta
+	return &ret
+}
+
+// KVPolicy selects how mistakes in keysAndValues are handled.
+type KVPolicy int
+
+const (
+	// KVLenient logs values without a string key under "!BADKEY".
+	KVLenient KVPolicy = iota
+
+	// KVDiagnose also reports each mistake, along with the file and line of
+	// the call, to Options.Diagnostics.
+	KVDiagnose
+
+	// KVPanic panics on any mistake, for catching them early in
+	// development and tests.
+	KVPanic
+)
+
+// kvFields converts keysAndValues into Fields, handling any mistakes as
+// selected by Options.KeysAndValues. It must be called directly by the
+// exported method called by the user, so the mistake can be attributed.
+func (l *Logger) kvFields(keysAndValues []interface{}) []Field {
+	if l.kvPolicy != KVLenient {
+		if problem := kvProblem(keysAndValues); problem != "" {
+			_, file, line, _ := runtime.Caller(2 + l.depthDelta)
+			msg := fmt.Sprintf("%s at %s:%d", problem, filepath.Base(file), line)
+			if l.kvPolicy == KVPanic {
+				panic("logger: " + msg)
+			}
+			l.diagnosef("%s", msg)
+		}
+	}
+	return kvFields(keysAndValues)
+}
+
+// kvProblem returns a description of the first mistake in keysAndValues,
+// or "" if there are none.
+func kvProblem(keysAndValues []interface{}) string {
+	for i := 0; i < len(keysAndValues); i++ {
+		if _, ok := keysAndValues[i].(Field); ok {
+			continue
+		}
+		if _, ok := keysAndValues[i].(string); !ok {
+			return fmt.Sprintf("non-string key %T(%v) in keysAndValues", keysAndValues[i], keysAndValues[i])
+		}
+		if i == len(keysAndValues)-1 {
+			return fmt.Sprintf("key %q without a value in keysAndValues", keysAndValues[i])
+		}
+		i++
+	}
+	return ""
+}
+
+// kvFields converts alternating keys and values into Fields.
+func kvFields(keysAndValues []interface{}) []Field {
+	ret := make([]Field, 0, len(keysAndValues)/2)
+	for i := 0; i < len(keysAndValues); i++ {
+		if f, ok := keysAndValues[i].(Field); ok {
+			ret = append(ret, f)
+			continue
+		}
+		key, ok := keysAndValues[i].(string)
+		if !ok || i == len(keysAndValues)-1 {
+			ret = append(ret, toField(badKey, keysAndValues[i]))
+			continue
+		}
+		ret = append(ret, toField(key, keysAndValues[i+1]))
+		i++
+	}
+	return ret
+}
+
+// toField returns a Field holding val under key, using the typed
+// constructors for common types.
+func toField(key string, val interface{}) Field {
+	switch v := val.(type) {
+	case string:
+		return Str(key, v)
+	case int:
+		return Int(key, v)
+	case int64:
+		return Int64(key, v)
+	case float64:
+		return Float64(key, v)
+	case bool:
+		return Bool(key, v)
+	case time.Duration:
+		return Dur(key, v)
+	case time.Time:
+		return Time(key, v)
+	case error:
+		return Field{Key: key, t: errorField, iface: v}
+	case fmt.Stringer:
+		return Str(key, v.String())
+	}
+	return Any(key, val)
+}
@@ -0,0 +1,191 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWith(t *testing.T) {
+	newTestLogger()
+	reqLog := testLogger.With("request_id", "abc", "attempt", 2)
+	reqLog.Info("started")
+	reqLog.With("user", "bob").WarningFields("denied", Str("path", "/admin"))
+	testLogger.Info("unchanged")
+
+	lines := strings.Split(strings.TrimSpace(contents()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Wrong number of lines: %q", lines)
+	}
+	for i, want := range []string{
+		"] started request_id=abc attempt=2",
+		"] denied request_id=abc attempt=2 user=bob path=/admin",
+		"] unchanged",
+	} {
+		if !strings.HasSuffix(lines[i], want) {
+			t.Errorf("Got %q want suffix %q", lines[i], want)
+		}
+	}
+	if !strings.Contains(lines[0], " with_test.go:") {
+		t.Errorf("Wrong caller: %q", lines[0])
+	}
+
+	// The derived Logger shares its parent's settings.
+	testLogger.SetIncludeDebug(true)
+	if !reqLog.IncludeDebug() {
+		t.Error("SetIncludeDebug not shared")
+	}
+}
+
+func TestWithJSON(t *testing.T) {
+	l := NewFromOptions(&Options{SyncWriter: &flushBuffer{}, Format: JSONFormat}).With("request_id", "abc", Group("http", Int("status", 200)))
+	l.Info("served")
+	if got := l.w.(*flushBuffer).String(); !strings.HasSuffix(got, `"message":"served","request_id":"abc","http":{"status":200}}`+"\n") {
+		t.Errorf("Wrong output: %q", got)
+	}
+}
+
+func TestNamed(t *testing.T) {
+	newTestLogger()
+	httpLog := testLogger.Named("server").Named("http")
+	httpLog.With("path", "/").Info("served")
+	testLogger.Info("unnamed")
+
+	lines := strings.Split(strings.TrimSpace(contents()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Wrong number of lines: %q", lines)
+	}
+	if !strings.Contains(lines[0], " with_test.go:") || !strings.HasSuffix(lines[0], "] server.http: served path=/") {
+		t.Errorf("Wrong named line: %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], "] unnamed") {
+		t.Errorf("Wrong unnamed line: %q", lines[1])
+	}
+
+	b := &flushBuffer{}
+	jsonLog := NewFromOptions(&Options{SyncWriter: b, Format: JSONFormat}).Named("db")
+	jsonLog.InfoFields("query", Int("rows", 2))
+	if want := `"message":"query","component":"db","rows":2}`; !strings.Contains(b.String(), want) {
+		t.Errorf("Got %q want %q", b.String(), want)
+	}
+}
+
+func TestWithCaller(t *testing.T) {
+	newTestLogger()
+	remote := testLogger.WithCaller("src/app/main.js", 42)
+	remote.Warning("uncaught exception")
+	remote.With("user", "bob").Info("clicked")
+	testLogger.Info("local")
+
+	lines := strings.Split(strings.TrimSpace(contents()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Wrong number of lines: %q", lines)
+	}
+	for i, want := range []string{
+		" main.js:42] uncaught exception",
+		" main.js:42] clicked user=bob",
+		"] local",
+	} {
+		if !strings.HasSuffix(lines[i], want) {
+			t.Errorf("Got %q want suffix %q", lines[i], want)
+		}
+	}
+	if !strings.Contains(lines[2], " with_test.go:") {
+		t.Errorf("Wrong caller: %q", lines[2])
+	}
+
+	b := &flushBuffer{}
+	NewFromOptions(&Options{SyncWriter: b, Format: JSONFormat}).WithCaller("main.js", 7).Error("boom")
+	if want := `"caller":{"file":"main.js","line":7},`; !strings.Contains(b.String(), want) {
+		t.Errorf("Got %q want %q", b.String(), want)
+	}
+}
+
+// logFromHelper is a helper that wants its caller reported.
+func logFromHelper(l *Logger, msg string) {
+	l.Info(msg)
+}
+
+func TestWithDepth(t *testing.T) {
+	newTestLogger()
+	_, _, line, _ := runtime.Caller(0)
+	logFromHelper(testLogger.WithDepth(1), "from the caller")
+	if want := fmt.Sprintf(" with_test.go:%d] from the caller", line+1); !strings.HasSuffix(strings.TrimSpace(contents()), want) {
+		t.Errorf("Got %q want suffix %q", contents(), want)
+	}
+}
+
+func TestKVFields(t *testing.T) {
+	ts := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	got := &buffer{}
+	for _, f := range kvFields([]interface{}{
+		"s", "v", "i", 1, "f", 1.5, "b", true, "d", time.Second, "t", ts,
+		"err", errors.New("boom"), "any", []int{1}, 42, "lonely",
+	}) {
+		f.appendTo(got)
+	}
+	want := ` s=v i=1 f=1.5 b=true d=1s t=2006-01-02T15:04:05Z err=boom any=[1] !BADKEY=42 !BADKEY=lonely`
+	if got.String() != want {
+		t.Errorf("Got %q want %q", got.String(), want)
+	}
+}
+
+func TestSugaredMethods(t *testing.T) {
+	newTestLogger()
+	testLogger.Debugw("hidden", "k", 1)
+	if contents() != "" {
+		t.Errorf("Debugw should not be emitted by default: %q", contents())
+	}
+	exited := false
+	defer func(exit func(int)) { osExit = exit }(osExit)
+	osExit = func(int) { exited = true }
+
+	for _, tc := range []struct {
+		log  func(*Logger, string, ...interface{})
+		char string
+	}{
+		{(*Logger).Infow, "I"},
+		{(*Logger).Warningw, "W"},
+		{(*Logger).Errorw, "E"},
+		{(*Logger).Fatalw, "F"},
+	} {
+		newTestLogger()
+		tc.log(testLogger, "served", "path", "/index.html", "status", 200)
+		if !strings.HasPrefix(contents(), tc.char) || !strings.Contains(contents(), " with_test.go:") || !strings.Contains(contents(), "] served path=/index.html status=200\n") {
+			t.Errorf("Wrong output: %q", contents())
+		}
+	}
+	if !exited {
+		t.Error("Fatalw did not exit")
+	}
+}
+
+func TestKVPolicy(t *testing.T) {
+	diagnostics := &flushBuffer{}
+	l := NewFromOptions(&Options{SyncWriter: &flushBuffer{}, KeysAndValues: KVDiagnose, Diagnostics: diagnostics})
+	l.Infow("ok", "k", 1, Int("n", 2))
+	l.Infow("odd", "k", 1, "lonely")
+	l.With(42, "v")
+	got := diagnostics.String()
+	lines := strings.Split(strings.TrimSpace(got), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Wrong number of diagnostics: %q", got)
+	}
+	if !strings.HasPrefix(lines[0], `logger: key "lonely" without a value in keysAndValues at with_test.go:`) {
+		t.Errorf("Wrong diagnostic: %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], `logger: non-string key int(42) in keysAndValues at with_test.go:`) {
+		t.Errorf("Wrong diagnostic: %q", lines[1])
+	}
+
+	l = NewFromOptions(&Options{SyncWriter: &flushBuffer{}, KeysAndValues: KVPanic})
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(string), "without a value") {
+			t.Errorf("Wrong panic: %v", r)
+		}
+	}()
+	l.Errorw("odd", "k")
+}